@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// Check compares the records of the registry with the desired records of the source like RunOnce, without changing
+// anything, and returns the inconsistencies between them. The changes held back by the policy, e.g. the records which
+// are not desired anymore with upsert-only, are reported as well, along with the ownership records of the registry left
+// without record.
+func (c *Controller) Check(ctx context.Context) ([]*plan.Inconsistency, error) {
+	records, err := c.Registry.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
+
+	endpoints, err := c.Source.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoints = c.Registry.AdjustEndpoints(endpoints)
+
+	domainFilter := endpoint.MatchAllDomainFilters{c.DomainFilter, c.Registry.GetDomainFilter()}
+	p := &plan.Plan{
+		Policies:           []plan.Policy{c.Policy},
+		Current:            records,
+		Desired:            endpoints,
+		DomainFilter:       domainFilter,
+		PropertyComparator: c.Registry.PropertyValuesEqual,
+		ManagedRecords:     c.ManagedRecordTypes,
+		ConflictResolver:   c.ConflictResolver,
+		OwnerID:            c.OwnerID,
+		AdoptOwnerIDs:      c.AdoptOwnerIDs,
+		AdoptAnnotated:     c.AdoptAnnotated,
+	}
+	inconsistencies := p.Calculate().Inconsistencies()
+
+	if lister, ok := c.Registry.(registry.OrphanedRecordsLister); ok {
+		orphans := filterChangesForDomains(&plan.Changes{Delete: lister.OrphanedRecords()}, domainFilter)
+		for _, ep := range orphans.Delete {
+			inconsistencies = append(inconsistencies, &plan.Inconsistency{Kind: plan.InconsistencyOrphanedOwnership, Endpoint: ep})
+		}
+	}
+
+	for _, i := range inconsistencies {
+		i.Pipeline = c.Name
+	}
+	return inconsistencies, nil
+}
+
+// Check checks every pipeline, see Controller.Check. A failing pipeline doesn't prevent the other ones from being
+// checked, their inconsistencies are returned along with the error.
+func (p Pipelines) Check(ctx context.Context) ([]*plan.Inconsistency, error) {
+	inconsistencies := []*plan.Inconsistency{}
+	failed := 0
+	for _, c := range p {
+		found, err := c.Check(ctx)
+		if err != nil {
+			c.logger().Errorf("Failed to check the DNS records: %v", err)
+			failed++
+			continue
+		}
+		inconsistencies = append(inconsistencies, found...)
+	}
+	if failed > 0 {
+		return inconsistencies, fmt.Errorf("%d of %d pipeline(s) failed", failed, len(p))
+	}
+	return inconsistencies, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.org"))
+	owned, err := registry.NewTXTRegistry(p, registry.TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+	require.NoError(t, owned.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("old.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("gone.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+	}))
+	other, err := registry.NewTXTRegistry(p, registry.TXTConfig{OwnerID: "other", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+	}))
+	// the record is deleted out-of-band
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("gone.example.org", endpoint.RecordTypeA, "1.1.1.1")},
+	}))
+	before, err := p.Records(ctx)
+	require.NoError(t, err)
+
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+		endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+		// the record of the other owner needs no change, but it is blocked all the same
+		endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+		endpoint.NewEndpoint("out.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+	}, nil)
+	r, err := registry.NewTXTRegistry(p, registry.TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Name:     "public",
+		Source:   source,
+		Registry: r,
+		// the records which are not desired anymore are reported even though the policy holds their deletion back
+		Policy:             &plan.UpsertOnlyPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"example.org"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		OwnerID:            "owner",
+	}
+
+	inconsistencies, err := ctrl.Check(ctx)
+	require.NoError(t, err)
+	kinds := map[string]plan.InconsistencyKind{}
+	for _, i := range inconsistencies {
+		kinds[i.Endpoint.RecordType+" "+i.Endpoint.DNSName] = i.Kind
+		assert.Equal(t, "public", i.Pipeline)
+	}
+	assert.Equal(t, map[string]plan.InconsistencyKind{
+		"A old.example.org":      plan.InconsistencyNotDesired,
+		"A foo.example.org":      plan.InconsistencyChanged,
+		"A bar.example.org":      plan.InconsistencyForeignOwner,
+		"A baz.example.org":      plan.InconsistencyForeignOwner,
+		"A new.example.org":      plan.InconsistencyMissing,
+		"A out.example.com":      plan.InconsistencyOutsideDomainFilter,
+		"TXT gone.example.org":   plan.InconsistencyOrphanedOwnership,
+		"TXT a-gone.example.org": plan.InconsistencyOrphanedOwnership,
+	}, kinds)
+
+	// nothing is changed
+	after, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, before, after)
+}
+
+func TestPipelinesCheck(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4")}, nil)
+	publicRegistry, err := registry.NewNoopRegistry(&filteredMockProvider{})
+	require.NoError(t, err)
+	privateRegistry, err := registry.NewNoopRegistry(&errorMockProvider{})
+	require.NoError(t, err)
+	pipelines := Pipelines{
+		{Name: "public", Source: source, Registry: publicRegistry, Policy: &plan.SyncPolicy{}, ManagedRecordTypes: []string{endpoint.RecordTypeA}},
+		{Name: "private", Source: source, Registry: privateRegistry, Policy: &plan.SyncPolicy{}, ManagedRecordTypes: []string{endpoint.RecordTypeA}},
+	}
+
+	// The private pipeline fails, but the public one is still checked.
+	inconsistencies, err := pipelines.Check(context.Background())
+	assert.EqualError(t, err, "1 of 2 pipeline(s) failed")
+	require.Len(t, inconsistencies, 1)
+	assert.Equal(t, plan.InconsistencyMissing, inconsistencies[0].Kind)
+	assert.Equal(t, "public", inconsistencies[0].Pipeline)
+}
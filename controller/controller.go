@@ -18,6 +18,8 @@ package controller
 
 import (
 	"context"
+	"io"
+	"os"
 	"sync"
 	"time"
 
@@ -32,7 +34,7 @@ import (
 )
 
 var (
-	registryErrorsTotal = prometheus.NewCounter(
+	registryErrorsTotal = newCounter(
 		prometheus.CounterOpts{
 			Namespace: "external_dns",
 			Subsystem: "registry",
@@ -40,7 +42,7 @@ var (
 			Help:      "Number of Registry errors.",
 		},
 	)
-	sourceErrorsTotal = prometheus.NewCounter(
+	sourceErrorsTotal = newCounter(
 		prometheus.CounterOpts{
 			Namespace: "external_dns",
 			Subsystem: "source",
@@ -48,7 +50,7 @@ var (
 			Help:      "Number of Source errors.",
 		},
 	)
-	sourceEndpointsTotal = prometheus.NewGauge(
+	sourceEndpointsTotal = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "source",
@@ -56,7 +58,7 @@ var (
 			Help:      "Number of Endpoints in all sources",
 		},
 	)
-	registryEndpointsTotal = prometheus.NewGauge(
+	registryEndpointsTotal = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "registry",
@@ -64,7 +66,7 @@ var (
 			Help:      "Number of Endpoints in the registry",
 		},
 	)
-	lastSyncTimestamp = prometheus.NewGauge(
+	lastSyncTimestamp = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "controller",
@@ -72,7 +74,7 @@ var (
 			Help:      "Timestamp of last successful sync with the DNS provider",
 		},
 	)
-	controllerNoChangesTotal = prometheus.NewCounter(
+	controllerNoChangesTotal = newCounter(
 		prometheus.CounterOpts{
 			Namespace: "external_dns",
 			Subsystem: "controller",
@@ -94,7 +96,7 @@ var (
 			Help:      "Number of Source errors.",
 		},
 	)
-	registryARecords = prometheus.NewGauge(
+	registryARecords = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "registry",
@@ -102,7 +104,7 @@ var (
 			Help:      "Number of Registry A records.",
 		},
 	)
-	registryAAAARecords = prometheus.NewGauge(
+	registryAAAARecords = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "registry",
@@ -110,7 +112,7 @@ var (
 			Help:      "Number of Registry AAAA records.",
 		},
 	)
-	sourceARecords = prometheus.NewGauge(
+	sourceARecords = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "source",
@@ -118,7 +120,7 @@ var (
 			Help:      "Number of Source A records.",
 		},
 	)
-	sourceAAAARecords = prometheus.NewGauge(
+	sourceAAAARecords = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "source",
@@ -126,7 +128,7 @@ var (
 			Help:      "Number of Source AAAA records.",
 		},
 	)
-	verifiedARecords = prometheus.NewGauge(
+	verifiedARecords = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "controller",
@@ -134,7 +136,7 @@ var (
 			Help:      "Number of DNS A-records that exists both in source and registry.",
 		},
 	)
-	verifiedAAAARecords = prometheus.NewGauge(
+	verifiedAAAARecords = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
 			Subsystem: "controller",
@@ -142,6 +144,14 @@ var (
 			Help:      "Number of DNS AAAA-records that exists both in source and registry.",
 		},
 	)
+	recordTypeConflicts = newGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "record_type_conflicts",
+			Help:      "Number of desired records not created because they would coexist with a CNAME record of the same name.",
+		},
+	)
 )
 
 func init() {
@@ -159,6 +169,7 @@ func init() {
 	prometheus.MustRegister(sourceAAAARecords)
 	prometheus.MustRegister(verifiedARecords)
 	prometheus.MustRegister(verifiedAAAARecords)
+	prometheus.MustRegister(recordTypeConflicts)
 }
 
 // Controller is responsible for orchestrating the different components.
@@ -178,44 +189,68 @@ type Controller struct {
 	DomainFilter endpoint.DomainFilterInterface
 	// The nextRunAt used for throttling and batching reconciliation
 	nextRunAt time.Time
-	// The nextRunAtMux is for atomic updating of nextRunAt
+	// The backoffUntil is the time before which no reconciliation happens after a failure
+	backoffUntil time.Time
+	// The nextRunAtMux is for atomic updating of nextRunAt and backoffUntil
 	nextRunAtMux sync.Mutex
 	// DNS record types that will be considered for management
 	ManagedRecordTypes []string
 	// MinEventSyncInterval is used as window for batching events
 	MinEventSyncInterval time.Duration
+	// ConflictResolver decides which resource acquires a DNS name claimed by several resources
+	ConflictResolver plan.ConflictResolver
+	// OwnerID of the registry, used to report the changes of records owned by somebody else as skipped when planning
+	OwnerID string
+	// AdoptOwnerIDs are the owners whose desired records are taken over by OwnerID
+	AdoptOwnerIDs []string
+	// AdoptAnnotated lets the desired records labeled with endpoint.AdoptLabelKey take over the records of any owner
+	AdoptAnnotated bool
+	// PlanOutput is the format in which the planned changes are printed, nothing is printed when empty
+	PlanOutput string
+	// PlanOutputWriter is where the planned changes are printed, defaults to stdout
+	PlanOutputWriter io.Writer
+	// StatusReporters are told the outcome of the synchronization of every desired endpoint
+	StatusReporters []StatusReporter
+	// Events emits Kubernetes Events on the objects the endpoints come from, no events are emitted when nil
+	Events *EventEmitter
+	// Name of the pipeline the controller runs, it labels the metrics and logs of the controller
+	Name string
+	// IsolateErrors retries the errors which are otherwise fatal, so that a failing pipeline doesn't stop the other ones
+	IsolateErrors bool
+	// failures holds the records whose change failed, to retry them with backoff
+	failures recordFailures
 }
 
 // RunOnce runs a single iteration of a reconciliation loop.
 func (c *Controller) RunOnce(ctx context.Context) error {
 	records, err := c.Registry.Records(ctx)
 	if err != nil {
-		registryErrorsTotal.Inc()
+		c.counter(registryErrorsTotal).Inc()
 		deprecatedRegistryErrors.Inc()
 		return err
 	}
 
 	missingRecords := c.Registry.MissingRecords()
 
-	registryEndpointsTotal.Set(float64(len(records)))
+	c.gauge(registryEndpointsTotal).Set(float64(len(records)))
 	regARecords, regAAAARecords := countAddressRecords(records)
-	registryARecords.Set(float64(regARecords))
-	registryAAAARecords.Set(float64(regAAAARecords))
+	c.gauge(registryARecords).Set(float64(regARecords))
+	c.gauge(registryAAAARecords).Set(float64(regAAAARecords))
 	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
 
 	endpoints, err := c.Source.Endpoints(ctx)
 	if err != nil {
-		sourceErrorsTotal.Inc()
+		c.counter(sourceErrorsTotal).Inc()
 		deprecatedSourceErrors.Inc()
 		return err
 	}
-	sourceEndpointsTotal.Set(float64(len(endpoints)))
+	c.gauge(sourceEndpointsTotal).Set(float64(len(endpoints)))
 	srcARecords, srcAAAARecords := countAddressRecords(endpoints)
-	sourceARecords.Set(float64(srcARecords))
-	sourceAAAARecords.Set(float64(srcAAAARecords))
+	c.gauge(sourceARecords).Set(float64(srcARecords))
+	c.gauge(sourceAAAARecords).Set(float64(srcAAAARecords))
 	vARecords, vAAAARecords := countMatchingAddressRecords(endpoints, records)
-	verifiedARecords.Set(float64(vARecords))
-	verifiedAAAARecords.Set(float64(vAAAARecords))
+	c.gauge(verifiedARecords).Set(float64(vARecords))
+	c.gauge(verifiedAAAARecords).Set(float64(vAAAARecords))
 	endpoints = c.Registry.AdjustEndpoints(endpoints)
 
 	if len(missingRecords) > 0 {
@@ -233,7 +268,7 @@ func (c *Controller) RunOnce(ctx context.Context) error {
 		if missingRecordsPlan.Changes.HasChanges() {
 			err = c.Registry.ApplyChanges(ctx, missingRecordsPlan.Changes)
 			if err != nil {
-				registryErrorsTotal.Inc()
+				c.counter(registryErrorsTotal).Inc()
 				deprecatedRegistryErrors.Inc()
 				return err
 			}
@@ -241,6 +276,24 @@ func (c *Controller) RunOnce(ctx context.Context) error {
 		}
 	}
 
+	if ownershipChanges := c.Registry.OwnershipChanges(); ownershipChanges != nil {
+		// The ownership records the registry changes on its own are subject to the domain filter and the policy as well.
+		ownershipChanges = filterChangesForDomains(ownershipChanges, endpoint.MatchAllDomainFilters{c.DomainFilter, c.Registry.GetDomainFilter()})
+		ownershipChanges = c.Policy.Apply(ownershipChanges)
+		if ownershipChanges.HasChanges() {
+			if c.PlanOutput != "" {
+				c.explainPlan(&plan.Plan{Changes: ownershipChanges})
+			}
+			err = c.Registry.ApplyChanges(ctx, ownershipChanges)
+			if err != nil {
+				c.counter(registryErrorsTotal).Inc()
+				deprecatedRegistryErrors.Inc()
+				return err
+			}
+			c.logger().Info("All ownership records are updated")
+		}
+	}
+
 	plan := &plan.Plan{
 		Policies:           []plan.Policy{c.Policy},
 		Current:            records,
@@ -248,26 +301,93 @@ func (c *Controller) RunOnce(ctx context.Context) error {
 		DomainFilter:       endpoint.MatchAllDomainFilters{c.DomainFilter, c.Registry.GetDomainFilter()},
 		PropertyComparator: c.Registry.PropertyValuesEqual,
 		ManagedRecords:     c.ManagedRecordTypes,
+		ConflictResolver:   c.ConflictResolver,
+		OwnerID:            c.OwnerID,
+		AdoptOwnerIDs:      c.AdoptOwnerIDs,
+		AdoptAnnotated:     c.AdoptAnnotated,
 	}
 
 	plan = plan.Calculate()
+	c.gauge(recordTypeConflicts).Set(float64(countRecordTypeConflicts(plan.Skipped)))
+
+	if c.PlanOutput != "" {
+		c.explainPlan(plan)
+	}
+
+	c.failures.prune(plan.PreChanges, plan.Changes)
+	c.gauge(failingRecords).Set(float64(len(c.failures.records)))
+
+	if plan.PreChanges.HasChanges() {
+		// Records replaced by a record of another type are deleted before the actual
+		// plan is applied, since a CNAME record cannot coexist with any other record.
+		if err = c.applyChanges(ctx, plan.PreChanges); err != nil {
+			return err
+		}
+	}
 
 	if plan.Changes.HasChanges() {
-		err = c.Registry.ApplyChanges(ctx, plan.Changes)
-		if err != nil {
-			registryErrorsTotal.Inc()
-			deprecatedRegistryErrors.Inc()
+		if err = c.applyChanges(ctx, plan.Changes); err != nil {
 			return err
 		}
-	} else {
-		controllerNoChangesTotal.Inc()
-		log.Info("All records are already up to date")
+	} else if !plan.PreChanges.HasChanges() {
+		c.counter(controllerNoChangesTotal).Inc()
+		c.logger().Info("All records are already up to date")
 	}
 
-	lastSyncTimestamp.SetToCurrentTime()
+	c.reportStatus(ctx, endpoints, plan)
+
+	c.gauge(lastSyncTimestamp).SetToCurrentTime()
 	return nil
 }
 
+// explainPlan prints the planned changes in the configured format.
+func (c *Controller) explainPlan(p *plan.Plan) {
+	w := c.PlanOutputWriter
+	if w == nil {
+		w = os.Stdout
+	}
+	changes := *p.Changes
+	if p.PreChanges != nil {
+		changes.Delete = append(append([]*endpoint.Endpoint{}, p.PreChanges.Delete...), changes.Delete...)
+	}
+	if err := changes.Explain(w, c.PlanOutput, p.Skipped); err != nil {
+		log.Errorf("Failed to print the planned changes: %v", err)
+	}
+}
+
+// filterChangesForDomains returns the changes of the records matching the domain filter
+func filterChangesForDomains(changes *plan.Changes, domainFilter endpoint.DomainFilterInterface) *plan.Changes {
+	filtered := &plan.Changes{}
+	for _, ep := range changes.Create {
+		if domainFilter.Match(ep.DNSName) {
+			filtered.Create = append(filtered.Create, ep)
+		}
+	}
+	for i := range changes.UpdateOld {
+		if i < len(changes.UpdateNew) && domainFilter.Match(changes.UpdateOld[i].DNSName) {
+			filtered.UpdateOld = append(filtered.UpdateOld, changes.UpdateOld[i])
+			filtered.UpdateNew = append(filtered.UpdateNew, changes.UpdateNew[i])
+		}
+	}
+	for _, ep := range changes.Delete {
+		if domainFilter.Match(ep.DNSName) {
+			filtered.Delete = append(filtered.Delete, ep)
+		}
+	}
+	return filtered
+}
+
+// countRecordTypeConflicts counts the records the plan refused to create because of a CNAME record of the same name.
+func countRecordTypeConflicts(skipped []*plan.SkippedEndpoint) int {
+	count := 0
+	for _, s := range skipped {
+		if s.Reason == plan.SkipReasonRecordTypeConflict {
+			count++
+		}
+	}
+	return count
+}
+
 // Counts the intersections of A and AAAA records in endpoint and registry.
 func countMatchingAddressRecords(endpoints []*endpoint.Endpoint, registryRecords []*endpoint.Endpoint) (int, int) {
 	recordsMap := make(map[string]map[string]struct{})
@@ -322,22 +442,41 @@ func (c *Controller) ScheduleRunOnce(now time.Time) {
 func (c *Controller) ShouldRunOnce(now time.Time) bool {
 	c.nextRunAtMux.Lock()
 	defer c.nextRunAtMux.Unlock()
-	if now.Before(c.nextRunAt) {
+	if now.Before(c.nextRunAt) || now.Before(c.backoffUntil) {
 		return false
 	}
 	c.nextRunAt = now.Add(c.Interval)
 	return true
 }
 
-// Run runs RunOnce in a loop with a delay until context is canceled
+// logger returns the logger of the controller, which tells the pipeline when there are several of them
+func (c *Controller) logger() log.FieldLogger {
+	if c.Name == "" {
+		return log.StandardLogger()
+	}
+	return log.WithField("pipeline", c.Name)
+}
+
+// Run runs RunOnce in a loop with a delay until context is canceled.
+// Retriable errors are retried with exponential backoff, any other error is fatal unless IsolateErrors is set.
 func (c *Controller) Run(ctx context.Context) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
+	failures := 0
 	for {
 		if c.ShouldRunOnce(time.Now()) {
-			if err := c.RunOnce(ctx); err != nil {
-				log.Fatal(err)
+			if err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				if !isRetriable(err) && !c.IsolateErrors {
+					log.Fatal(err)
+				}
+				failures++
+				delay := retryBackoff(failures)
+				c.logger().Errorf("Failed to synchronize the DNS records (%d failure(s) in a row), retrying in %s: %v", failures, delay.Round(time.Second), err)
+				c.retryAt(time.Now().Add(delay))
+			} else {
+				failures = 0
 			}
+			c.gauge(consecutiveFailedRuns).Set(float64(failures))
 		}
 		select {
 		case <-ticker.C:
@@ -17,6 +17,7 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"math"
@@ -311,6 +312,46 @@ func testControllerFiltersDomains(t *testing.T, configuredEndpoints []*endpoint.
 	}
 }
 
+func TestRunOncePlanOutput(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "create-record.used.tld",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			Labels:     endpoint.Labels{endpoint.ResourceLabelKey: "service/default/create"},
+		},
+		{
+			DNSName:    "create-record.unused.tld",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			Labels:     endpoint.Labels{endpoint.ResourceLabelKey: "service/default/unused"},
+		},
+	}, nil)
+
+	provider := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	output := new(bytes.Buffer)
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"used.tld"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		PlanOutput:         plan.OutputFormatText,
+		PlanOutputWriter:   output,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, `Plan: 1 to create, 0 to update, 0 to delete, 1 skipped
++ create create-record.used.tld A [1.2.3.4] (resource=service/default/create)
+! skip   create-record.unused.tld A [1.2.3.4] (resource=service/default/unused): domain-filter
+`, output.String())
+	require.Len(t, provider.ApplyChangesCalls, 1)
+}
+
 type noopRegistryWithMissing struct {
 	*registry.NoopRegistry
 	missingRecords []*endpoint.Endpoint
@@ -320,6 +361,82 @@ func (r *noopRegistryWithMissing) MissingRecords() []*endpoint.Endpoint {
 	return r.missingRecords
 }
 
+type noopRegistryWithOwnershipChanges struct {
+	*registry.NoopRegistry
+	ownershipChanges *plan.Changes
+}
+
+func (r *noopRegistryWithOwnershipChanges) OwnershipChanges() *plan.Changes {
+	return r.ownershipChanges
+}
+
+func TestControllerAppliesOwnershipChanges(t *testing.T) {
+	oldTXT := endpoint.NewEndpoint("some-record.used.tld", endpoint.RecordTypeTXT, "old")
+	newTXT := endpoint.NewEndpoint("some-record.used.tld", endpoint.RecordTypeTXT, "new")
+	orphanTXT := endpoint.NewEndpoint("orphan.used.tld", endpoint.RecordTypeTXT, "orphan")
+
+	for _, tc := range []struct {
+		title          string
+		policy         plan.Policy
+		expectedDelete []*endpoint.Endpoint
+	}{
+		{"sync", &plan.SyncPolicy{}, []*endpoint.Endpoint{orphanTXT}},
+		{"upsert-only", &plan.UpsertOnlyPolicy{}, nil},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			source := new(testutils.MockSource)
+			source.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+			provider := &filteredMockProvider{}
+			noop, err := registry.NewNoopRegistry(provider)
+			require.NoError(t, err)
+			output := new(bytes.Buffer)
+
+			ctrl := &Controller{
+				Source: source,
+				Registry: &noopRegistryWithOwnershipChanges{
+					NoopRegistry: noop,
+					ownershipChanges: &plan.Changes{
+						UpdateOld: []*endpoint.Endpoint{oldTXT},
+						UpdateNew: []*endpoint.Endpoint{newTXT},
+						Delete:    []*endpoint.Endpoint{orphanTXT},
+					},
+				},
+				Policy:             tc.policy,
+				ManagedRecordTypes: []string{endpoint.RecordTypeA},
+				PlanOutput:         plan.OutputFormatText,
+				PlanOutputWriter:   output,
+			}
+
+			require.NoError(t, ctrl.RunOnce(context.Background()))
+			require.Len(t, provider.ApplyChangesCalls, 1)
+			assert.Equal(t, []*endpoint.Endpoint{oldTXT}, provider.ApplyChangesCalls[0].UpdateOld)
+			assert.Equal(t, []*endpoint.Endpoint{newTXT}, provider.ApplyChangesCalls[0].UpdateNew)
+			assert.Equal(t, tc.expectedDelete, provider.ApplyChangesCalls[0].Delete)
+			assert.Contains(t, output.String(), "~ update some-record.used.tld TXT [old] -> [new]")
+		})
+	}
+}
+
+func TestFilterChangesForDomains(t *testing.T) {
+	used := endpoint.NewEndpoint("used.tld", endpoint.RecordTypeTXT, "used")
+	usedNew := endpoint.NewEndpoint("used.tld", endpoint.RecordTypeTXT, "new")
+	unused := endpoint.NewEndpoint("unused.tld", endpoint.RecordTypeTXT, "unused")
+	unusedNew := endpoint.NewEndpoint("unused.tld", endpoint.RecordTypeTXT, "new")
+
+	filtered := filterChangesForDomains(&plan.Changes{
+		Create:    []*endpoint.Endpoint{used, unused},
+		UpdateOld: []*endpoint.Endpoint{unused, used},
+		UpdateNew: []*endpoint.Endpoint{unusedNew, usedNew},
+		Delete:    []*endpoint.Endpoint{unused, used},
+	}, endpoint.NewDomainFilter([]string{"used.tld"}))
+	assert.Equal(t, &plan.Changes{
+		Create:    []*endpoint.Endpoint{used},
+		UpdateOld: []*endpoint.Endpoint{used},
+		UpdateNew: []*endpoint.Endpoint{usedNew},
+		Delete:    []*endpoint.Endpoint{used},
+	}, filtered)
+}
+
 func testControllerFiltersDomainsWithMissing(t *testing.T, configuredEndpoints []*endpoint.Endpoint, domainFilter endpoint.DomainFilterInterface, providerEndpoints, missingEndpoints []*endpoint.Endpoint, expectedChanges []*plan.Changes) {
 	t.Helper()
 	cfg := externaldns.NewConfig()
@@ -737,6 +854,78 @@ func TestMissingRecordsApply(t *testing.T) {
 		})
 }
 
+func TestRecordTypeChangeApply(t *testing.T) {
+	testControllerFiltersDomains(
+		t,
+		[]*endpoint.Endpoint{
+			{
+				DNSName:    "record.used.tld",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"1.2.3.4"},
+			},
+		},
+		endpoint.NewDomainFilter([]string{"used.tld"}),
+		[]*endpoint.Endpoint{
+			{
+				DNSName:    "record.used.tld",
+				RecordType: endpoint.RecordTypeCNAME,
+				Targets:    endpoint.Targets{"lb.used.tld"},
+			},
+		},
+		[]*plan.Changes{
+			// The CNAME record is deleted before the A record is created.
+			{
+				Delete: []*endpoint.Endpoint{
+					{
+						DNSName:    "record.used.tld",
+						RecordType: endpoint.RecordTypeCNAME,
+						Targets:    endpoint.Targets{"lb.used.tld"},
+					},
+				},
+			},
+			{
+				Create: []*endpoint.Endpoint{
+					{
+						DNSName:    "record.used.tld",
+						RecordType: endpoint.RecordTypeA,
+						Targets:    endpoint.Targets{"1.2.3.4"},
+					},
+				},
+			},
+		})
+}
+
+func TestRecordTypeConflicts(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "record.used.tld",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+		},
+		{
+			DNSName:    "record.used.tld",
+			RecordType: endpoint.RecordTypeCNAME,
+			Targets:    endpoint.Targets{"lb.used.tld"},
+		},
+	}, nil)
+
+	provider := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Empty(t, provider.ApplyChangesCalls)
+	assert.Equal(t, math.Float64bits(2), valueFromMetric(recordTypeConflicts))
+}
+
 func TestAAAARecords(t *testing.T) {
 	testControllerFiltersDomains(
 		t,
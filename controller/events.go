@@ -0,0 +1,202 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Reasons of the events emitted on the objects the endpoints come from
+const (
+	EventReasonRecordCreated = "RecordCreated"
+	EventReasonRecordUpdated = "RecordUpdated"
+	EventReasonRecordDeleted = "RecordDeleted"
+	EventReasonRecordSkipped = "RecordSkipped"
+	EventReasonRecordFailed  = "RecordFailed"
+)
+
+// eventComponent is the source component of the emitted events
+const eventComponent = "external-dns"
+
+// EventRecorder records an event on a Kubernetes object
+type EventRecorder interface {
+	Event(ctx context.Context, ref *corev1.ObjectReference, eventType, reason, message string)
+}
+
+// kubeEventRecorder creates the events with the Kubernetes API
+type kubeEventRecorder struct {
+	client kubernetes.Interface
+}
+
+// NewKubeEventRecorder returns an EventRecorder creating the events with the given client
+func NewKubeEventRecorder(client kubernetes.Interface) EventRecorder {
+	return &kubeEventRecorder{client: client}
+}
+
+// Event creates an event on the object, the failures are logged since the events are informational
+func (r *kubeEventRecorder) Event(ctx context.Context, ref *corev1.ObjectReference, eventType, reason, message string) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", ref.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject:      *ref,
+		Reason:              reason,
+		Message:             message,
+		Type:                eventType,
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		Source:              corev1.EventSource{Component: eventComponent},
+		ReportingController: eventComponent,
+	}
+	if _, err := r.client.CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		log.Warnf("Failed to emit event %s on %s %s/%s: %v", reason, ref.Kind, ref.Namespace, ref.Name, err)
+	}
+}
+
+// ReferenceResolver resolves the resource label of the endpoints to the Kubernetes object they come from
+type ReferenceResolver interface {
+	ResolveReference(ctx context.Context, resource string) (*corev1.ObjectReference, error)
+}
+
+// eventKey identifies a record of an object for which an event was emitted
+type eventKey struct {
+	resource string
+	record   recordKey
+}
+
+// EventEmitter emits Kubernetes Events on the objects the endpoints come from when their records
+// are created, updated or deleted, or when they are skipped or fail to be applied.
+type EventEmitter struct {
+	recorder EventRecorder
+	resolver ReferenceResolver
+	// reported holds the message of the last warning emitted for every skipped or failed record,
+	// so that it is emitted once instead of in every reconciliation loop
+	reported map[eventKey]string
+	// references memoizes the objects resolved during the current reconciliation loop, so that every
+	// object is looked up once however many records it has
+	references map[string]*resolvedReference
+}
+
+// resolvedReference is the outcome of the resolution of a resource label
+type resolvedReference struct {
+	ref *corev1.ObjectReference
+	err error
+}
+
+// NewEventEmitter returns an EventEmitter recording the events with the given recorder
+func NewEventEmitter(recorder EventRecorder, resolver ReferenceResolver) *EventEmitter {
+	return &EventEmitter{
+		recorder:   recorder,
+		resolver:   resolver,
+		reported:   map[eventKey]string{},
+		references: map[string]*resolvedReference{},
+	}
+}
+
+// emit records an event on the object the endpoint comes from, if it is known
+func (e *EventEmitter) emit(ctx context.Context, ep *endpoint.Endpoint, eventType, reason, message string) {
+	resource := ep.Labels[endpoint.ResourceLabelKey]
+	if resource == "" {
+		return
+	}
+	resolved, ok := e.references[resource]
+	if !ok {
+		resolved = &resolvedReference{}
+		resolved.ref, resolved.err = e.resolver.ResolveReference(ctx, resource)
+		e.references[resource] = resolved
+	}
+	if resolved.err != nil {
+		log.Debugf("Not emitting event %s for %s %s: %v", reason, ep.DNSName, ep.RecordType, resolved.err)
+		return
+	}
+	e.recorder.Event(ctx, resolved.ref, eventType, reason, message)
+}
+
+// emitChanges emits an event for every change which was applied, i.e. which is not waiting to be retried after a failure
+func (e *EventEmitter) emitChanges(ctx context.Context, changes *plan.Changes, failures recordFailures) {
+	applied := func(ep *endpoint.Endpoint) bool {
+		_, failed := failures.records[newRecordKey(ep)]
+		return !failed
+	}
+	for _, ep := range changes.Create {
+		if applied(ep) {
+			e.emit(ctx, ep, corev1.EventTypeNormal, EventReasonRecordCreated, fmt.Sprintf("Created %s record %s with targets %s", ep.RecordType, ep.DNSName, ep.Targets))
+		}
+	}
+	for _, ep := range changes.UpdateNew {
+		if applied(ep) {
+			e.emit(ctx, ep, corev1.EventTypeNormal, EventReasonRecordUpdated, fmt.Sprintf("Updated %s record %s to targets %s", ep.RecordType, ep.DNSName, ep.Targets))
+		}
+	}
+	for _, ep := range changes.Delete {
+		if applied(ep) {
+			e.emit(ctx, ep, corev1.EventTypeNormal, EventReasonRecordDeleted, fmt.Sprintf("Deleted %s record %s", ep.RecordType, ep.DNSName))
+		}
+	}
+}
+
+// emitWarnings emits a warning for every record skipped because of its ownership or failing to be applied.
+// A warning is emitted again only when its message changes or after the record recovered.
+func (e *EventEmitter) emitWarnings(ctx context.Context, results []*endpoint.SyncResult) {
+	reported := map[eventKey]string{}
+	for _, r := range results {
+		var reason, message string
+		switch r.State {
+		case endpoint.EndpointStateConflict:
+			reason = EventReasonRecordSkipped
+			message = fmt.Sprintf("Skipped %s record %s: %s", r.Endpoint.RecordType, r.Endpoint.DNSName, r.Message)
+		case endpoint.EndpointStateFailed:
+			reason = EventReasonRecordFailed
+			message = fmt.Sprintf("Failed to apply %s record %s: %s", r.Endpoint.RecordType, r.Endpoint.DNSName, r.Message)
+		default:
+			continue
+		}
+
+		key := eventKey{resource: r.Endpoint.Labels[endpoint.ResourceLabelKey], record: newRecordKey(r.Endpoint)}
+		reported[key] = message
+		if e.reported[key] != message {
+			e.emit(ctx, r.Endpoint, corev1.EventTypeWarning, reason, message)
+		}
+	}
+	e.reported = reported
+}
+
+// emitEvents emits the events about the outcome of the synchronization
+func (c *Controller) emitEvents(ctx context.Context, p *plan.Plan, results []*endpoint.SyncResult) {
+	// the objects might have been recreated since the last loop
+	c.Events.references = map[string]*resolvedReference{}
+	c.Events.emitChanges(ctx, p.PreChanges.WithoutSkipped(p.Skipped), c.failures)
+	c.Events.emitChanges(ctx, p.Changes.WithoutSkipped(p.Skipped), c.failures)
+	c.Events.emitWarnings(ctx, results)
+}
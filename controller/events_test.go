@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// fakeReferenceResolver resolves every resource label of the form kind/namespace/name to a Service.
+type fakeReferenceResolver struct{}
+
+func (fakeReferenceResolver) ResolveReference(ctx context.Context, resource string) (*corev1.ObjectReference, error) {
+	parts := strings.Split(resource, "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid resource label %q", resource)
+	}
+	return &corev1.ObjectReference{Kind: "Service", APIVersion: "v1", Namespace: parts[1], Name: parts[2]}, nil
+}
+
+// fakeEventRecorder records the events as "type reason message".
+type fakeEventRecorder struct {
+	events []string
+}
+
+func (r *fakeEventRecorder) Event(ctx context.Context, ref *corev1.ObjectReference, eventType, reason, message string) {
+	r.events = append(r.events, fmt.Sprintf("%s %s %s", eventType, reason, message))
+}
+
+// drainEvents returns the events recorded by the fake recorder since the last call.
+func drainEvents(recorder *fakeEventRecorder) []string {
+	events := recorder.events
+	recorder.events = nil
+	return events
+}
+
+func TestRunOnceEmitsEvents(t *testing.T) {
+	newEndpoint := func(name, target, resource string) *endpoint.Endpoint {
+		ep := endpoint.NewEndpoint(name, endpoint.RecordTypeA, target)
+		ep.Labels[endpoint.ResourceLabelKey] = resource
+		return ep
+	}
+	created := newEndpoint("created.used.tld", "1.2.3.4", "service/default/created")
+	failed := newEndpoint("failed.used.tld", "1.2.3.4", "service/default/failed")
+	winner := newEndpoint("shared.used.tld", "1.1.1.1", "service/default/winner")
+	loser := newEndpoint("shared.used.tld", "2.2.2.2", "service/default/loser")
+	filtered := newEndpoint("filtered.unused.tld", "1.2.3.4", "service/default/filtered")
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{created, failed, winner, loser, filtered}, nil)
+
+	p := &partialFailureMockProvider{failing: map[string]bool{"failed.used.tld": true}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	recorder := &fakeEventRecorder{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"used.tld"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Events:             NewEventEmitter(recorder, fakeReferenceResolver{}),
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"Normal RecordCreated Created A record created.used.tld with targets 1.2.3.4",
+		"Normal RecordCreated Created A record shared.used.tld with targets 1.1.1.1",
+		"Warning RecordFailed Failed to apply A record failed.used.tld: invalid record",
+		"Warning RecordSkipped Skipped A record shared.used.tld: lost-conflict",
+	}, drainEvents(recorder))
+
+	// The provider still lacks the records, so they are created again, but the warnings are not repeated.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"Normal RecordCreated Created A record created.used.tld with targets 1.2.3.4",
+		"Normal RecordCreated Created A record shared.used.tld with targets 1.1.1.1",
+	}, drainEvents(recorder))
+}
+
+// countingReferenceResolver counts the resolutions of every resource label.
+type countingReferenceResolver struct {
+	fakeReferenceResolver
+	resolved map[string]int
+}
+
+func (r *countingReferenceResolver) ResolveReference(ctx context.Context, resource string) (*corev1.ObjectReference, error) {
+	r.resolved[resource]++
+	return r.fakeReferenceResolver.ResolveReference(ctx, resource)
+}
+
+func TestRunOnceResolvesEveryObjectOncePerLoop(t *testing.T) {
+	newEndpoint := func(name, recordType, target string) *endpoint.Endpoint {
+		ep := endpoint.NewEndpoint(name, recordType, target)
+		ep.Labels[endpoint.ResourceLabelKey] = "service/default/dual-stack"
+		return ep
+	}
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		newEndpoint("dual-stack.used.tld", endpoint.RecordTypeA, "1.2.3.4"),
+		newEndpoint("dual-stack.used.tld", endpoint.RecordTypeAAAA, "2001:db8::1"),
+	}, nil)
+
+	r, err := registry.NewNoopRegistry(&filteredMockProvider{})
+	require.NoError(t, err)
+
+	resolver := &countingReferenceResolver{resolved: map[string]int{}}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA},
+		Events:             NewEventEmitter(&fakeEventRecorder{}, resolver),
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, map[string]int{"service/default/dual-stack": 1}, resolver.resolved)
+
+	// the object might have been recreated, so it is looked up again in the next loop
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, map[string]int{"service/default/dual-stack": 2}, resolver.resolved)
+}
+
+func TestKubeEventRecorder(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	recorder := NewKubeEventRecorder(client)
+	ref := &corev1.ObjectReference{Kind: "Service", APIVersion: "v1", Namespace: "default", Name: "my-app", UID: "6a4a3b5e"}
+
+	recorder.Event(context.Background(), ref, corev1.EventTypeNormal, EventReasonRecordCreated, "Created A record my-app.used.tld with targets 1.2.3.4")
+
+	events, err := client.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 1)
+	event := events.Items[0]
+	assert.Equal(t, *ref, event.InvolvedObject)
+	assert.Equal(t, corev1.EventTypeNormal, event.Type)
+	assert.Equal(t, EventReasonRecordCreated, event.Reason)
+	assert.Equal(t, "Created A record my-app.used.tld with targets 1.2.3.4", event.Message)
+	assert.Equal(t, "external-dns", event.Source.Component)
+	assert.Equal(t, int32(1), event.Count)
+}
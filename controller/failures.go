@@ -0,0 +1,239 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// minRecordRetryBackoff is the time a record waits to be retried after its change failed for the first time
+	minRecordRetryBackoff = time.Minute
+	// maxRecordRetryBackoff is the longest time a record waits to be retried after its change failed
+	maxRecordRetryBackoff = time.Hour
+)
+
+var (
+	failedRecordsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "failed_records_total",
+			Help:      "Number of record changes the DNS provider failed to apply.",
+		},
+		[]string{"record_type"},
+	)
+	pipelineFailedRecordsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: pipelineSubsystem,
+			Name:      "controller_failed_records_total",
+			Help:      "Number of record changes the DNS provider failed to apply.",
+		},
+		[]string{"pipeline", "record_type"},
+	)
+	failingRecords = newGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "failing_records",
+			Help:      "Number of records whose last change failed and which are waiting to be retried.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(failedRecordsTotal)
+	prometheus.MustRegister(pipelineFailedRecordsTotal)
+	prometheus.MustRegister(failingRecords)
+}
+
+// recordKey identifies a record across the reconciliation loops
+type recordKey struct {
+	dnsName       string
+	setIdentifier string
+	recordType    string
+}
+
+func newRecordKey(ep *endpoint.Endpoint) recordKey {
+	return recordKey{dnsName: ep.DNSName, setIdentifier: ep.SetIdentifier, recordType: ep.RecordType}
+}
+
+// recordFailure tracks the failed attempts to apply the change of a record
+type recordFailure struct {
+	attempts int
+	retryAt  time.Time
+	err      error
+}
+
+// recordFailures holds the records whose change failed, so that they are retried with backoff
+// instead of being sent to the DNS provider in every reconciliation loop.
+type recordFailures struct {
+	records map[recordKey]*recordFailure
+}
+
+// backoff returns the time to wait before the next attempt after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	d := minRecordRetryBackoff
+	for i := 1; i < attempts && d < maxRecordRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRecordRetryBackoff {
+		d = maxRecordRetryBackoff
+	}
+	return d
+}
+
+// prune forgets the failed records which are not part of the given changes anymore,
+// e.g. because the resource they come from was fixed or deleted.
+func (f *recordFailures) prune(changes ...*plan.Changes) {
+	planned := map[recordKey]bool{}
+	for _, c := range changes {
+		for _, ep := range changedEndpoints(c) {
+			planned[newRecordKey(ep)] = true
+		}
+	}
+	for key := range f.records {
+		if !planned[key] {
+			delete(f.records, key)
+		}
+	}
+}
+
+// holdBack returns the changes without the records which are still backing off after a failure
+func (f *recordFailures) holdBack(changes *plan.Changes, now time.Time) *plan.Changes {
+	if len(f.records) == 0 {
+		return changes
+	}
+	waiting := func(ep *endpoint.Endpoint) bool {
+		failure, ok := f.records[newRecordKey(ep)]
+		if ok && now.Before(failure.retryAt) {
+			log.Infof("Postponing the change of %s %s after %d failed attempt(s), next retry at %s", ep.DNSName, ep.RecordType, failure.attempts, failure.retryAt.Format(time.RFC3339))
+			return true
+		}
+		return false
+	}
+
+	held := false
+	result := &plan.Changes{}
+	for _, ep := range changes.Create {
+		if waiting(ep) {
+			held = true
+			continue
+		}
+		result.Create = append(result.Create, ep)
+	}
+	for i := range changes.UpdateNew {
+		if waiting(changes.UpdateNew[i]) {
+			held = true
+			continue
+		}
+		result.UpdateNew = append(result.UpdateNew, changes.UpdateNew[i])
+		result.UpdateOld = append(result.UpdateOld, changes.UpdateOld[i])
+	}
+	for _, ep := range changes.Delete {
+		if waiting(ep) {
+			held = true
+			continue
+		}
+		result.Delete = append(result.Delete, ep)
+	}
+	if !held {
+		return changes
+	}
+	return result
+}
+
+// update records the outcome of applying the changes: the failed records are scheduled
+// for a retry with exponential backoff and the other ones are forgotten.
+func (f *recordFailures) update(changes *plan.Changes, failures []*provider.RecordError, now time.Time) {
+	if f.records == nil {
+		f.records = map[recordKey]*recordFailure{}
+	}
+	failed := map[recordKey]bool{}
+	for _, failure := range failures {
+		key := newRecordKey(failure.Endpoint)
+		failed[key] = true
+
+		r, ok := f.records[key]
+		if !ok {
+			r = &recordFailure{}
+			f.records[key] = r
+		}
+		r.attempts++
+		r.retryAt = now.Add(backoff(r.attempts))
+		r.err = failure.Err
+		log.Errorf("Failed to apply the change of %s %s (attempt %d, next retry at %s): %v", failure.Endpoint.DNSName, failure.Endpoint.RecordType, r.attempts, r.retryAt.Format(time.RFC3339), failure.Err)
+	}
+	for _, ep := range changedEndpoints(changes) {
+		if key := newRecordKey(ep); !failed[key] {
+			delete(f.records, key)
+		}
+	}
+}
+
+// changedEndpoints returns the records created, updated or deleted by the changes
+func changedEndpoints(changes *plan.Changes) []*endpoint.Endpoint {
+	var endpoints []*endpoint.Endpoint
+	endpoints = append(endpoints, changes.Create...)
+	endpoints = append(endpoints, changes.UpdateNew...)
+	endpoints = append(endpoints, changes.Delete...)
+	return endpoints
+}
+
+// applyChanges applies the changes through the registry. Records which failed recently are held
+// back until their backoff expires. When the DNS provider reports that only some records failed,
+// the failures are recorded and no error is returned, so that the other records keep being synchronized.
+func (c *Controller) applyChanges(ctx context.Context, changes *plan.Changes) error {
+	now := time.Now()
+	changes = c.failures.holdBack(changes, now)
+	if !changes.HasChanges() {
+		return nil
+	}
+
+	err := c.Registry.ApplyChanges(ctx, changes)
+	var partial *provider.PartialFailureError
+	if errors.As(err, &partial) {
+		for _, failure := range partial.Failures {
+			if c.Name == "" {
+				failedRecordsTotal.WithLabelValues(failure.Endpoint.RecordType).Inc()
+			} else {
+				pipelineFailedRecordsTotal.WithLabelValues(c.Name, failure.Endpoint.RecordType).Inc()
+			}
+		}
+		c.failures.update(changes, partial.Failures, now)
+		c.gauge(failingRecords).Set(float64(len(c.failures.records)))
+		return nil
+	}
+	if err != nil {
+		c.counter(registryErrorsTotal).Inc()
+		deprecatedRegistryErrors.Inc()
+		return err
+	}
+	c.failures.update(changes, nil, now)
+	c.gauge(failingRecords).Set(float64(len(c.failures.records)))
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// partialFailureMockProvider fails to apply the changes of the records in failing
+type partialFailureMockProvider struct {
+	filteredMockProvider
+	failing map[string]bool
+}
+
+func (p *partialFailureMockProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.ApplyChangesCalls = append(p.ApplyChangesCalls, changes)
+	var failures []*provider.RecordError
+	for _, ep := range changes.Create {
+		if p.failing[ep.DNSName] {
+			failures = append(failures, &provider.RecordError{Endpoint: ep, Err: errors.New("invalid record")})
+		}
+	}
+	if len(failures) > 0 {
+		return provider.NewPartialFailureError(failures...)
+	}
+	return nil
+}
+
+func TestRunOncePartialFailure(t *testing.T) {
+	good := &endpoint.Endpoint{DNSName: "good.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	bad := &endpoint.Endpoint{DNSName: "bad.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{good, bad}, nil)
+
+	p := &partialFailureMockProvider{failing: map[string]bool{"bad.used.tld": true}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	assert.ElementsMatch(t, []*endpoint.Endpoint{good, bad}, p.ApplyChangesCalls[0].Create)
+	assert.Equal(t, math.Float64bits(1), valueFromMetric(failingRecords))
+
+	// The provider still lacks both records, but only the one which did not fail is sent again.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 2)
+	assert.Equal(t, []*endpoint.Endpoint{good}, p.ApplyChangesCalls[1].Create)
+
+	// Once the backoff expired the failed record is retried.
+	ctrl.failures.records[newRecordKey(bad)].retryAt = time.Now().Add(-time.Second)
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 3)
+	assert.ElementsMatch(t, []*endpoint.Endpoint{good, bad}, p.ApplyChangesCalls[2].Create)
+	assert.Equal(t, 2, ctrl.failures.records[newRecordKey(bad)].attempts)
+}
+
+func TestRecordFailuresPrune(t *testing.T) {
+	foo := endpoint.NewEndpoint("foo.used.tld", endpoint.RecordTypeA, "1.2.3.4")
+	bar := endpoint.NewEndpoint("bar.used.tld", endpoint.RecordTypeA, "1.2.3.4")
+
+	f := &recordFailures{}
+	now := time.Now()
+	f.update(&plan.Changes{Create: []*endpoint.Endpoint{foo, bar}}, []*provider.RecordError{{Endpoint: foo, Err: errors.New("foo")}, {Endpoint: bar, Err: errors.New("bar")}}, now)
+	require.Len(t, f.records, 2)
+
+	f.prune(&plan.Changes{Create: []*endpoint.Endpoint{foo}})
+	assert.Len(t, f.records, 1)
+	assert.Contains(t, f.records, newRecordKey(foo))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, backoff(1))
+	assert.Equal(t, 2*time.Minute, backoff(2))
+	assert.Equal(t, 8*time.Minute, backoff(4))
+	assert.Equal(t, time.Hour, backoff(10))
+	assert.Equal(t, time.Hour, backoff(100))
+}
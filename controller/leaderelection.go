@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var leaderElectionIsLeader = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "leader_election_is_leader",
+		Help:      "Whether this instance currently holds the leader election lease (1) or is on standby (0).",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(leaderElectionIsLeader)
+}
+
+// LeaderElectionConfig configures the Lease based leader election used to run
+// several replicas of the controller with a single active writer.
+type LeaderElectionConfig struct {
+	// LeaseName is the name of the Lease object used as lock
+	LeaseName string
+	// LeaseNamespace is the namespace of the Lease object used as lock
+	LeaseNamespace string
+	// Identity uniquely identifies this replica among the candidates
+	Identity string
+	// LeaseDuration is the duration non-leader candidates wait before trying to acquire the lease
+	LeaseDuration time.Duration
+	// RenewDeadline is the duration the leader retries refreshing the lease before giving it up
+	RenewDeadline time.Duration
+	// RetryPeriod is the duration candidates wait between attempts to acquire or renew the lease
+	RetryPeriod time.Duration
+}
+
+// RunWithLeaderElection runs the controller loop only while this replica holds
+// the leader election lease. Replicas that are not leading stay on standby, so the
+// informers of their sources are kept up to date and they can take over immediately.
+// When leadership is lost the controller loop stops and the replica becomes a
+// candidate again, the loop only starts again once the previous one returned.
+// It returns once the context is canceled and the controller loop returned.
+func (c *Controller) RunWithLeaderElection(ctx context.Context, client kubernetes.Interface, cfg LeaderElectionConfig) error {
+	return runWithLeaderElection(ctx, c, client, cfg)
+}
+
+// runner is a controller loop which can be run with leader election
+type runner interface {
+	ScheduleRunOnce(now time.Time)
+	Run(ctx context.Context)
+}
+
+func runWithLeaderElection(ctx context.Context, r runner, client kubernetes.Interface, cfg LeaderElectionConfig) error {
+	if cfg.Identity == "" {
+		return errors.New("leader election identity cannot be empty")
+	}
+
+	// running is held while the controller loop runs. When leadership is lost, the loop is only
+	// notified through its context and may still be in the middle of a synchronization, so it
+	// has to return before the loop of the next leadership starts.
+	var running sync.Mutex
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      cfg.LeaseName,
+			Namespace: cfg.LeaseNamespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: cfg.Identity,
+		},
+	}
+
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            cfg.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				running.Lock()
+				defer running.Unlock()
+
+				log.Infof("Acquired leader election lease %s/%s as %s", cfg.LeaseNamespace, cfg.LeaseName, cfg.Identity)
+				leaderElectionIsLeader.Set(1)
+				// Reconcile right away, the previous leader might have stopped in the middle of an interval.
+				r.ScheduleRunOnce(time.Now())
+				r.Run(ctx)
+			},
+			OnStoppedLeading: func() {
+				log.Infof("Lost leader election lease %s/%s, switching to standby", cfg.LeaseNamespace, cfg.LeaseName)
+				leaderElectionIsLeader.Set(0)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != cfg.Identity {
+					log.Infof("Current leader is %s", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	leaderElectionIsLeader.Set(0)
+	for {
+		// Run blocks until leadership is lost or the context is canceled.
+		le.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			// wait for the controller loop to return
+			running.Lock()
+			defer running.Unlock()
+			return nil
+		default:
+		}
+	}
+}
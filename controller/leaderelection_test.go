@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func testLeaderElectionConfig(identity string) LeaderElectionConfig {
+	return LeaderElectionConfig{
+		LeaseName:      "external-dns",
+		LeaseNamespace: "default",
+		Identity:       identity,
+		LeaseDuration:  2 * time.Second,
+		RenewDeadline:  time.Second,
+		RetryPeriod:    100 * time.Millisecond,
+	}
+}
+
+func TestRunWithLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	p := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	source := &fakeSource{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		Interval:           time.Hour,
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ctrl.RunWithLeaderElection(ctx, client, testLeaderElectionConfig("replica-1"))
+	}()
+
+	require.Eventually(t, func() bool {
+		return valueFromMetric(leaderElectionIsLeader) == math.Float64bits(1)
+	}, 5*time.Second, 50*time.Millisecond)
+
+	lease, err := client.CoordinationV1().Leases("default").Get(context.Background(), "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "replica-1", *lease.Spec.HolderIdentity)
+
+	require.Eventually(t, func() bool {
+		return p.RecordsCallCount > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not stop after context cancellation")
+	}
+	assert.Equal(t, math.Float64bits(0), valueFromMetric(leaderElectionIsLeader))
+}
+
+// slowRunner keeps running for a while after its context is canceled, like a controller
+// loop in the middle of a synchronization.
+type slowRunner struct {
+	mu         sync.Mutex
+	runs       int
+	stopped    int
+	running    int
+	maxRunning int
+}
+
+func (r *slowRunner) ScheduleRunOnce(now time.Time) {}
+
+func (r *slowRunner) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.runs++
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	r.mu.Lock()
+	r.stopped++
+	r.mu.Unlock()
+	time.Sleep(500 * time.Millisecond)
+
+	r.mu.Lock()
+	r.running--
+	r.mu.Unlock()
+}
+
+func (r *slowRunner) stats() (runs, maxRunning int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs, r.maxRunning
+}
+
+func TestRunWithLeaderElectionWaitsForPreviousRun(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := &slowRunner{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runWithLeaderElection(ctx, r, client, testLeaderElectionConfig("replica-1"))
+	}()
+
+	require.Eventually(t, func() bool {
+		runs, _ := r.stats()
+		return runs == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the lease cannot be renewed until the first loop is told to stop, then it is acquired again
+	var failRenewals atomic.Bool
+	failRenewals.Store(true)
+	client.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failRenewals.Load() {
+			return true, nil, errors.New("lease update failed")
+		}
+		return false, nil, nil
+	})
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.stopped == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	failRenewals.Store(false)
+
+	require.Eventually(t, func() bool {
+		runs, _ := r.stats()
+		return runs == 2
+	}, 10*time.Second, 50*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not stop after context cancellation")
+	}
+	_, maxRunning := r.stats()
+	assert.Equal(t, 1, maxRunning)
+}
+
+func TestRunWithLeaderElectionRequiresIdentity(t *testing.T) {
+	ctrl := &Controller{}
+	assert.Error(t, ctrl.RunWithLeaderElection(context.Background(), fake.NewSimpleClientset(), testLeaderElectionConfig("")))
+}
+
+// fakeSource returns no endpoints.
+type fakeSource struct{}
+
+func (s *fakeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return []*endpoint.Endpoint{}, nil
+}
+
+func (s *fakeSource) AddEventHandler(ctx context.Context, handler func()) {}
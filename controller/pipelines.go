@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// pipelineSubsystem is the subsystem of the metrics reported per pipeline
+const pipelineSubsystem = "pipeline"
+
+// pipelineGauges and pipelineCounters hold the metrics of the controller reported per pipeline by the controllers
+// running a pipeline, as external_dns_pipeline_<subsystem>_<name> with a pipeline label, so that the series of a single
+// controller are left unchanged.
+var (
+	pipelineGauges   = map[prometheus.Gauge]*prometheus.GaugeVec{}
+	pipelineCounters = map[prometheus.Counter]*prometheus.CounterVec{}
+)
+
+func init() {
+	for _, g := range pipelineGauges {
+		prometheus.MustRegister(g)
+	}
+	for _, c := range pipelineCounters {
+		prometheus.MustRegister(c)
+	}
+}
+
+// newGauge returns a gauge of the controller, which is also reported per pipeline
+func newGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	g := prometheus.NewGauge(opts)
+	opts.Name = opts.Subsystem + "_" + opts.Name
+	opts.Subsystem = pipelineSubsystem
+	pipelineGauges[g] = prometheus.NewGaugeVec(opts, []string{"pipeline"})
+	return g
+}
+
+// newCounter returns a counter of the controller, which is also reported per pipeline
+func newCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	c := prometheus.NewCounter(opts)
+	opts.Name = opts.Subsystem + "_" + opts.Name
+	opts.Subsystem = pipelineSubsystem
+	pipelineCounters[c] = prometheus.NewCounterVec(opts, []string{"pipeline"})
+	return c
+}
+
+// gauge returns the given gauge, or its series of the pipeline when the controller runs a pipeline
+func (c *Controller) gauge(g prometheus.Gauge) prometheus.Gauge {
+	if c.Name == "" {
+		return g
+	}
+	return pipelineGauges[g].WithLabelValues(c.Name)
+}
+
+// counter returns the given counter, or its series of the pipeline when the controller runs a pipeline
+func (c *Controller) counter(counter prometheus.Counter) prometheus.Counter {
+	if c.Name == "" {
+		return counter
+	}
+	return pipelineCounters[counter].WithLabelValues(c.Name)
+}
+
+// Pipelines are controllers synchronizing the endpoints of the same sources with different
+// DNS providers, registries, domain filters or policies. Every controller runs its own loop,
+// so that the errors of one pipeline neither delay nor stop the other ones.
+type Pipelines []*Controller
+
+// RunOnce runs a single iteration of the reconciliation loop of every pipeline. A failing
+// pipeline doesn't prevent the other ones from running.
+func (p Pipelines) RunOnce(ctx context.Context) error {
+	failed := 0
+	for _, c := range p {
+		if err := c.RunOnce(ctx); err != nil {
+			c.logger().Errorf("Failed to synchronize the DNS records: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d pipeline(s) failed", failed, len(p))
+	}
+	return nil
+}
+
+// ScheduleRunOnce schedules a reconciliation of every pipeline.
+func (p Pipelines) ScheduleRunOnce(now time.Time) {
+	for _, c := range p {
+		c.ScheduleRunOnce(now)
+	}
+}
+
+// Run runs the loops of all pipelines until the context is canceled.
+func (p Pipelines) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, c := range p {
+		wg.Add(1)
+		go func(c *Controller) {
+			defer wg.Done()
+			c.Run(ctx)
+		}(c)
+	}
+	wg.Wait()
+}
+
+// RunWithLeaderElection runs the loops of all pipelines only while this replica holds the
+// leader election lease, see Controller.RunWithLeaderElection.
+func (p Pipelines) RunWithLeaderElection(ctx context.Context, client kubernetes.Interface, cfg LeaderElectionConfig) error {
+	return runWithLeaderElection(ctx, p, client, cfg)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestPipelinesRunOnce(t *testing.T) {
+	public := endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	private := endpoint.NewEndpoint("app.internal.example.org", endpoint.RecordTypeA, "10.0.0.1")
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{public, private}, nil)
+
+	publicProvider := &filteredMockProvider{}
+	publicRegistry, err := registry.NewNoopRegistry(publicProvider)
+	require.NoError(t, err)
+	privateRegistry, err := registry.NewNoopRegistry(&errorMockProvider{})
+	require.NoError(t, err)
+
+	pipelines := Pipelines{
+		{
+			Name:               "public",
+			Source:             source,
+			Registry:           publicRegistry,
+			Policy:             &plan.SyncPolicy{},
+			DomainFilter:       endpoint.NewDomainFilterWithExclusions([]string{"example.org"}, []string{"internal.example.org"}),
+			ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		},
+		{
+			Name:               "private",
+			Source:             source,
+			Registry:           privateRegistry,
+			Policy:             &plan.SyncPolicy{},
+			DomainFilter:       endpoint.NewDomainFilter([]string{"internal.example.org"}),
+			ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		},
+	}
+
+	// The private pipeline fails, but the public one still synchronizes its records.
+	err = pipelines.RunOnce(context.Background())
+	assert.EqualError(t, err, "1 of 2 pipeline(s) failed")
+	require.Len(t, publicProvider.ApplyChangesCalls, 1)
+	assert.Equal(t, []*endpoint.Endpoint{public}, publicProvider.ApplyChangesCalls[0].Create)
+
+	assert.Equal(t, math.Float64bits(2), valueFromMetric(pipelineGauges[sourceEndpointsTotal].WithLabelValues("public")))
+	assert.NotEqual(t, math.Float64bits(0), valueFromMetric(pipelineGauges[lastSyncTimestamp].WithLabelValues("public")))
+	assert.Equal(t, math.Float64bits(0), valueFromMetric(pipelineGauges[lastSyncTimestamp].WithLabelValues("private")))
+}
+
+func TestPipelinesScheduleRunOnce(t *testing.T) {
+	pipelines := Pipelines{{Interval: time.Hour}, {Interval: time.Hour}}
+	now := time.Now()
+	for _, c := range pipelines {
+		require.True(t, c.ShouldRunOnce(now))
+	}
+
+	pipelines.ScheduleRunOnce(now)
+	for _, c := range pipelines {
+		assert.True(t, c.ShouldRunOnce(now))
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/source"
+)
+
+const (
+	// minRetryBackoff is the time waited before retrying a reconciliation which failed for the first time
+	minRetryBackoff = 5 * time.Second
+	// maxRetryBackoff is the longest time waited before retrying a failed reconciliation
+	maxRetryBackoff = 5 * time.Minute
+	// retryJitter is the maximum fraction of the backoff randomly added to it, so that
+	// several instances throttled by the same DNS provider do not retry all at once
+	retryJitter = 0.2
+)
+
+var consecutiveFailedRuns = newGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "consecutive_failed_runs",
+		Help:      "Number of reconcile loops in a row which failed with a retriable error.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(consecutiveFailedRuns)
+}
+
+// isRetriable tells whether the error of a reconciliation is expected to go away on its own,
+// e.g. the DNS provider throttling the requests, a transient network error or informer caches
+// which are not synced yet. The other errors are fatal.
+func isRetriable(err error) bool {
+	if errors.Is(err, provider.ErrSoft) || errors.Is(err, source.ErrCacheNotSynced) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
+
+// retryBackoff returns the time to wait before retrying after the given number of failed reconciliations in a row
+func retryBackoff(failures int) time.Duration {
+	d := minRetryBackoff
+	for i := 1; i < failures && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return wait.Jitter(d, retryJitter)
+}
+
+// retryAt schedules the next reconciliation at the given time. Reconciliations scheduled by events
+// in the meantime are postponed until then.
+func (c *Controller) retryAt(t time.Time) {
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+	c.nextRunAt = t
+	c.backoffUntil = t
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+func TestIsRetriable(t *testing.T) {
+	for _, tc := range []struct {
+		title     string
+		err       error
+		retriable bool
+	}{
+		{"soft error", fmt.Errorf("records retrieval failed: %w", provider.NewSoftError(errors.New("throttled"))), true},
+		{"informer not synced", fmt.Errorf("%w: *v1.Service", source.ErrCacheNotSynced), true},
+		{"deadline exceeded", fmt.Errorf("listing zones: %w", context.DeadlineExceeded), true},
+		{"network error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"api server throttling", apierrors.NewTooManyRequests("slow down", 1), true},
+		{"api server unavailable", apierrors.NewServiceUnavailable("unavailable"), true},
+		{"forbidden", apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, "foo", errors.New("rbac")), false},
+		{"other error", errors.New("invalid configuration"), false},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.retriable, isRetriable(tc.err))
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		failures int
+		min      time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{100, 5 * time.Minute},
+	} {
+		d := retryBackoff(tc.failures)
+		assert.GreaterOrEqual(t, d, tc.min)
+		assert.LessOrEqual(t, d, time.Duration(float64(tc.min)*(1+retryJitter)))
+	}
+}
+
+func TestRetryAtPostponesScheduledRuns(t *testing.T) {
+	now := time.Now()
+	ctrl := &Controller{Interval: time.Hour, MinEventSyncInterval: time.Second}
+
+	ctrl.retryAt(now.Add(time.Minute))
+	ctrl.ScheduleRunOnce(now)
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(2*time.Second)), "events must not cut the backoff short")
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Minute)))
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute+time.Second)))
+}
+
+// failingSource fails with the given error.
+type failingSource struct {
+	err   error
+	calls int32
+}
+
+func (s *failingSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return nil, s.err
+}
+
+func (s *failingSource) AddEventHandler(ctx context.Context, handler func()) {}
+
+func TestRunRetriesRetriableErrors(t *testing.T) {
+	r, err := registry.NewNoopRegistry(&filteredMockProvider{})
+	require.NoError(t, err)
+
+	src := &failingSource{err: provider.NewSoftError(errors.New("throttled"))}
+	ctrl := &Controller{
+		Source:             src,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		Interval:           time.Hour,
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return valueFromMetric(consecutiveFailedRuns) == math.Float64bits(1)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&src.calls))
+	assert.False(t, ctrl.ShouldRunOnce(time.Now()), "the next run should wait for the backoff")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not stop after context cancellation")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// StatusReporter is implemented by the sources which report the outcome of the synchronization
+// on the resources the endpoints come from, e.g. the status of DNSEndpoint resources.
+type StatusReporter interface {
+	ReportStatus(ctx context.Context, results []*endpoint.SyncResult)
+}
+
+// skipReasonStates maps the reasons why the plan skipped a record to the state reported for it
+var skipReasonStates = map[plan.SkipReason]endpoint.EndpointState{
+	plan.SkipReasonDomainFilter:       endpoint.EndpointStateFiltered,
+	plan.SkipReasonUnmanagedType:      endpoint.EndpointStateFiltered,
+	plan.SkipReasonForeignOwner:       endpoint.EndpointStateConflict,
+	plan.SkipReasonConflict:           endpoint.EndpointStateConflict,
+	plan.SkipReasonRecordTypeConflict: endpoint.EndpointStateConflict,
+	plan.SkipReasonPolicy:             endpoint.EndpointStateNotApplied,
+}
+
+// syncResults returns the outcome of the synchronization of every desired endpoint: skipped by the
+// plan, failed to be applied by the DNS provider, or programmed.
+func (c *Controller) syncResults(desired []*endpoint.Endpoint, p *plan.Plan) []*endpoint.SyncResult {
+	skipped := map[*endpoint.Endpoint]*plan.SkippedEndpoint{}
+	// the records planned for update or creation might be copies of the desired endpoints
+	skippedByKey := map[recordKey]*plan.SkippedEndpoint{}
+	for _, s := range p.Skipped {
+		skipped[s.Endpoint] = s
+		if s.Reason == plan.SkipReasonForeignOwner || s.Reason == plan.SkipReasonRecordTypeConflict || s.Reason == plan.SkipReasonPolicy {
+			skippedByKey[newRecordKey(s.Endpoint)] = s
+		}
+	}
+
+	results := make([]*endpoint.SyncResult, 0, len(desired))
+	for _, ep := range desired {
+		result := &endpoint.SyncResult{Endpoint: ep, State: endpoint.EndpointStateProgrammed}
+		s, ok := skipped[ep]
+		if !ok {
+			s, ok = skippedByKey[newRecordKey(ep)]
+		}
+		if ok {
+			result.State = skipReasonStates[s.Reason]
+			result.Message = string(s.Reason)
+		} else if failure, ok := c.failures.records[newRecordKey(ep)]; ok {
+			result.State = endpoint.EndpointStateFailed
+			result.Message = failure.err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// reportStatus tells the status reporters and the event emitter the outcome of the synchronization
+func (c *Controller) reportStatus(ctx context.Context, desired []*endpoint.Endpoint, p *plan.Plan) {
+	if len(c.StatusReporters) == 0 && c.Events == nil {
+		return
+	}
+	results := c.syncResults(desired, p)
+	for _, r := range c.StatusReporters {
+		r.ReportStatus(ctx, results)
+	}
+	if c.Events != nil {
+		c.emitEvents(ctx, p, results)
+	}
+}
+
+// statePrecedence orders the states of a record synchronized by several pipelines: the state reported is the one
+// with the highest precedence, e.g. a record published by a pipeline and filtered out by the other ones is programmed.
+var statePrecedence = map[endpoint.EndpointState]int{
+	endpoint.EndpointStateFiltered:   0,
+	endpoint.EndpointStateProgrammed: 1,
+	endpoint.EndpointStateNotApplied: 2,
+	endpoint.EndpointStateConflict:   3,
+	endpoint.EndpointStateFailed:     4,
+}
+
+// pipelinesStatus merges the outcome of the synchronization of every pipeline before reporting it, so that the
+// pipelines don't overwrite the status reported by each other.
+type pipelinesStatus struct {
+	sync.Mutex
+	reporters []StatusReporter
+	results   map[string][]*endpoint.SyncResult
+}
+
+// pipelineStatusReporter is the status reporter of a single pipeline
+type pipelineStatusReporter struct {
+	name   string
+	status *pipelinesStatus
+}
+
+func (r *pipelineStatusReporter) ReportStatus(ctx context.Context, results []*endpoint.SyncResult) {
+	r.status.Lock()
+	defer r.status.Unlock()
+	r.status.results[r.name] = results
+	merged := r.status.merged()
+	for _, reporter := range r.status.reporters {
+		reporter.ReportStatus(ctx, merged)
+	}
+}
+
+// merged returns the latest outcome of every pipeline, keeping a single result per resource and record
+func (s *pipelinesStatus) merged() []*endpoint.SyncResult {
+	names := make([]string, 0, len(s.results))
+	for name := range s.results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type resourceRecord struct {
+		resource string
+		record   recordKey
+	}
+	var merged []*endpoint.SyncResult
+	index := map[resourceRecord]int{}
+	for _, name := range names {
+		for _, r := range s.results[name] {
+			key := resourceRecord{resource: r.Endpoint.Labels[endpoint.ResourceLabelKey], record: newRecordKey(r.Endpoint)}
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, r)
+			} else if statePrecedence[r.State] > statePrecedence[merged[i].State] {
+				merged[i] = r
+			}
+		}
+	}
+	return merged
+}
+
+// SetStatusReporters makes every pipeline report the outcome of its synchronization to the status reporters. The
+// outcomes of the pipelines are merged, see statePrecedence.
+func (p Pipelines) SetStatusReporters(reporters []StatusReporter) {
+	status := &pipelinesStatus{reporters: reporters, results: make(map[string][]*endpoint.SyncResult, len(p))}
+	for _, c := range p {
+		c.StatusReporters = []StatusReporter{&pipelineStatusReporter{name: c.Name, status: status}}
+	}
+}
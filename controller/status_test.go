@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// recordingStatusReporter records the reported results.
+type recordingStatusReporter struct {
+	results []*endpoint.SyncResult
+}
+
+func (r *recordingStatusReporter) ReportStatus(ctx context.Context, results []*endpoint.SyncResult) {
+	r.results = results
+}
+
+func TestRunOnceReportsStatus(t *testing.T) {
+	programmed := &endpoint.Endpoint{DNSName: "programmed.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	filtered := &endpoint.Endpoint{DNSName: "filtered.unused.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	failed := &endpoint.Endpoint{DNSName: "failed.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	winner := &endpoint.Endpoint{DNSName: "shared.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}}
+	loser := &endpoint.Endpoint{DNSName: "shared.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"2.2.2.2"}}
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{programmed, filtered, failed, winner, loser}, nil)
+
+	p := &partialFailureMockProvider{failing: map[string]bool{"failed.used.tld": true}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	reporter := &recordingStatusReporter{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"used.tld"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		StatusReporters:    []StatusReporter{reporter},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []*endpoint.SyncResult{
+		{Endpoint: programmed, State: endpoint.EndpointStateProgrammed},
+		{Endpoint: filtered, State: endpoint.EndpointStateFiltered, Message: string(plan.SkipReasonDomainFilter)},
+		{Endpoint: failed, State: endpoint.EndpointStateFailed, Message: "invalid record"},
+		{Endpoint: winner, State: endpoint.EndpointStateProgrammed},
+		{Endpoint: loser, State: endpoint.EndpointStateConflict, Message: string(plan.SkipReasonConflict)},
+	}, reporter.results)
+}
+
+func TestRunOnceReportsRecordsHeldBackByPolicy(t *testing.T) {
+	created := &endpoint.Endpoint{DNSName: "created.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	updated := &endpoint.Endpoint{DNSName: "updated.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{created, updated}, nil)
+
+	p := &filteredMockProvider{RecordsStore: []*endpoint.Endpoint{
+		{DNSName: "updated.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"4.3.2.1"}},
+	}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	reporter := &recordingStatusReporter{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.CreateOnlyPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		StatusReporters:    []StatusReporter{reporter},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, []*endpoint.SyncResult{
+		{Endpoint: created, State: endpoint.EndpointStateProgrammed},
+		{Endpoint: updated, State: endpoint.EndpointStateNotApplied, Message: string(plan.SkipReasonPolicy)},
+	}, reporter.results)
+}
+
+func TestPipelinesMergeReportedStatus(t *testing.T) {
+	newResult := func(name string, state endpoint.EndpointState) *endpoint.SyncResult {
+		ep := endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")
+		ep.Labels[endpoint.ResourceLabelKey] = "crd/default/foo"
+		return &endpoint.SyncResult{Endpoint: ep, State: state}
+	}
+
+	reporter := &recordingStatusReporter{}
+	pipelines := Pipelines{{Name: "public"}, {Name: "private"}}
+	pipelines.SetStatusReporters([]StatusReporter{reporter})
+
+	public := []*endpoint.SyncResult{newResult("a.example.org", endpoint.EndpointStateProgrammed), newResult("b.example.org", endpoint.EndpointStateFiltered)}
+	pipelines[0].StatusReporters[0].ReportStatus(context.Background(), public)
+	assert.Equal(t, public, reporter.results)
+
+	private := []*endpoint.SyncResult{newResult("a.example.org", endpoint.EndpointStateFiltered), newResult("b.example.org", endpoint.EndpointStateFailed)}
+	pipelines[1].StatusReporters[0].ReportStatus(context.Background(), private)
+	assert.Equal(t, []*endpoint.SyncResult{public[0], private[1]}, reporter.results)
+}
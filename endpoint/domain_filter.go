@@ -17,6 +17,8 @@ limitations under the License.
 package endpoint
 
 import (
+	"encoding/json"
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -178,3 +180,53 @@ func (df DomainFilter) IsConfigured() bool {
 	}
 	return len(df.Filters) > 0 || len(df.exclude) > 0
 }
+
+type domainFilterSerde struct {
+	Include      []string `json:"include,omitempty"`
+	Exclude      []string `json:"exclude,omitempty"`
+	RegexInclude string   `json:"regexInclude,omitempty"`
+	RegexExclude string   `json:"regexExclude,omitempty"`
+}
+
+// MarshalJSON serializes the DomainFilter so it can be exchanged with out-of-process providers.
+func (df DomainFilter) MarshalJSON() ([]byte, error) {
+	serde := domainFilterSerde{
+		Include: df.Filters,
+		Exclude: df.exclude,
+	}
+	if df.regex != nil {
+		serde.RegexInclude = df.regex.String()
+	}
+	if df.regexExclusion != nil {
+		serde.RegexExclude = df.regexExclusion.String()
+	}
+	return json.Marshal(serde)
+}
+
+// UnmarshalJSON restores a DomainFilter serialized with MarshalJSON.
+func (df *DomainFilter) UnmarshalJSON(b []byte) error {
+	var serde domainFilterSerde
+	if err := json.Unmarshal(b, &serde); err != nil {
+		return err
+	}
+
+	if serde.RegexInclude == "" && serde.RegexExclude == "" {
+		*df = NewDomainFilterWithExclusions(serde.Include, serde.Exclude)
+		return nil
+	}
+
+	if len(serde.Include) > 0 || len(serde.Exclude) > 0 {
+		return fmt.Errorf("cannot have both domain list and regex")
+	}
+
+	regex, err := regexp.Compile(serde.RegexInclude)
+	if err != nil {
+		return fmt.Errorf("invalid regexInclude: %w", err)
+	}
+	regexExclusion, err := regexp.Compile(serde.RegexExclude)
+	if err != nil {
+		return fmt.Errorf("invalid regexExclude: %w", err)
+	}
+	*df = NewRegexDomainFilter(regex, regexExclusion)
+	return nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package endpoint
 
 import (
+	"encoding/json"
 	"regexp"
 	"testing"
 
@@ -458,3 +459,30 @@ func TestDomainFilterIsConfigured(t *testing.T) {
 		})
 	}
 }
+
+func TestDomainFilterJSONRoundTrip(t *testing.T) {
+	for i, tt := range domainFilterTests {
+		var deserialized DomainFilter
+		serialized, err := json.Marshal(NewDomainFilterWithExclusions(tt.domainFilter, tt.exclusions))
+		assert.NoError(t, err, "index %d", i)
+		assert.NoError(t, json.Unmarshal(serialized, &deserialized), "index %d", i)
+		for _, domain := range tt.domains {
+			assert.Equal(t, tt.expected, deserialized.Match(domain), "index %d, domain %s", i, domain)
+		}
+	}
+	for i, tt := range regexDomainFilterTests {
+		var deserialized DomainFilter
+		serialized, err := json.Marshal(NewRegexDomainFilter(tt.regex, tt.regexExclusion))
+		assert.NoError(t, err, "index %d", i)
+		assert.NoError(t, json.Unmarshal(serialized, &deserialized), "index %d", i)
+		for _, domain := range tt.domains {
+			assert.Equal(t, tt.expected, deserialized.Match(domain), "index %d, domain %s", i, domain)
+		}
+	}
+}
+
+func TestDomainFilterUnmarshalJSONErrors(t *testing.T) {
+	var df DomainFilter
+	assert.Error(t, json.Unmarshal([]byte(`{"include":["example.org"],"regexInclude":"example"}`), &df))
+	assert.Error(t, json.Unmarshal([]byte(`{"regexInclude":"("}`), &df))
+}
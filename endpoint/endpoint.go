@@ -249,11 +249,64 @@ type DNSEndpointSpec struct {
 	Endpoints []*Endpoint `json:"endpoints,omitempty"`
 }
 
+// Condition types of a DNSEndpoint
+const (
+	// DNSEndpointAccepted tells whether all the endpoints of the spec are valid
+	DNSEndpointAccepted = "Accepted"
+	// DNSEndpointProgrammed tells whether all the accepted endpoints are published by the DNS provider
+	DNSEndpointProgrammed = "Programmed"
+	// DNSEndpointConflict tells whether some endpoints are not published because the record is held by somebody else
+	DNSEndpointConflict = "Conflict"
+	// DNSEndpointFiltered tells whether some endpoints are not published because of the domain filter or managed record types
+	DNSEndpointFiltered = "Filtered"
+)
+
+// EndpointState is the outcome of the synchronization of an endpoint
+type EndpointState string
+
+const (
+	// EndpointStateProgrammed is used for endpoints published by the DNS provider
+	EndpointStateProgrammed EndpointState = "Programmed"
+	// EndpointStateConflict is used for endpoints whose record is held by another resource or owner
+	EndpointStateConflict EndpointState = "Conflict"
+	// EndpointStateFiltered is used for endpoints left out by the domain filter or managed record types
+	EndpointStateFiltered EndpointState = "Filtered"
+	// EndpointStateFailed is used for endpoints the DNS provider failed to publish
+	EndpointStateFailed EndpointState = "Failed"
+	// EndpointStateInvalid is used for endpoints rejected by the source, e.g. because of an illegal target
+	EndpointStateInvalid EndpointState = "Invalid"
+	// EndpointStateNotApplied is used for endpoints whose change is held back by the policy, e.g. upsert-only or create-only
+	EndpointStateNotApplied EndpointState = "NotApplied"
+)
+
+// EndpointStatus is the outcome of the synchronization of an endpoint of a DNSEndpoint
+type EndpointStatus struct {
+	DNSName       string        `json:"dnsName"`
+	RecordType    string        `json:"recordType,omitempty"`
+	SetIdentifier string        `json:"setIdentifier,omitempty"`
+	State         EndpointState `json:"state"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SyncResult is the outcome of the synchronization of a desired endpoint, as seen by the controller
+type SyncResult struct {
+	Endpoint *Endpoint
+	State    EndpointState
+	Message  string
+}
+
 // DNSEndpointStatus defines the observed state of DNSEndpoint
 type DNSEndpointStatus struct {
 	// The generation observed by the external-dns controller.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions tell whether the endpoints are accepted and published: Accepted, Programmed, Conflict and Filtered.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Endpoints holds the outcome of the synchronization of every endpoint of the spec.
+	// +optional
+	Endpoints []EndpointStatus `json:"endpoints,omitempty"`
 }
 
 // +genclient
@@ -28,6 +28,26 @@ import (
 // ErrInvalidHeritage is returned when heritage was not found, or different heritage is found
 var ErrInvalidHeritage = errors.New("heritage is unknown or not found")
 
+// LabelsFormat is the format of the serialized labels
+type LabelsFormat string
+
+const (
+	// LabelsFormatDefault serializes the labels as "heritage=external-dns,external-dns/<key>=<value>,..."
+	LabelsFormatDefault LabelsFormat = "default"
+	// LabelsFormatCompact serializes the labels as "external-dns=v2,o=<owner>,r=<resource>,<key>=<value>,...",
+	// which is shorter, so that the TXT records stay under the length limits of the DNS providers
+	LabelsFormatCompact LabelsFormat = "compact"
+
+	// compactVersion is the version of the compact format, stored as the value of its heritage token
+	compactVersion = "v2"
+)
+
+// compactKeys are the short keys of the most common labels in the compact format
+var compactKeys = map[string]string{
+	OwnerLabelKey:    "o",
+	ResourceLabelKey: "r",
+}
+
 const (
 	heritage = "external-dns"
 	// OwnerLabelKey is the name of the label that defines the owner of an Endpoint.
@@ -44,6 +64,38 @@ const (
 	// DualstackLabelKey is the name of the label that identifies dualstack endpoints
 	DualstackLabelKey = "dualstack"
 
+	// PriorityLabelKey is the name of the label that holds the priority of the k8s resource which wants to acquire the DNS name.
+	// It is only used to resolve conflicts in the planner and is not persisted in the registry.
+	PriorityLabelKey = "priority"
+
+	// ResourceCreationTimestampLabelKey is the name of the label that holds the creation timestamp of the k8s resource
+	// which wants to acquire the DNS name. It is only used to resolve conflicts in the planner and is not persisted in the registry.
+	ResourceCreationTimestampLabelKey = "resource-creation-timestamp"
+
+	// AdoptLabelKey is the name of the label that takes over the ownership of a record owned by another instance.
+	// It is set to "true" on the desired records asking for it, and to the new owner on the current records the
+	// planner adopted. It is only used in the planner and the registry and is not persisted in the registry.
+	AdoptLabelKey = "adopt"
+
+	// SharedLabelKey is the name of the label that marks a record shared by several owners, each of them contributing
+	// its own targets to it. It is set to "true" on the desired records to share and persisted in the registry.
+	SharedLabelKey = "shared"
+
+	// RetainLabelKey is the name of the label that keeps a record, and its ownership, when it is not desired anymore,
+	// e.g. after its resource is deleted. It is set to "true" on the desired records to retain and persisted in the registry.
+	RetainLabelKey = "retain"
+
+	// TargetsLabelKey is the name of the label that holds the targets the owner contributes to a shared record,
+	// separated by TargetsSeparator. It is persisted in the registry.
+	TargetsLabelKey = "targets"
+
+	// SharedTargetsLabelKey is the name of the label that holds the targets the other owners contribute to a shared
+	// record, separated by TargetsSeparator. It is only used in the planner and is not persisted in the registry.
+	SharedTargetsLabelKey = "shared-targets"
+
+	// TargetsSeparator separates the targets in the values of TargetsLabelKey and SharedTargetsLabelKey
+	TargetsSeparator = ";"
+
 	// txtEncryptionNonce label for keep same nonce for same txt records, for prevent different result of encryption for same txt record, it can cause issues for some providers
 	txtEncryptionNonce = "txt-encryption-nonce"
 )
@@ -61,8 +113,19 @@ func NewLabels() Labels {
 // if heritage set to another value is found then error is returned
 // no heritage automatically assumes is not owned by external-dns and returns invalidHeritage error
 func NewLabelsFromStringPlain(labelText string) (Labels, error) {
-	endpointLabels := map[string]string{}
+	labels, _, err := newLabelsFromStringPlain(labelText)
+	return labels, err
+}
+
+// newLabelsFromStringPlain is like NewLabelsFromStringPlain and returns the format of the labels as well
+func newLabelsFromStringPlain(labelText string) (Labels, LabelsFormat, error) {
 	labelText = strings.Trim(labelText, "\"") // drop quotes
+	if strings.HasPrefix(labelText, heritage+"=") {
+		labels, err := newLabelsFromCompactString(labelText)
+		return labels, LabelsFormatCompact, err
+	}
+
+	endpointLabels := map[string]string{}
 	tokens := strings.Split(labelText, ",")
 	foundExternalDNSHeritage := false
 	for _, token := range tokens {
@@ -72,7 +135,7 @@ func NewLabelsFromStringPlain(labelText string) (Labels, error) {
 		key := strings.Split(token, "=")[0]
 		val := strings.Split(token, "=")[1]
 		if key == "heritage" && val != heritage {
-			return nil, ErrInvalidHeritage
+			return nil, "", ErrInvalidHeritage
 		}
 		if key == "heritage" {
 			foundExternalDNSHeritage = true
@@ -84,41 +147,109 @@ func NewLabelsFromStringPlain(labelText string) (Labels, error) {
 	}
 
 	if !foundExternalDNSHeritage {
-		return nil, ErrInvalidHeritage
+		return nil, "", ErrInvalidHeritage
+	}
+
+	return endpointLabels, LabelsFormatDefault, nil
+}
+
+// newLabelsFromCompactString parses labels in the compact format. The labels of an unknown version of the format
+// are handled like the ones of an unknown heritage.
+func newLabelsFromCompactString(labelText string) (Labels, error) {
+	longKeys := make(map[string]string, len(compactKeys))
+	for key, short := range compactKeys {
+		longKeys[short] = key
 	}
 
+	endpointLabels := map[string]string{}
+	tokens := strings.Split(labelText, ",")
+	if tokens[0] != heritage+"="+compactVersion {
+		log.Debugf("Unsupported version of the labels %q", labelText)
+		return nil, ErrInvalidHeritage
+	}
+	for _, token := range tokens[1:] {
+		key, val, found := strings.Cut(token, "=")
+		if !found {
+			continue
+		}
+		if long, ok := longKeys[key]; ok {
+			key = long
+		}
+		endpointLabels[key] = val
+	}
 	return endpointLabels, nil
 }
 
 func NewLabelsFromString(labelText string, aesKey []byte) (Labels, error) {
+	labels, _, err := NewLabelsFromStringWithKeys(labelText, aesKey, nil)
+	return labels, err
+}
+
+// NewLabelsFromStringWithKeys is like NewLabelsFromString, but falls back to the retired keys when the text cannot
+// be decrypted with aesKey. retired tells whether the text was decrypted with one of the retired keys, in which case
+// the encryption nonce is not kept, so that the labels are encrypted with a new nonce next time.
+func NewLabelsFromStringWithKeys(labelText string, aesKey []byte, retiredKeys [][]byte) (labels Labels, retired bool, err error) {
+	labels, _, retired, err = NewLabelsFromStringWithFormat(labelText, aesKey, retiredKeys)
+	return labels, retired, err
+}
+
+// NewLabelsFromStringWithFormat is like NewLabelsFromStringWithKeys, and returns the format of the labels as well
+func NewLabelsFromStringWithFormat(labelText string, aesKey []byte, retiredKeys [][]byte) (labels Labels, format LabelsFormat, retired bool, err error) {
 	if len(aesKey) != 0 {
 		decryptedText, encryptionNonce, err := DecryptText(strings.Trim(labelText, "\""), aesKey)
 		//in case if we have decryption error, just try process original text
 		//decryption errors should be ignored here, because we can already have plain-text labels in registry
 		if err == nil {
-			labels, err := NewLabelsFromStringPlain(decryptedText)
+			labels, format, err := newLabelsFromStringPlain(decryptedText)
 			if err == nil {
 				labels[txtEncryptionNonce] = encryptionNonce
 			}
 
-			return labels, err
+			return labels, format, false, err
 		}
 	}
-	return NewLabelsFromStringPlain(labelText)
+	for _, key := range retiredKeys {
+		if decryptedText, _, err := DecryptText(strings.Trim(labelText, "\""), key); err == nil {
+			labels, format, err := newLabelsFromStringPlain(decryptedText)
+			return labels, format, true, err
+		}
+	}
+	labels, format, err = newLabelsFromStringPlain(labelText)
+	return labels, format, false, err
 }
 
 // SerializePlain transforms endpoints labels into a external-dns recognizable format string
 // withQuotes adds additional quotes
 func (l Labels) SerializePlain(withQuotes bool) string {
+	return l.SerializePlainWithFormat(withQuotes, LabelsFormatDefault)
+}
+
+// SerializePlainWithFormat is like SerializePlain, in the given format
+func (l Labels) SerializePlainWithFormat(withQuotes bool, format LabelsFormat) string {
 	var tokens []string
-	tokens = append(tokens, fmt.Sprintf("heritage=%s", heritage))
+	if format == LabelsFormatCompact {
+		tokens = append(tokens, fmt.Sprintf("%s=%s", heritage, compactVersion))
+	} else {
+		tokens = append(tokens, fmt.Sprintf("heritage=%s", heritage))
+	}
 	var keys []string
 	for key := range l {
+		if key == PriorityLabelKey || key == ResourceCreationTimestampLabelKey || key == AdoptLabelKey || key == SharedTargetsLabelKey {
+			continue
+		}
 		keys = append(keys, key)
 	}
 	sort.Strings(keys) // sort for consistency
 
 	for _, key := range keys {
+		if format == LabelsFormatCompact {
+			short, ok := compactKeys[key]
+			if !ok {
+				short = key
+			}
+			tokens = append(tokens, fmt.Sprintf("%s=%s", short, l[key]))
+			continue
+		}
 		tokens = append(tokens, fmt.Sprintf("%s/%s=%s", heritage, key, l[key]))
 	}
 	if withQuotes {
@@ -129,8 +260,13 @@ func (l Labels) SerializePlain(withQuotes bool) string {
 
 // Serialize same to SerializePlain, but encrypt data, if encryption enabled
 func (l Labels) Serialize(withQuotes bool, txtEncryptEnabled bool, aesKey []byte) string {
+	return l.SerializeWithFormat(withQuotes, txtEncryptEnabled, aesKey, LabelsFormatDefault)
+}
+
+// SerializeWithFormat is like Serialize, in the given format
+func (l Labels) SerializeWithFormat(withQuotes bool, txtEncryptEnabled bool, aesKey []byte, format LabelsFormat) string {
 	if !txtEncryptEnabled {
-		return l.SerializePlain(withQuotes)
+		return l.SerializePlainWithFormat(withQuotes, format)
 	}
 
 	var encryptionNonce []byte = nil
@@ -139,7 +275,7 @@ func (l Labels) Serialize(withQuotes bool, txtEncryptEnabled bool, aesKey []byte
 		delete(l, txtEncryptionNonce)
 	}
 
-	text := l.SerializePlain(false)
+	text := l.SerializePlainWithFormat(false, format)
 	log.Debugf("Encrypt the serialized text %#v before returning it.", text)
 	var err error
 	text, err = EncryptText(text, aesKey, encryptionNonce)
@@ -79,6 +79,25 @@ func (suite *LabelsSuite) TestEncryptionNonceReUsage() {
 	suite.Equal(serialized, suite.fooAsTextEncrypted, "serialized result should be equal")
 }
 
+func (suite *LabelsSuite) TestDeserializeWithRetiredKeys() {
+	newKey := []byte("new-key-new-key-new-key-new-key-")
+
+	foo, retired, err := NewLabelsFromStringWithKeys(suite.fooAsTextWithQuotesEncrypted, newKey, [][]byte{[]byte("other-key-other-key-other-key-ot"), suite.aesKey})
+	suite.NoError(err, "should succeed for text encrypted with a retired key")
+	suite.True(retired, "should tell that a retired key was used")
+	suite.Equal(suite.foo, foo, "should reconstruct original label map without the encryption nonce")
+
+	foo, retired, err = NewLabelsFromStringWithKeys(suite.fooAsTextWithQuotesEncrypted, suite.aesKey, [][]byte{newKey})
+	suite.NoError(err, "should succeed for text encrypted with the current key")
+	suite.False(retired, "should tell that the current key was used")
+	suite.Equal("foo-owner", foo["owner"])
+
+	foo, retired, err = NewLabelsFromStringWithKeys(suite.fooAsText, newKey, [][]byte{suite.aesKey})
+	suite.NoError(err, "should succeed for plain text")
+	suite.False(retired, "plain text is not encrypted with a retired key")
+	suite.Equal(suite.foo, foo)
+}
+
 func (suite *LabelsSuite) TestDeserialize() {
 	foo, err := NewLabelsFromStringPlain(suite.fooAsText)
 	suite.NoError(err, "should succeed for valid label text")
@@ -121,6 +140,49 @@ func (suite *LabelsSuite) TestDeserialize() {
 	suite.Nil(multipleHeritage, "if error should return nil")
 }
 
+func (suite *LabelsSuite) TestSerializeSkipsPlanningLabels() {
+	foo := Labels{
+		"owner":                           "foo-owner",
+		"resource":                        "foo-resource",
+		PriorityLabelKey:                  "10",
+		ResourceCreationTimestampLabelKey: "2023-01-01T00:00:00Z",
+		AdoptLabelKey:                     "true",
+		SharedTargetsLabelKey:             "1.2.3.4",
+	}
+	suite.Equal(suite.fooAsText, foo.SerializePlain(false), "should not serialize planning labels")
+}
+
+func (suite *LabelsSuite) TestCompactFormat() {
+	fooAsCompactText := "external-dns=v2,o=foo-owner,r=foo-resource"
+	suite.Equal(fooAsCompactText, suite.foo.SerializePlainWithFormat(false, LabelsFormatCompact), "should serialize in the compact format")
+	suite.Equal(fmt.Sprintf(`"%s"`, fooAsCompactText), suite.foo.SerializeWithFormat(true, false, nil, LabelsFormatCompact), "should serialize in the compact format")
+
+	foo, format, retired, err := NewLabelsFromStringWithFormat(fmt.Sprintf(`"%s"`, fooAsCompactText), nil, nil)
+	suite.NoError(err, "should succeed for valid compact label text")
+	suite.Equal(suite.foo, foo, "should reconstruct original label map")
+	suite.Equal(LabelsFormatCompact, format)
+	suite.False(retired)
+
+	_, format, _, err = NewLabelsFromStringWithFormat(suite.fooAsText, nil, nil)
+	suite.NoError(err, "should succeed for valid label text")
+	suite.Equal(LabelsFormatDefault, format)
+
+	encrypted := suite.foo.SerializeWithFormat(true, true, suite.aesKey, LabelsFormatCompact)
+	foo, format, _, err = NewLabelsFromStringWithFormat(encrypted, suite.aesKey, nil)
+	suite.NoError(err, "should succeed for valid encrypted compact label text")
+	suite.Equal("foo-owner", foo[OwnerLabelKey])
+	suite.Equal("foo-resource", foo[ResourceLabelKey])
+	suite.Equal(LabelsFormatCompact, format)
+
+	bar, err := NewLabelsFromStringPlain("external-dns=v2,o=bar-owner,r=bar-resource,new-key=bar-new-key,no-equal-sign")
+	suite.NoError(err, "should succeed for valid compact label text")
+	suite.Equal(suite.barTextAsMap, bar, "should reconstruct original label map")
+
+	unknownVersion, err := NewLabelsFromStringPlain("external-dns=v3,o=foo-owner")
+	suite.Equal(ErrInvalidHeritage, err, "should fail for an unknown version of the compact format")
+	suite.Nil(unknownVersion, "if error should return nil")
+}
+
 func TestLabels(t *testing.T) {
 	suite.Run(t, new(LabelsSuite))
 }
@@ -28,6 +28,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"sigs.k8s.io/external-dns/controller"
@@ -73,6 +75,7 @@ import (
 	"sigs.k8s.io/external-dns/provider/ultradns"
 	"sigs.k8s.io/external-dns/provider/vinyldns"
 	"sigs.k8s.io/external-dns/provider/vultr"
+	"sigs.k8s.io/external-dns/provider/webhook"
 	"sigs.k8s.io/external-dns/registry"
 	"sigs.k8s.io/external-dns/source"
 )
@@ -106,6 +109,18 @@ func main() {
 	go serveMetrics(cfg.MetricsAddress)
 	go handleSigterm(cancel)
 
+	if cfg.WebhookServer {
+		// Only the provider is served, neither the sources nor the access to Kubernetes are needed.
+		_, p, err := newProvider(ctx, cfg, source.NewEmptySource())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := webhook.StartHTTPApi(p, nil, cfg.WebhookProviderReadTimeout, cfg.WebhookProviderWriteTimeout, cfg.WebhookServerAddress); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
 	// error is explicitly ignored because the filter is already validated in validation.ValidateConfig
 	labelSelector, _ := labels.Parse(cfg.LabelFilter)
 
@@ -126,6 +141,10 @@ func main() {
 		PublishInternal:                cfg.PublishInternal,
 		PublishHostIP:                  cfg.PublishHostIP,
 		AlwaysPublishNotReadyAddresses: cfg.AlwaysPublishNotReadyAddresses,
+		PublishPodIPs:                  cfg.PublishPodIPs,
+		PodNamespaceLabelFilter:        cfg.PodNamespaceLabelFilter,
+		PodFQDNTemplate:                cfg.PodFQDNTemplate,
+		UnstructuredResources:          cfg.UnstructuredResources,
 		ConnectorServer:                cfg.ConnectorSourceServer,
 		CRDSourceAPIVersion:            cfg.CRDSourceAPIVersion,
 		CRDSourceKind:                  cfg.CRDSourceKind,
@@ -143,10 +162,10 @@ func main() {
 		OCPRouterName:                  cfg.OCPRouterName,
 		UpdateEvents:                   cfg.UpdateEvents,
 		ResolveLoadBalancerHostname:    cfg.ResolveServiceLoadBalancerHostname,
+		ManagedRecordTypes:             cfg.ManagedDNSRecordTypes,
 	}
 
-	// Lookup all the selected sources by names and pass them the desired configuration.
-	sources, err := source.ByNames(ctx, &source.SingletonClientGenerator{
+	clientGenerator := &source.SingletonClientGenerator{
 		KubeConfig:   cfg.KubeConfig,
 		APIServerURL: cfg.APIServerURL,
 		// If update events are enabled, disable timeout.
@@ -156,7 +175,10 @@ func main() {
 			}
 			return cfg.RequestTimeout
 		}(),
-	}, cfg.Sources, sourceCfg)
+	}
+
+	// Lookup all the selected sources by names and pass them the desired configuration.
+	sources, err := source.ByNames(ctx, clientGenerator, cfg.Sources, sourceCfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -168,6 +190,106 @@ func main() {
 	endpointsSource := source.NewDedupSource(source.NewMultiSource(sources, sourceCfg.DefaultTargets))
 	endpointsSource = source.NewTargetFilterSource(endpointsSource, targetFilter)
 
+	if cfg.PipelinesConfig != "" {
+		pipelines, err := newPipelines(ctx, cfg, clientGenerator, sources, endpointsSource)
+		if err != nil {
+			log.Fatal(err)
+		}
+		run(ctx, cfg, clientGenerator, endpointsSource, pipelines)
+		return
+	}
+
+	domainFilter, p, err := newProvider(ctx, cfg, endpointsSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctrl, err := newController(cfg, clientGenerator, endpointsSource, domainFilter, p)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !cfg.DryRun {
+		// sources like crd report the outcome of the synchronization on their resources
+		for _, s := range sources {
+			if reporter, ok := s.(controller.StatusReporter); ok {
+				ctrl.StatusReporters = append(ctrl.StatusReporters, reporter)
+			}
+		}
+		if cfg.EmitEvents {
+			recorder, resolver, err := newEventRecorder(clientGenerator, sources)
+			if err != nil {
+				log.Fatal(err)
+			}
+			ctrl.Events = controller.NewEventEmitter(recorder, resolver)
+		}
+	}
+
+	run(ctx, cfg, clientGenerator, endpointsSource, ctrl)
+}
+
+// runner is the reconciliation loop of a single controller or of several pipelines
+type runner interface {
+	RunOnce(ctx context.Context) error
+	Check(ctx context.Context) ([]*plan.Inconsistency, error)
+	ScheduleRunOnce(now time.Time)
+	Run(ctx context.Context)
+	RunWithLeaderElection(ctx context.Context, client kubernetes.Interface, cfg controller.LeaderElectionConfig) error
+}
+
+func run(ctx context.Context, cfg *externaldns.Config, clientGenerator source.ClientGenerator, endpointsSource source.Source, r runner) {
+	if cfg.Check {
+		check(ctx, cfg, r)
+	}
+
+	if cfg.Once {
+		err := r.RunOnce(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		os.Exit(0)
+	}
+
+	if cfg.UpdateEvents {
+		// Add RunOnce as the handler function that will be called when ingress/service sources have changed.
+		// Note that k8s Informers will perform an initial list operation, which results in the handler
+		// function initially being called for every Service/Ingress that exists
+		endpointsSource.AddEventHandler(ctx, func() { r.ScheduleRunOnce(time.Now()) })
+	}
+
+	if cfg.LeaderElection {
+		runWithLeaderElection(ctx, cfg, clientGenerator, r)
+		return
+	}
+
+	r.ScheduleRunOnce(time.Now())
+	r.Run(ctx)
+}
+
+// check prints the inconsistencies between the DNS records and the desired ones, and exits with a non-zero code when
+// there are some, so that the drift of the DNS records can gate a deployment
+func check(ctx context.Context, cfg *externaldns.Config, r runner) {
+	inconsistencies, checkErr := r.Check(ctx)
+	format := cfg.PlanOutput
+	if format == "" {
+		format = plan.OutputFormatText
+	}
+	if err := plan.ExplainInconsistencies(os.Stdout, format, inconsistencies); err != nil {
+		log.Fatal(err)
+	}
+	if checkErr != nil {
+		log.Fatal(checkErr)
+	}
+	if len(inconsistencies) > 0 {
+		log.Errorf("Found %d inconsistencies between the DNS records and the desired ones", len(inconsistencies))
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// newProvider returns the DNS provider of the configuration and the domain filter it was created with
+func newProvider(ctx context.Context, cfg *externaldns.Config, endpointsSource source.Source) (endpoint.DomainFilter, provider.Provider, error) {
 	// RegexDomainFilter overrides DomainFilter
 	var domainFilter endpoint.DomainFilter
 	if cfg.RegexDomainFilter.String() != "" {
@@ -180,6 +302,7 @@ func main() {
 	zoneTypeFilter := provider.NewZoneTypeFilter(cfg.AWSZoneType)
 	zoneTagFilter := provider.NewZoneTagFilter(cfg.AWSZoneTagFilter)
 
+	var err error
 	var p provider.Provider
 	switch cfg.Provider {
 	case "akamai":
@@ -371,62 +494,181 @@ func main() {
 		p, err = plural.NewPluralProvider(cfg.PluralCluster, cfg.PluralProvider)
 	case "tencentcloud":
 		p, err = tencentcloud.NewTencentCloudProvider(domainFilter, zoneIDFilter, cfg.TencentCloudConfigFile, cfg.TencentCloudZoneType, cfg.DryRun)
+	case "webhook":
+		p, err = webhook.NewWebhookProvider(ctx, cfg.WebhookProviderURL)
 	default:
-		log.Fatalf("unknown dns provider: %s", cfg.Provider)
+		return domainFilter, nil, fmt.Errorf("unknown dns provider: %s", cfg.Provider)
 	}
-	if err != nil {
-		log.Fatal(err)
+	return domainFilter, p, err
+}
+
+// newController returns the controller synchronizing the endpoints of the source with the DNS provider
+func newController(cfg *externaldns.Config, clientGenerator source.ClientGenerator, endpointsSource source.Source, domainFilter endpoint.DomainFilter, p provider.Provider) (*controller.Controller, error) {
+	cacheInterval := cfg.ProviderCacheInterval
+	if cacheInterval == 0 && cfg.Registry == "txt" {
+		// --txt-cache-interval is deprecated in favor of --provider-cache-interval
+		cacheInterval = cfg.TXTCacheInterval
+	}
+	// the AWS SD registry works with the AWS SD provider itself
+	if cacheInterval > 0 && cfg.Registry != "aws-sd" {
+		p = provider.NewCachedProvider(p, cacheInterval)
 	}
 
 	var r registry.Registry
+	var err error
 	switch cfg.Registry {
 	case "noop":
 		r, err = registry.NewNoopRegistry(p)
 	case "txt":
-		r, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTOwnerID, cfg.TXTCacheInterval, cfg.TXTWildcardReplacement, cfg.ManagedDNSRecordTypes, cfg.TXTEncryptEnabled, []byte(cfg.TXTEncryptAESKey))
+		txtDecryptAESKeys := make([][]byte, 0, len(cfg.TXTDecryptAESKeys))
+		for _, key := range cfg.TXTDecryptAESKeys {
+			txtDecryptAESKeys = append(txtDecryptAESKeys, []byte(key))
+		}
+		r, err = registry.NewTXTRegistry(p, registry.TXTConfig{
+			Prefix:              cfg.TXTPrefix,
+			Suffix:              cfg.TXTSuffix,
+			OwnerID:             cfg.TXTOwnerID,
+			WildcardReplacement: cfg.TXTWildcardReplacement,
+			ManagedRecordTypes:  cfg.ManagedDNSRecordTypes,
+			EncryptEnabled:      cfg.TXTEncryptEnabled,
+			EncryptAESKey:       []byte(cfg.TXTEncryptAESKey),
+			DecryptAESKeys:      txtDecryptAESKeys,
+			DeleteOrphans:       cfg.TXTDeleteOrphans,
+			NameTemplate:        cfg.TXTNameTemplate,
+			ApexNameTemplate:    cfg.TXTApexNameTemplate,
+			Format:              endpoint.LabelsFormat(cfg.TXTFormat),
+		})
 	case "aws-sd":
 		r, err = registry.NewAWSSDRegistry(p.(*awssd.AWSSDProvider), cfg.TXTOwnerID)
+	case "configmap":
+		var kubeClient kubernetes.Interface
+		if kubeClient, err = clientGenerator.KubeClient(); err == nil {
+			r, err = registry.NewConfigMapRegistry(p, kubeClient, cfg.ConfigMapRegistryNamespace, cfg.ConfigMapRegistryName, cfg.TXTOwnerID)
+		}
 	default:
-		log.Fatalf("unknown registry: %s", cfg.Registry)
+		return nil, fmt.Errorf("unknown registry: %s", cfg.Registry)
 	}
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	policy, exists := plan.Policies[cfg.Policy]
 	if !exists {
-		log.Fatalf("unknown policy: %s", cfg.Policy)
+		return nil, fmt.Errorf("unknown policy: %s", cfg.Policy)
 	}
 
-	ctrl := controller.Controller{
+	conflictResolver, exists := plan.ConflictResolvers[cfg.ConflictResolver]
+	if !exists {
+		return nil, fmt.Errorf("unknown conflict resolver: %s", cfg.ConflictResolver)
+	}
+
+	ctrl := &controller.Controller{
 		Source:               endpointsSource,
 		Registry:             r,
 		Policy:               policy,
+		ConflictResolver:     conflictResolver,
+		PlanOutput:           cfg.PlanOutput,
 		Interval:             cfg.Interval,
 		DomainFilter:         domainFilter,
 		ManagedRecordTypes:   cfg.ManagedDNSRecordTypes,
 		MinEventSyncInterval: cfg.MinEventSyncInterval,
 	}
 
-	if cfg.Once {
-		err := ctrl.RunOnce(ctx)
+	if cfg.Registry == "txt" || cfg.Registry == "aws-sd" || cfg.Registry == "configmap" {
+		// these registries only apply changes to records of their owner
+		ctrl.OwnerID = cfg.TXTOwnerID
+		ctrl.AdoptOwnerIDs = cfg.AdoptOwnerIDs
+		ctrl.AdoptAnnotated = cfg.AdoptAnnotated
+	}
+	return ctrl, nil
+}
+
+// newPipelines returns a controller for every pipeline of the --pipelines-config file. The controllers
+// share the sources, and so their informers, but have their own provider, registry and reconciliation loop.
+func newPipelines(ctx context.Context, cfg *externaldns.Config, clientGenerator source.ClientGenerator, sources []source.Source, endpointsSource source.Source) (controller.Pipelines, error) {
+	pipelines, err := externaldns.LoadPipelines(cfg.PipelinesConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var recorder controller.EventRecorder
+	var resolver controller.ReferenceResolver
+	if cfg.EmitEvents && !cfg.DryRun {
+		if recorder, resolver, err = newEventRecorder(clientGenerator, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	ctrls := make(controller.Pipelines, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		pipelineCfg := cfg.ForPipeline(pipeline)
+		if err := validation.ValidateConfig(pipelineCfg); err != nil {
+			return nil, fmt.Errorf("pipeline %q: %w", pipeline.Name, err)
+		}
+		domainFilter, p, err := newProvider(ctx, pipelineCfg, endpointsSource)
 		if err != nil {
-			log.Fatal(err)
+			return nil, fmt.Errorf("pipeline %q: %w", pipeline.Name, err)
+		}
+		ctrl, err := newController(pipelineCfg, clientGenerator, endpointsSource, domainFilter, p)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %q: %w", pipeline.Name, err)
+		}
+		ctrl.Name = pipeline.Name
+		ctrl.IsolateErrors = true
+		if recorder != nil {
+			ctrl.Events = controller.NewEventEmitter(recorder, resolver)
 		}
+		log.Infof("Synchronizing pipeline %s with provider %s", pipeline.Name, pipelineCfg.Provider)
+		ctrls = append(ctrls, ctrl)
+	}
 
-		os.Exit(0)
+	if !cfg.DryRun {
+		var reporters []controller.StatusReporter
+		for _, s := range sources {
+			if reporter, ok := s.(controller.StatusReporter); ok {
+				reporters = append(reporters, reporter)
+			}
+		}
+		if len(reporters) > 0 {
+			ctrls.SetStatusReporters(reporters)
+		}
 	}
+	return ctrls, nil
+}
 
-	if cfg.UpdateEvents {
-		// Add RunOnce as the handler function that will be called when ingress/service sources have changed.
-		// Note that k8s Informers will perform an initial list operation, which results in the handler
-		// function initially being called for every Service/Ingress that exists
-		ctrl.Source.AddEventHandler(ctx, func() { ctrl.ScheduleRunOnce(time.Now()) })
+func runWithLeaderElection(ctx context.Context, cfg *externaldns.Config, clientGenerator source.ClientGenerator, r runner) {
+	kubeClient, err := clientGenerator.KubeClient()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	ctrl.ScheduleRunOnce(time.Now())
-	ctrl.Run(ctx)
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("failed to determine leader election identity: %v", err)
+	}
+
+	err = r.RunWithLeaderElection(ctx, kubeClient, controller.LeaderElectionConfig{
+		LeaseName:      cfg.LeaderElectionLeaseName,
+		LeaseNamespace: cfg.LeaderElectionNamespace,
+		Identity:       hostname + "_" + string(uuid.NewUUID()),
+		LeaseDuration:  cfg.LeaderElectionLeaseDuration,
+		RenewDeadline:  cfg.LeaderElectionRenewDeadline,
+		RetryPeriod:    cfg.LeaderElectionRetryPeriod,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newEventRecorder returns an event recorder and the resolver of the resources the DNS records come from, which
+// looks them up in the informer caches of the sources
+func newEventRecorder(clientGenerator source.ClientGenerator, sources []source.Source) (controller.EventRecorder, controller.ReferenceResolver, error) {
+	kubeClient, err := clientGenerator.KubeClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	return controller.NewKubeEventRecorder(kubeClient), source.NewResourceReferenceResolver(sources), nil
 }
 
 func handleSigterm(cancel func()) {
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Pipeline synchronizes the endpoints of the sources with its own provider, registry, domain filter
+// and policy. The settings which are not set are taken from the command line flags.
+type Pipeline struct {
+	Name                  string   `yaml:"name"`
+	Provider              string   `yaml:"provider"`
+	Registry              string   `yaml:"registry"`
+	Policy                string   `yaml:"policy"`
+	DomainFilter          []string `yaml:"domainFilter"`
+	ExcludeDomains        []string `yaml:"excludeDomains"`
+	RegexDomainFilter     string   `yaml:"regexDomainFilter"`
+	RegexDomainExclusion  string   `yaml:"regexDomainExclusion"`
+	ZoneIDFilter          []string `yaml:"zoneIdFilter"`
+	AWSZoneType           string   `yaml:"awsZoneType"`
+	TXTOwnerID            string   `yaml:"txtOwnerId"`
+	TXTPrefix             string   `yaml:"txtPrefix"`
+	TXTSuffix             string   `yaml:"txtSuffix"`
+	ManagedDNSRecordTypes []string `yaml:"managedRecordTypes"`
+}
+
+// pipelinesConfig is the content of the file given with --pipelines-config
+type pipelinesConfig struct {
+	Pipelines []Pipeline `yaml:"pipelines"`
+}
+
+// LoadPipelines reads the pipelines from the given file
+func LoadPipelines(path string) ([]Pipeline, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipelines config file '%s': %v", path, err)
+	}
+	cfg := &pipelinesConfig{}
+	if err := yaml.UnmarshalStrict(contents, cfg); err != nil {
+		return nil, fmt.Errorf("failed to read pipelines config file '%s': %v", path, err)
+	}
+
+	if len(cfg.Pipelines) == 0 {
+		return nil, fmt.Errorf("no pipelines specified in '%s'", path)
+	}
+	names := map[string]bool{}
+	for _, p := range cfg.Pipelines {
+		if p.Name == "" {
+			return nil, fmt.Errorf("pipeline without name in '%s'", path)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("duplicate pipeline %q in '%s'", p.Name, path)
+		}
+		names[p.Name] = true
+		if p.Provider == "" {
+			return nil, fmt.Errorf("no provider specified for pipeline %q", p.Name)
+		}
+		if p.TXTPrefix != "" && p.TXTSuffix != "" {
+			return nil, fmt.Errorf("txtPrefix and txtSuffix are mutual exclusive in pipeline %q", p.Name)
+		}
+		for _, r := range []string{p.RegexDomainFilter, p.RegexDomainExclusion} {
+			if _, err := regexp.Compile(r); err != nil {
+				return nil, fmt.Errorf("invalid regex in pipeline %q: %v", p.Name, err)
+			}
+		}
+	}
+	return cfg.Pipelines, nil
+}
+
+// ForPipeline returns a copy of the config with the settings of the given pipeline
+func (cfg *Config) ForPipeline(p Pipeline) *Config {
+	c := *cfg
+	c.Provider = p.Provider
+	if p.Registry != "" {
+		c.Registry = p.Registry
+	}
+	if p.Policy != "" {
+		c.Policy = p.Policy
+	}
+	if len(p.DomainFilter) > 0 || len(p.ExcludeDomains) > 0 || p.RegexDomainFilter != "" || p.RegexDomainExclusion != "" {
+		// the domain filter of the pipeline replaces the one of the flags as a whole
+		c.DomainFilter = p.DomainFilter
+		c.ExcludeDomains = p.ExcludeDomains
+		c.RegexDomainFilter = regexp.MustCompile(p.RegexDomainFilter)
+		c.RegexDomainExclusion = regexp.MustCompile(p.RegexDomainExclusion)
+	}
+	if len(p.ZoneIDFilter) > 0 {
+		c.ZoneIDFilter = p.ZoneIDFilter
+	}
+	if p.AWSZoneType != "" {
+		c.AWSZoneType = p.AWSZoneType
+	}
+	if p.TXTOwnerID != "" {
+		c.TXTOwnerID = p.TXTOwnerID
+	}
+	if p.TXTPrefix != "" || p.TXTSuffix != "" {
+		c.TXTPrefix = p.TXTPrefix
+		c.TXTSuffix = p.TXTSuffix
+	}
+	if len(p.ManagedDNSRecordTypes) > 0 {
+		c.ManagedDNSRecordTypes = p.ManagedDNSRecordTypes
+	}
+	return &c
+}
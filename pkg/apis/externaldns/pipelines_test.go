@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePipelinesConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "pipelines.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPipelines(t *testing.T) {
+	path := writePipelinesConfig(t, `
+pipelines:
+- name: public
+  provider: cloudflare
+  domainFilter: [example.org]
+  excludeDomains: [internal.example.org]
+  txtOwnerId: public
+- name: private
+  provider: aws
+  policy: upsert-only
+  domainFilter: [internal.example.org]
+  awsZoneType: private
+  managedRecordTypes: [A, CNAME]
+`)
+	pipelines, err := LoadPipelines(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Pipeline{
+		{
+			Name:           "public",
+			Provider:       "cloudflare",
+			DomainFilter:   []string{"example.org"},
+			ExcludeDomains: []string{"internal.example.org"},
+			TXTOwnerID:     "public",
+		},
+		{
+			Name:                  "private",
+			Provider:              "aws",
+			Policy:                "upsert-only",
+			DomainFilter:          []string{"internal.example.org"},
+			AWSZoneType:           "private",
+			ManagedDNSRecordTypes: []string{"A", "CNAME"},
+		},
+	}, pipelines)
+}
+
+func TestLoadPipelinesInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		content string
+	}{
+		{"no pipelines", "pipelines: []"},
+		{"unknown setting", "pipelines:\n- name: public\n  provider: aws\n  zone: example.org"},
+		{"no name", "pipelines:\n- provider: aws"},
+		{"no provider", "pipelines:\n- name: public"},
+		{"duplicate name", "pipelines:\n- name: public\n  provider: aws\n- name: public\n  provider: google"},
+		{"prefix and suffix", "pipelines:\n- name: public\n  provider: aws\n  txtPrefix: p-\n  txtSuffix: -s"},
+		{"invalid regex", "pipelines:\n- name: public\n  provider: aws\n  regexDomainFilter: '('"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := LoadPipelines(writePipelinesConfig(t, tc.content))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadPipelines(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestForPipeline(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Provider = "aws"
+	cfg.Registry = "txt"
+	cfg.TXTOwnerID = "default"
+	cfg.TXTPrefix = "prefix-"
+	cfg.DomainFilter = []string{"example.org"}
+	cfg.ManagedDNSRecordTypes = []string{"A", "AAAA", "CNAME"}
+
+	pipelineCfg := cfg.ForPipeline(Pipeline{
+		Name:         "private",
+		Provider:     "google",
+		DomainFilter: []string{"internal.example.org"},
+		TXTOwnerID:   "private",
+		TXTSuffix:    "-suffix",
+	})
+	assert.Equal(t, "google", pipelineCfg.Provider)
+	assert.Equal(t, "txt", pipelineCfg.Registry)
+	assert.Equal(t, []string{"internal.example.org"}, pipelineCfg.DomainFilter)
+	assert.Equal(t, "private", pipelineCfg.TXTOwnerID)
+	assert.Equal(t, "", pipelineCfg.TXTPrefix)
+	assert.Equal(t, "-suffix", pipelineCfg.TXTSuffix)
+	assert.Equal(t, []string{"A", "AAAA", "CNAME"}, pipelineCfg.ManagedDNSRecordTypes)
+
+	// the original config is left untouched
+	assert.Equal(t, "aws", cfg.Provider)
+	assert.Equal(t, []string{"example.org"}, cfg.DomainFilter)
+	assert.Equal(t, "prefix-", cfg.TXTPrefix)
+
+	// a pipeline only excluding domains replaces the domain filter too
+	pipelineCfg = cfg.ForPipeline(Pipeline{Name: "public", Provider: "aws", RegexDomainExclusion: `\.internal\.example\.org$`})
+	assert.Empty(t, pipelineCfg.DomainFilter)
+	assert.Equal(t, `\.internal\.example\.org$`, pipelineCfg.RegexDomainExclusion.String())
+}
@@ -66,8 +66,13 @@ type Config struct {
 	PublishInternal                    bool
 	PublishHostIP                      bool
 	AlwaysPublishNotReadyAddresses     bool
+	PublishPodIPs                      bool
+	PodNamespaceLabelFilter            string
+	PodFQDNTemplate                    string
+	UnstructuredResources              []string
 	ConnectorSourceServer              string
 	Provider                           string
+	PipelinesConfig                    string
 	GoogleProject                      string
 	GoogleBatchChangeSize              int
 	GoogleBatchChangeInterval          time.Duration
@@ -144,21 +149,35 @@ type Config struct {
 	TLSClientCert                      string
 	TLSClientCertKey                   string
 	Policy                             string
+	ConflictResolver                   string
 	Registry                           string
 	TXTOwnerID                         string
+	AdoptOwnerIDs                      []string
+	AdoptAnnotated                     bool
+	ConfigMapRegistryName              string
+	ConfigMapRegistryNamespace         string
 	TXTPrefix                          string
 	TXTSuffix                          string
 	TXTEncryptEnabled                  bool
-	TXTEncryptAESKey                   string
+	TXTEncryptAESKey                   string   `secure:"yes"`
+	TXTDecryptAESKeys                  []string `secure:"yes"`
+	TXTDeleteOrphans                   bool
+	TXTNameTemplate                    string
+	TXTApexNameTemplate                string
+	TXTFormat                          string
 	Interval                           time.Duration
 	MinEventSyncInterval               time.Duration
 	Once                               bool
+	Check                              bool
 	DryRun                             bool
+	PlanOutput                         string
+	EmitEvents                         bool
 	UpdateEvents                       bool
 	LogFormat                          string
 	MetricsAddress                     string
 	LogLevel                           string
 	TXTCacheInterval                   time.Duration
+	ProviderCacheInterval              time.Duration
 	TXTWildcardReplacement             string
 	ExoscaleEndpoint                   string
 	ExoscaleAPIKey                     string `secure:"yes"`
@@ -205,6 +224,17 @@ type Config struct {
 	PiholeTLSInsecureSkipVerify        bool
 	PluralCluster                      string
 	PluralProvider                     string
+	WebhookProviderURL                 string
+	WebhookProviderReadTimeout         time.Duration
+	WebhookProviderWriteTimeout        time.Duration
+	WebhookServer                      bool
+	WebhookServerAddress               string
+	LeaderElection                     bool
+	LeaderElectionLeaseName            string
+	LeaderElectionNamespace            string
+	LeaderElectionLeaseDuration        time.Duration
+	LeaderElectionRenewDeadline        time.Duration
+	LeaderElectionRetryPeriod          time.Duration
 }
 
 var defaultConfig = &Config{
@@ -230,8 +260,12 @@ var defaultConfig = &Config{
 	Compatibility:               "",
 	PublishInternal:             false,
 	PublishHostIP:               false,
+	PublishPodIPs:               false,
+	PodNamespaceLabelFilter:     "",
+	PodFQDNTemplate:             "",
 	ConnectorSourceServer:       "localhost:8080",
 	Provider:                    "",
+	PipelinesConfig:             "",
 	GoogleProject:               "",
 	GoogleBatchChangeSize:       1000,
 	GoogleBatchChangeInterval:   time.Second,
@@ -292,18 +326,32 @@ var defaultConfig = &Config{
 	TLSClientCert:               "",
 	TLSClientCertKey:            "",
 	Policy:                      "sync",
+	ConflictResolver:            "per-resource",
 	Registry:                    "txt",
 	TXTOwnerID:                  "default",
+	AdoptOwnerIDs:               []string{},
+	AdoptAnnotated:              false,
+	ConfigMapRegistryName:       "external-dns-registry",
+	ConfigMapRegistryNamespace:  "default",
 	TXTPrefix:                   "",
 	TXTSuffix:                   "",
 	TXTCacheInterval:            0,
+	ProviderCacheInterval:       0,
 	TXTWildcardReplacement:      "",
 	MinEventSyncInterval:        5 * time.Second,
 	TXTEncryptEnabled:           false,
 	TXTEncryptAESKey:            "",
+	TXTDecryptAESKeys:           []string{},
+	TXTDeleteOrphans:            false,
+	TXTNameTemplate:             "",
+	TXTApexNameTemplate:         "",
+	TXTFormat:                   "default",
 	Interval:                    time.Minute,
 	Once:                        false,
+	Check:                       false,
 	DryRun:                      false,
+	PlanOutput:                  "",
+	EmitEvents:                  false,
 	UpdateEvents:                false,
 	LogFormat:                   "text",
 	MetricsAddress:              ":7979",
@@ -350,6 +398,17 @@ var defaultConfig = &Config{
 	PiholeTLSInsecureSkipVerify: false,
 	PluralCluster:               "",
 	PluralProvider:              "",
+	WebhookProviderURL:          "http://localhost:8888",
+	WebhookProviderReadTimeout:  5 * time.Second,
+	WebhookProviderWriteTimeout: 10 * time.Second,
+	WebhookServer:               false,
+	WebhookServerAddress:        "localhost:8888",
+	LeaderElection:              false,
+	LeaderElectionLeaseName:     "external-dns",
+	LeaderElectionNamespace:     "default",
+	LeaderElectionLeaseDuration: 15 * time.Second,
+	LeaderElectionRenewDeadline: 10 * time.Second,
+	LeaderElectionRetryPeriod:   2 * time.Second,
 }
 
 // NewConfig returns new Config object
@@ -365,12 +424,16 @@ func (cfg *Config) String() string {
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 		if val, ok := f.Tag.Lookup("secure"); ok && val == "yes" {
-			if f.Type.Kind() != reflect.String {
-				continue
-			}
 			v := reflect.ValueOf(&temp).Elem().Field(i)
-			if v.String() != "" {
+			switch {
+			case f.Type.Kind() == reflect.String && v.String() != "":
 				v.SetString(passwordMask)
+			case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.String && v.Len() > 0:
+				masked := make([]string, v.Len())
+				for j := range masked {
+					masked[j] = passwordMask
+				}
+				v.Set(reflect.ValueOf(masked))
 			}
 		}
 	}
@@ -414,11 +477,11 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("skipper-routegroup-groupversion", "The resource version for skipper routegroup").Default(source.DefaultRoutegroupVersion).StringVar(&cfg.SkipperRouteGroupVersion)
 
 	// Flags related to processing source
-	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, fake, connector, gateway-httproute, gateway-grpcroute, gateway-tlsroute, gateway-tcproute, gateway-udproute, istio-gateway, istio-virtualservice, cloudfoundry, contour-ingressroute, contour-httpproxy, gloo-proxy, crd, empty, skipper-routegroup, openshift-route, ambassador-host, kong-tcpingress, f5-virtualserver)").Required().PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "pod", "gateway-httproute", "gateway-grpcroute", "gateway-tlsroute", "gateway-tcproute", "gateway-udproute", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-ingressroute", "contour-httpproxy", "gloo-proxy", "fake", "connector", "crd", "empty", "skipper-routegroup", "openshift-route", "ambassador-host", "kong-tcpingress", "f5-virtualserver")
+	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required unless --webhook-server is set, options: service, ingress, node, fake, connector, gateway-httproute, gateway-grpcroute, gateway-tlsroute, gateway-tcproute, gateway-udproute, istio-gateway, istio-virtualservice, cloudfoundry, contour-ingressroute, contour-httpproxy, gloo-proxy, crd, empty, skipper-routegroup, openshift-route, ambassador-host, kong-tcpingress, f5-virtualserver, unstructured)").PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "pod", "gateway-httproute", "gateway-grpcroute", "gateway-tlsroute", "gateway-tcproute", "gateway-udproute", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-ingressroute", "contour-httpproxy", "gloo-proxy", "fake", "connector", "crd", "empty", "skipper-routegroup", "openshift-route", "ambassador-host", "kong-tcpingress", "f5-virtualserver", "unstructured")
 	app.Flag("openshift-router-name", "if source is openshift-route then you can pass the ingress controller name. Based on this name external-dns will select the respective router from the route status and map that routerCanonicalHostname to the route host while creating a CNAME record.").StringVar(&cfg.OCPRouterName)
 	app.Flag("namespace", "Limit sources of endpoints to a specific namespace (default: all namespaces)").Default(defaultConfig.Namespace).StringVar(&cfg.Namespace)
 	app.Flag("annotation-filter", "Filter sources managed by external-dns via annotation using label selector semantics (default: all sources)").Default(defaultConfig.AnnotationFilter).StringVar(&cfg.AnnotationFilter)
-	app.Flag("label-filter", "Filter sources managed by external-dns via label selector when listing all resources; currently supported by source types CRD, ingress, service and openshift-route").Default(defaultConfig.LabelFilter).StringVar(&cfg.LabelFilter)
+	app.Flag("label-filter", "Filter sources managed by external-dns via label selector when listing all resources; currently supported by source types CRD, ingress, service, pod and openshift-route").Default(defaultConfig.LabelFilter).StringVar(&cfg.LabelFilter)
 	app.Flag("ingress-class", "Require an ingress to have this class name (defaults to any class; specify multiple times to allow more than one class)").StringsVar(&cfg.IngressClassNames)
 	app.Flag("fqdn-template", "A templated string that's used to generate DNS names from sources that don't define a hostname themselves, or to add a hostname suffix when paired with the fake source (optional). Accepts comma separated list for multiple global FQDN.").Default(defaultConfig.FQDNTemplate).StringVar(&cfg.FQDNTemplate)
 	app.Flag("combine-fqdn-annotation", "Combine FQDN template and Annotations instead of overwriting").BoolVar(&cfg.CombineFQDNAndAnnotation)
@@ -430,19 +493,24 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("ignore-ingress-rules-spec", "Ignore rules spec section in ingresses resources, applicable only for ingress sources (optional, default: false)").BoolVar(&cfg.IgnoreIngressRulesSpec)
 	app.Flag("publish-internal-services", "Allow external-dns to publish DNS records for ClusterIP services (optional)").BoolVar(&cfg.PublishInternal)
 	app.Flag("publish-host-ip", "Allow external-dns to publish host-ip for headless services (optional)").BoolVar(&cfg.PublishHostIP)
-	app.Flag("always-publish-not-ready-addresses", "Always publish also not ready addresses for headless services (optional)").BoolVar(&cfg.AlwaysPublishNotReadyAddresses)
+	app.Flag("always-publish-not-ready-addresses", "Always publish also not ready addresses for headless services and pods (optional)").BoolVar(&cfg.AlwaysPublishNotReadyAddresses)
+	app.Flag("publish-pod-ips", "Allow external-dns to publish the pods without host networking with their pod IPs, valid only when using pod source (optional)").BoolVar(&cfg.PublishPodIPs)
+	app.Flag("pod-namespace-label-filter", "Limit the pods published with their pod IPs to the namespaces matching this label selector, valid only when using pod source with --publish-pod-ips (default: all namespaces)").Default(defaultConfig.PodNamespaceLabelFilter).StringVar(&cfg.PodNamespaceLabelFilter)
+	app.Flag("pod-fqdn-template", "A templated string that's used to generate DNS names from the pods without hostname annotation, valid only when using pod source; --combine-fqdn-annotation and --ignore-hostname-annotation only apply to the pods along with it (optional)").Default(defaultConfig.PodFQDNTemplate).StringVar(&cfg.PodFQDNTemplate)
 	app.Flag("connector-source-server", "The server to connect for connector source, valid only when using connector source").Default(defaultConfig.ConnectorSourceServer).StringVar(&cfg.ConnectorSourceServer)
 	app.Flag("crd-source-apiversion", "API version of the CRD for crd source, e.g. `externaldns.k8s.io/v1alpha1`, valid only when using crd source").Default(defaultConfig.CRDSourceAPIVersion).StringVar(&cfg.CRDSourceAPIVersion)
 	app.Flag("crd-source-kind", "Kind of the CRD for the crd source in API group and version specified by crd-source-apiversion").Default(defaultConfig.CRDSourceKind).StringVar(&cfg.CRDSourceKind)
+	app.Flag("unstructured-resource", "The resources to read with the unstructured source, given as resource.version.group=hostnameJSONPath,targetJSONPath, e.g. ingressroutes.v1alpha1.traefik.containo.us={.spec.host},{.status.loadBalancer.ingress[*].ip}; the JSONPath expressions are optional; specify multiple times for multiple resources (required when using unstructured source)").StringsVar(&cfg.UnstructuredResources)
 	app.Flag("service-type-filter", "The service types to take care about (default: all, expected: ClusterIP, NodePort, LoadBalancer or ExternalName)").StringsVar(&cfg.ServiceTypeFilter)
-	app.Flag("managed-record-types", "Record types to manage; specify multiple times to include many; (default: A, AAAA, CNAME) (supported records: CNAME, A, AAAA, NS").Default("A", "AAAA", "CNAME").StringsVar(&cfg.ManagedDNSRecordTypes)
+	app.Flag("managed-record-types", "Record types to manage; specify multiple times to include many; (default: A, AAAA, CNAME) (supported records: CNAME, A, AAAA, NS, MX, SRV, TXT, PTR)").Default("A", "AAAA", "CNAME").StringsVar(&cfg.ManagedDNSRecordTypes)
 	app.Flag("default-targets", "Set globally default IP address that will apply as a target instead of source addresses. Specify multiple times for multiple targets (optional)").StringsVar(&cfg.DefaultTargets)
 	app.Flag("target-net-filter", "Limit possible targets by a net filter; specify multiple times for multiple possible nets (optional)").StringsVar(&cfg.TargetNetFilter)
 	app.Flag("exclude-target-net", "Exclude target nets (optional)").StringsVar(&cfg.ExcludeTargetNets)
 
 	// Flags related to providers
-	providers := []string{"akamai", "alibabacloud", "aws", "aws-sd", "azure", "azure-dns", "azure-private-dns", "bluecat", "civo", "cloudflare", "coredns", "designate", "digitalocean", "dnsimple", "dyn", "exoscale", "gandi", "godaddy", "google", "ibmcloud", "infoblox", "inmemory", "linode", "ns1", "oci", "ovh", "pdns", "pihole", "plural", "rcodezero", "rdns", "rfc2136", "safedns", "scaleway", "skydns", "tencentcloud", "transip", "ultradns", "vinyldns", "vultr"}
-	app.Flag("provider", "The DNS provider where the DNS records will be created (required, options: "+strings.Join(providers, ", ")+")").Required().PlaceHolder("provider").EnumVar(&cfg.Provider, providers...)
+	providers := []string{"akamai", "alibabacloud", "aws", "aws-sd", "azure", "azure-dns", "azure-private-dns", "bluecat", "civo", "cloudflare", "coredns", "designate", "digitalocean", "dnsimple", "dyn", "exoscale", "gandi", "godaddy", "google", "ibmcloud", "infoblox", "inmemory", "linode", "ns1", "oci", "ovh", "pdns", "pihole", "plural", "rcodezero", "rdns", "rfc2136", "safedns", "scaleway", "skydns", "tencentcloud", "transip", "ultradns", "vinyldns", "vultr", "webhook"}
+	app.Flag("provider", "The DNS provider where the DNS records will be created (required unless --pipelines-config is specified, options: "+strings.Join(providers, ", ")+")").PlaceHolder("provider").EnumVar(&cfg.Provider, providers...)
+	app.Flag("pipelines-config", "When set, the DNS records are synchronized by the pipelines described in this file, each one with its own provider, registry, domain filter and policy, instead of the single pipeline of --provider (optional)").Default(defaultConfig.PipelinesConfig).StringVar(&cfg.PipelinesConfig)
 	app.Flag("domain-filter", "Limit possible target zones by a domain suffix; specify multiple times for multiple domains (optional)").Default("").StringsVar(&cfg.DomainFilter)
 	app.Flag("exclude-domains", "Exclude subdomains (optional)").Default("").StringsVar(&cfg.ExcludeDomains)
 	app.Flag("regex-domain-filter", "Limit possible domains and target zones by a Regex filter; Overrides domain-filter (optional)").Default(defaultConfig.RegexDomainFilter.String()).RegexpVar(&cfg.RegexDomainFilter)
@@ -568,24 +636,51 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("plural-cluster", "When using the plural provider, specify the cluster name you're running with").Default(defaultConfig.PluralCluster).StringVar(&cfg.PluralCluster)
 	app.Flag("plural-provider", "When using the plural provider, specify the provider name you're running with").Default(defaultConfig.PluralProvider).StringVar(&cfg.PluralProvider)
 
+	// Flags related to the webhook provider
+	app.Flag("webhook-provider-url", "When using the webhook provider, specify the URL of the webhook provider server (default: http://localhost:8888)").Default(defaultConfig.WebhookProviderURL).StringVar(&cfg.WebhookProviderURL)
+	app.Flag("webhook-provider-read-timeout", "When running as a webhook server, the read timeout of the webhook provider API (default: 5s)").Default(defaultConfig.WebhookProviderReadTimeout.String()).DurationVar(&cfg.WebhookProviderReadTimeout)
+	app.Flag("webhook-provider-write-timeout", "When running as a webhook server, the write timeout of the webhook provider API (default: 10s)").Default(defaultConfig.WebhookProviderWriteTimeout.String()).DurationVar(&cfg.WebhookProviderWriteTimeout)
+	app.Flag("webhook-server", "When enabled, serves the configured provider over the webhook provider API instead of running the controller (default: disabled)").BoolVar(&cfg.WebhookServer)
+	app.Flag("webhook-server-address", "When running as a webhook server, the address to listen on (default: localhost:8888)").Default(defaultConfig.WebhookServerAddress).StringVar(&cfg.WebhookServerAddress)
+
 	// Flags related to policies
 	app.Flag("policy", "Modify how DNS records are synchronized between sources and providers (default: sync, options: sync, upsert-only, create-only)").Default(defaultConfig.Policy).EnumVar(&cfg.Policy, "sync", "upsert-only", "create-only")
+	app.Flag("conflict-resolver", "How to pick the desired endpoint when several resources claim the same DNS name, record type and set identifier (default: per-resource, options: per-resource, merge-targets, priority, oldest-resource)").Default(defaultConfig.ConflictResolver).EnumVar(&cfg.ConflictResolver, "per-resource", "merge-targets", "priority", "oldest-resource")
 
 	// Flags related to the registry
-	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd")
-	app.Flag("txt-owner-id", "When using the TXT registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd, configmap)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd", "configmap")
+	app.Flag("txt-owner-id", "When using the TXT, AWS SD or ConfigMap registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("adopt-owner-id", "When using the TXT, AWS SD or ConfigMap registry, take over the desired records owned by this other owner id; specify multiple times for multiple owners (optional)").StringsVar(&cfg.AdoptOwnerIDs)
+	app.Flag("adopt-annotated", "When using the TXT, AWS SD or ConfigMap registry, let the resources annotated with external-dns.alpha.kubernetes.io/adopt take over the records of any other owner; anybody able to annotate a resource can then take over the records of other instances (default: disabled)").BoolVar(&cfg.AdoptAnnotated)
+	app.Flag("configmap-registry-name", "When using the ConfigMap registry, the name of the ConfigMap storing the ownership of the DNS records (default: external-dns-registry)").Default(defaultConfig.ConfigMapRegistryName).StringVar(&cfg.ConfigMapRegistryName)
+	app.Flag("configmap-registry-namespace", "When using the ConfigMap registry, the namespace of the ConfigMap storing the ownership of the DNS records (default: default)").Default(defaultConfig.ConfigMapRegistryNamespace).StringVar(&cfg.ConfigMapRegistryNamespace)
 	app.Flag("txt-prefix", "When using the TXT registry, a custom string that's prefixed to each ownership DNS record (optional). Could contain record type template like '%{record_type}-prefix-'. Mutual exclusive with txt-suffix!").Default(defaultConfig.TXTPrefix).StringVar(&cfg.TXTPrefix)
 	app.Flag("txt-suffix", "When using the TXT registry, a custom string that's suffixed to the host portion of each ownership DNS record (optional). Could contain record type template like '-%{record_type}-suffix'. Mutual exclusive with txt-prefix!").Default(defaultConfig.TXTSuffix).StringVar(&cfg.TXTSuffix)
 	app.Flag("txt-wildcard-replacement", "When using the TXT registry, a custom string that's used instead of an asterisk for TXT records corresponding to wildcard DNS records (optional)").Default(defaultConfig.TXTWildcardReplacement).StringVar(&cfg.TXTWildcardReplacement)
 	app.Flag("txt-encrypt-enabled", "When using the TXT registry, set if TXT records should be encrypted before stored (default: disabled)").BoolVar(&cfg.TXTEncryptEnabled)
 	app.Flag("txt-encrypt-aes-key", "When using the TXT registry, set TXT record decryption and encryption 32 byte aes key (required when --txt-encrypt=true)").Default(defaultConfig.TXTEncryptAESKey).StringVar(&cfg.TXTEncryptAESKey)
+	app.Flag("txt-decrypt-aes-key", "When using the TXT registry, a retired 32 byte aes key only used to decrypt TXT records, which are re-encrypted with --txt-encrypt-aes-key; specify multiple times for multiple keys (optional)").StringsVar(&cfg.TXTDecryptAESKeys)
+	app.Flag("txt-delete-orphans", "When using the TXT registry, delete the TXT records of the owner which do not own any record anymore, and the TXT records in the legacy format, which are not created anymore (default: disabled)").BoolVar(&cfg.TXTDeleteOrphans)
+	app.Flag("txt-name-template", "When using the TXT registry, the template of the names of the ownership DNS records, made of %{record_type}, %{owner_id}, and either %{name} or %{label} and %{domain}, e.g. '%{record_type}-%{label}.%{domain}' (optional). The records named with --txt-prefix or --txt-suffix are migrated to the template").Default(defaultConfig.TXTNameTemplate).StringVar(&cfg.TXTNameTemplate)
+	app.Flag("txt-apex-name-template", "When using the TXT registry, the template of the names of the ownership DNS records of the records at a zone apex, i.e. named after one of the domains of --domain-filter (optional, requires --txt-name-template)").Default(defaultConfig.TXTApexNameTemplate).StringVar(&cfg.TXTApexNameTemplate)
+	app.Flag("txt-format", "When using the TXT registry, the format of the ownership information stored in the TXT records; the TXT records of the owner in the other format are rewritten, and the releases before the compact format cannot read it (default: default, options: default, compact)").Default(defaultConfig.TXTFormat).EnumVar(&cfg.TXTFormat, "default", "compact")
 
 	// Flags related to the main control loop
-	app.Flag("txt-cache-interval", "The interval between cache synchronizations in duration format (default: disabled)").Default(defaultConfig.TXTCacheInterval.String()).DurationVar(&cfg.TXTCacheInterval)
+	app.Flag("txt-cache-interval", "Deprecated: use --provider-cache-interval instead. When using the TXT registry, the interval between cache synchronizations in duration format (default: disabled)").Default(defaultConfig.TXTCacheInterval.String()).DurationVar(&cfg.TXTCacheInterval)
+	app.Flag("provider-cache-interval", "The interval during which the records of the DNS provider are cached, zone by zone, before being listed again in duration format; the zones touched by failed changes are listed again on the next synchronization (default: disabled)").Default(defaultConfig.ProviderCacheInterval.String()).DurationVar(&cfg.ProviderCacheInterval)
 	app.Flag("interval", "The interval between two consecutive synchronizations in duration format (default: 1m)").Default(defaultConfig.Interval.String()).DurationVar(&cfg.Interval)
 	app.Flag("min-event-sync-interval", "The minimum interval between two consecutive synchronizations triggered from kubernetes events in duration format (default: 5s)").Default(defaultConfig.MinEventSyncInterval.String()).DurationVar(&cfg.MinEventSyncInterval)
 	app.Flag("once", "When enabled, exits the synchronization loop after the first iteration (default: disabled)").BoolVar(&cfg.Once)
+	app.Flag("check", "When enabled, reports the inconsistencies between the DNS records and the desired ones once without changing anything, and exits with a non-zero code when there are some; the report is printed in the --plan-output format (default: disabled)").BoolVar(&cfg.Check)
 	app.Flag("dry-run", "When enabled, prints DNS record changes rather than actually performing them (default: disabled)").BoolVar(&cfg.DryRun)
+	app.Flag("plan-output", "When set, prints the planned DNS record changes with the resource and owner of every record and the reason why records are skipped in every synchronization (optional, options: text, json)").Default(defaultConfig.PlanOutput).EnumVar(&cfg.PlanOutput, "", "text", "json")
+	app.Flag("emit-events", "When enabled, emits Kubernetes Events on the resources the DNS records come from when their records are created, updated, deleted, skipped because of their ownership or fail to be applied (default: disabled)").BoolVar(&cfg.EmitEvents)
+	app.Flag("leader-election", "When enabled, only the replica holding the leader election lease synchronizes DNS records, other replicas stay on standby (default: disabled)").BoolVar(&cfg.LeaderElection)
+	app.Flag("leader-election-lease-name", "The name of the Lease object used for leader election (default: external-dns)").Default(defaultConfig.LeaderElectionLeaseName).StringVar(&cfg.LeaderElectionLeaseName)
+	app.Flag("leader-election-namespace", "The namespace of the Lease object used for leader election (default: default)").Default(defaultConfig.LeaderElectionNamespace).StringVar(&cfg.LeaderElectionNamespace)
+	app.Flag("leader-election-lease-duration", "The duration standby replicas wait before trying to acquire the leader election lease (default: 15s)").Default(defaultConfig.LeaderElectionLeaseDuration.String()).DurationVar(&cfg.LeaderElectionLeaseDuration)
+	app.Flag("leader-election-renew-deadline", "The duration the leader retries renewing the leader election lease before giving it up (default: 10s)").Default(defaultConfig.LeaderElectionRenewDeadline.String()).DurationVar(&cfg.LeaderElectionRenewDeadline)
+	app.Flag("leader-election-retry-period", "The duration replicas wait between attempts to acquire or renew the leader election lease (default: 2s)").Default(defaultConfig.LeaderElectionRetryPeriod.String()).DurationVar(&cfg.LeaderElectionRetryPeriod)
 	app.Flag("events", "When enabled, in addition to running every interval, the reconciliation loop will get triggered when supported sources change (default: disabled)").BoolVar(&cfg.UpdateEvents)
 
 	// Miscellaneous flags
@@ -43,6 +43,7 @@ var (
 		FQDNTemplate:                "",
 		Compatibility:               "",
 		Provider:                    "google",
+		PipelinesConfig:             "",
 		GoogleProject:               "",
 		GoogleBatchChangeSize:       1000,
 		GoogleBatchChangeInterval:   time.Second,
@@ -100,14 +101,22 @@ var (
 		PDNSServer:                  "http://localhost:8081",
 		PDNSAPIKey:                  "",
 		Policy:                      "sync",
+		ConflictResolver:            "per-resource",
 		Registry:                    "txt",
 		TXTOwnerID:                  "default",
+		ConfigMapRegistryName:       "external-dns-registry",
+		ConfigMapRegistryNamespace:  "default",
 		TXTPrefix:                   "",
+		TXTFormat:                   "default",
 		TXTCacheInterval:            0,
+		ProviderCacheInterval:       0,
 		Interval:                    time.Minute,
 		MinEventSyncInterval:        5 * time.Second,
 		Once:                        false,
+		Check:                       false,
 		DryRun:                      false,
+		PlanOutput:                  "",
+		EmitEvents:                  false,
 		UpdateEvents:                false,
 		LogFormat:                   "text",
 		MetricsAddress:              ":7979",
@@ -129,6 +138,15 @@ var (
 		IBMCloudConfigFile:          "/etc/kubernetes/ibmcloud.json",
 		TencentCloudConfigFile:      "/etc/kubernetes/tencent-cloud.json",
 		TencentCloudZoneType:        "",
+		WebhookProviderURL:          "http://localhost:8888",
+		WebhookProviderReadTimeout:  5 * time.Second,
+		WebhookProviderWriteTimeout: 10 * time.Second,
+		WebhookServerAddress:        "localhost:8888",
+		LeaderElectionLeaseName:     "external-dns",
+		LeaderElectionNamespace:     "default",
+		LeaderElectionLeaseDuration: 15 * time.Second,
+		LeaderElectionRenewDeadline: 10 * time.Second,
+		LeaderElectionRetryPeriod:   2 * time.Second,
 	}
 
 	overriddenConfig = &Config{
@@ -145,7 +163,12 @@ var (
 		IgnoreIngressRulesSpec:      true,
 		FQDNTemplate:                "{{.Name}}.service.example.com",
 		Compatibility:               "mate",
+		PublishPodIPs:               true,
+		PodNamespaceLabelFilter:     "dns=pods",
+		PodFQDNTemplate:             "{{.Name}}.pod.example.com",
+		UnstructuredResources:       []string{"ingressroutes.v1alpha1.traefik.containo.us={.spec.host},{.status.loadBalancer.ingress[*].ip}", "routes.v1.serving.knative.dev"},
 		Provider:                    "google",
+		PipelinesConfig:             "/etc/external-dns/pipelines.yaml",
 		GoogleProject:               "project",
 		GoogleBatchChangeSize:       100,
 		GoogleBatchChangeInterval:   time.Second * 2,
@@ -209,14 +232,28 @@ var (
 		TLSClientCert:               "/path/to/cert.pem",
 		TLSClientCertKey:            "/path/to/key.pem",
 		Policy:                      "upsert-only",
+		ConflictResolver:            "oldest-resource",
 		Registry:                    "noop",
 		TXTOwnerID:                  "owner-1",
+		AdoptOwnerIDs:               []string{"owner-0", "legacy"},
+		AdoptAnnotated:              true,
+		TXTDecryptAESKeys:           []string{"retired-key-1", "retired-key-2"},
+		TXTDeleteOrphans:            true,
+		TXTNameTemplate:             "%{record_type}-%{label}.%{domain}",
+		TXTApexNameTemplate:         "%{record_type}._owner.%{name}",
+		TXTFormat:                   "compact",
+		ConfigMapRegistryName:       "dns-ownership",
+		ConfigMapRegistryNamespace:  "external-dns",
 		TXTPrefix:                   "associated-txt-record",
 		TXTCacheInterval:            12 * time.Hour,
+		ProviderCacheInterval:       30 * time.Minute,
 		Interval:                    10 * time.Minute,
 		MinEventSyncInterval:        50 * time.Second,
 		Once:                        true,
+		Check:                       true,
 		DryRun:                      true,
+		PlanOutput:                  "json",
+		EmitEvents:                  true,
 		UpdateEvents:                true,
 		LogFormat:                   "json",
 		MetricsAddress:              "127.0.0.1:9099",
@@ -239,6 +276,17 @@ var (
 		IBMCloudConfigFile:          "ibmcloud.json",
 		TencentCloudConfigFile:      "tencent-cloud.json",
 		TencentCloudZoneType:        "private",
+		WebhookProviderURL:          "http://localhost:8889",
+		WebhookProviderReadTimeout:  5 * time.Second,
+		WebhookProviderWriteTimeout: 10 * time.Second,
+		WebhookServer:               true,
+		WebhookServerAddress:        "localhost:8889",
+		LeaderElection:              true,
+		LeaderElectionLeaseName:     "external-dns-ha",
+		LeaderElectionNamespace:     "external-dns",
+		LeaderElectionLeaseDuration: 30 * time.Second,
+		LeaderElectionRenewDeadline: 20 * time.Second,
+		LeaderElectionRetryPeriod:   5 * time.Second,
 	}
 )
 
@@ -277,7 +325,13 @@ func TestParseFlags(t *testing.T) {
 				"--ignore-ingress-tls-spec",
 				"--ignore-ingress-rules-spec",
 				"--compatibility=mate",
+				"--publish-pod-ips",
+				"--pod-namespace-label-filter=dns=pods",
+				"--pod-fqdn-template={{.Name}}.pod.example.com",
+				"--unstructured-resource=ingressroutes.v1alpha1.traefik.containo.us={.spec.host},{.status.loadBalancer.ingress[*].ip}",
+				"--unstructured-resource=routes.v1.serving.knative.dev",
 				"--provider=google",
+				"--pipelines-config=/etc/external-dns/pipelines.yaml",
 				"--google-project=project",
 				"--google-batch-change-size=100",
 				"--google-batch-change-interval=2s",
@@ -347,14 +401,30 @@ func TestParseFlags(t *testing.T) {
 				"--aws-sd-service-cleanup",
 				"--no-aws-evaluate-target-health",
 				"--policy=upsert-only",
+				"--conflict-resolver=oldest-resource",
 				"--registry=noop",
 				"--txt-owner-id=owner-1",
+				"--adopt-owner-id=owner-0",
+				"--adopt-owner-id=legacy",
+				"--adopt-annotated",
+				"--txt-decrypt-aes-key=retired-key-1",
+				"--txt-decrypt-aes-key=retired-key-2",
+				"--txt-delete-orphans",
+				"--txt-name-template=%{record_type}-%{label}.%{domain}",
+				"--txt-apex-name-template=%{record_type}._owner.%{name}",
+				"--txt-format=compact",
+				"--configmap-registry-name=dns-ownership",
+				"--configmap-registry-namespace=external-dns",
 				"--txt-prefix=associated-txt-record",
 				"--txt-cache-interval=12h",
+				"--provider-cache-interval=30m",
 				"--interval=10m",
 				"--min-event-sync-interval=50s",
 				"--once",
+				"--check",
 				"--dry-run",
+				"--plan-output=json",
+				"--emit-events",
 				"--events",
 				"--log-format=json",
 				"--metrics-address=127.0.0.1:9099",
@@ -380,6 +450,15 @@ func TestParseFlags(t *testing.T) {
 				"--ibmcloud-config-file=ibmcloud.json",
 				"--tencent-cloud-config-file=tencent-cloud.json",
 				"--tencent-cloud-zone-type=private",
+				"--webhook-provider-url=http://localhost:8889",
+				"--webhook-server",
+				"--webhook-server-address=localhost:8889",
+				"--leader-election",
+				"--leader-election-lease-name=external-dns-ha",
+				"--leader-election-namespace=external-dns",
+				"--leader-election-lease-duration=30s",
+				"--leader-election-renew-deadline=20s",
+				"--leader-election-retry-period=5s",
 			},
 			envVars:  map[string]string{},
 			expected: overriddenConfig,
@@ -401,7 +480,12 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_IGNORE_INGRESS_TLS_SPEC":         "1",
 				"EXTERNAL_DNS_IGNORE_INGRESS_RULES_SPEC":       "1",
 				"EXTERNAL_DNS_COMPATIBILITY":                   "mate",
+				"EXTERNAL_DNS_PUBLISH_POD_IPS":                 "1",
+				"EXTERNAL_DNS_POD_NAMESPACE_LABEL_FILTER":      "dns=pods",
+				"EXTERNAL_DNS_POD_FQDN_TEMPLATE":               "{{.Name}}.pod.example.com",
+				"EXTERNAL_DNS_UNSTRUCTURED_RESOURCE":           "ingressroutes.v1alpha1.traefik.containo.us={.spec.host},{.status.loadBalancer.ingress[*].ip}\nroutes.v1.serving.knative.dev",
 				"EXTERNAL_DNS_PROVIDER":                        "google",
+				"EXTERNAL_DNS_PIPELINES_CONFIG":                "/etc/external-dns/pipelines.yaml",
 				"EXTERNAL_DNS_GOOGLE_PROJECT":                  "project",
 				"EXTERNAL_DNS_GOOGLE_BATCH_CHANGE_SIZE":        "100",
 				"EXTERNAL_DNS_GOOGLE_BATCH_CHANGE_INTERVAL":    "2s",
@@ -465,14 +549,28 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_AWS_ZONES_CACHE_DURATION":        "10s",
 				"EXTERNAL_DNS_AWS_SD_SERVICE_CLEANUP":          "true",
 				"EXTERNAL_DNS_POLICY":                          "upsert-only",
+				"EXTERNAL_DNS_CONFLICT_RESOLVER":               "oldest-resource",
 				"EXTERNAL_DNS_REGISTRY":                        "noop",
 				"EXTERNAL_DNS_TXT_OWNER_ID":                    "owner-1",
+				"EXTERNAL_DNS_ADOPT_OWNER_ID":                  "owner-0\nlegacy",
+				"EXTERNAL_DNS_ADOPT_ANNOTATED":                 "1",
+				"EXTERNAL_DNS_TXT_DECRYPT_AES_KEY":             "retired-key-1\nretired-key-2",
+				"EXTERNAL_DNS_TXT_DELETE_ORPHANS":              "1",
+				"EXTERNAL_DNS_TXT_NAME_TEMPLATE":               "%{record_type}-%{label}.%{domain}",
+				"EXTERNAL_DNS_TXT_APEX_NAME_TEMPLATE":          "%{record_type}._owner.%{name}",
+				"EXTERNAL_DNS_TXT_FORMAT":                      "compact",
+				"EXTERNAL_DNS_CONFIGMAP_REGISTRY_NAME":         "dns-ownership",
+				"EXTERNAL_DNS_CONFIGMAP_REGISTRY_NAMESPACE":    "external-dns",
 				"EXTERNAL_DNS_TXT_PREFIX":                      "associated-txt-record",
 				"EXTERNAL_DNS_TXT_CACHE_INTERVAL":              "12h",
+				"EXTERNAL_DNS_PROVIDER_CACHE_INTERVAL":         "30m",
 				"EXTERNAL_DNS_INTERVAL":                        "10m",
 				"EXTERNAL_DNS_MIN_EVENT_SYNC_INTERVAL":         "50s",
 				"EXTERNAL_DNS_ONCE":                            "1",
+				"EXTERNAL_DNS_CHECK":                           "1",
 				"EXTERNAL_DNS_DRY_RUN":                         "1",
+				"EXTERNAL_DNS_PLAN_OUTPUT":                     "json",
+				"EXTERNAL_DNS_EMIT_EVENTS":                     "1",
 				"EXTERNAL_DNS_EVENTS":                          "1",
 				"EXTERNAL_DNS_LOG_FORMAT":                      "json",
 				"EXTERNAL_DNS_METRICS_ADDRESS":                 "127.0.0.1:9099",
@@ -495,6 +593,15 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_IBMCLOUD_CONFIG_FILE":            "ibmcloud.json",
 				"EXTERNAL_DNS_TENCENT_CLOUD_CONFIG_FILE":       "tencent-cloud.json",
 				"EXTERNAL_DNS_TENCENT_CLOUD_ZONE_TYPE":         "private",
+				"EXTERNAL_DNS_WEBHOOK_PROVIDER_URL":            "http://localhost:8889",
+				"EXTERNAL_DNS_WEBHOOK_SERVER":                  "1",
+				"EXTERNAL_DNS_WEBHOOK_SERVER_ADDRESS":          "localhost:8889",
+				"EXTERNAL_DNS_LEADER_ELECTION":                 "1",
+				"EXTERNAL_DNS_LEADER_ELECTION_LEASE_NAME":      "external-dns-ha",
+				"EXTERNAL_DNS_LEADER_ELECTION_NAMESPACE":       "external-dns",
+				"EXTERNAL_DNS_LEADER_ELECTION_LEASE_DURATION":  "30s",
+				"EXTERNAL_DNS_LEADER_ELECTION_RENEW_DEADLINE":  "20s",
+				"EXTERNAL_DNS_LEADER_ELECTION_RETRY_PERIOD":    "5s",
 			},
 			expected: overriddenConfig,
 		},
@@ -535,6 +642,8 @@ func TestPasswordsNotLogged(t *testing.T) {
 		InfobloxWapiPassword: "infoblox-pass",
 		PDNSAPIKey:           "pdns-api-key",
 		RFC2136TSIGSecret:    "tsig-secret",
+		TXTEncryptAESKey:     "txt-encrypt-aes-key",
+		TXTDecryptAESKeys:    []string{"txt-decrypt-aes-key"},
 	}
 
 	s := cfg.String()
@@ -543,4 +652,7 @@ func TestPasswordsNotLogged(t *testing.T) {
 	assert.False(t, strings.Contains(s, "infoblox-pass"))
 	assert.False(t, strings.Contains(s, "pdns-api-key"))
 	assert.False(t, strings.Contains(s, "tsig-secret"))
+	assert.False(t, strings.Contains(s, "txt-encrypt-aes-key"))
+	assert.False(t, strings.Contains(s, "txt-decrypt-aes-key"))
+	assert.Equal(t, []string{"txt-decrypt-aes-key"}, cfg.TXTDecryptAESKeys, "the config itself should not be masked")
 }
@@ -31,12 +31,15 @@ func ValidateConfig(cfg *externaldns.Config) error {
 	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
 		return fmt.Errorf("unsupported log format: %s", cfg.LogFormat)
 	}
-	if len(cfg.Sources) == 0 {
+	if len(cfg.Sources) == 0 && !cfg.WebhookServer {
 		return errors.New("no sources specified")
 	}
-	if cfg.Provider == "" {
+	if cfg.Provider == "" && cfg.PipelinesConfig == "" {
 		return errors.New("no provider specified")
 	}
+	if cfg.PipelinesConfig != "" && cfg.WebhookServer {
+		return errors.New("--webhook-server cannot be used with --pipelines-config")
+	}
 
 	// Azure provider specific validations
 	if cfg.Provider == "azure" {
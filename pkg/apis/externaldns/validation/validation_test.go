@@ -50,6 +50,25 @@ func TestValidateFlags(t *testing.T) {
 	cfg = newValidConfig(t)
 	cfg.Provider = ""
 	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Provider = ""
+	cfg.PipelinesConfig = "pipelines.yaml"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.PipelinesConfig = "pipelines.yaml"
+	cfg.WebhookServer = true
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Sources = nil
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Sources = nil
+	cfg.WebhookServer = true
+	assert.NoError(t, ValidateConfig(cfg))
 }
 
 func newValidConfig(t *testing.T) *externaldns.Config {
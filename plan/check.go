@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// InconsistencyKind tells how a DNS record differs from the desired one
+type InconsistencyKind string
+
+const (
+	// InconsistencyNotDesired is used for records of the owner which are not desired anymore
+	InconsistencyNotDesired InconsistencyKind = "not-desired"
+	// InconsistencyMissing is used for desired records which do not exist
+	InconsistencyMissing InconsistencyKind = "missing"
+	// InconsistencyChanged is used for records whose targets, TTL or properties differ from the desired ones
+	InconsistencyChanged InconsistencyKind = "changed"
+	// InconsistencyForeignOwner is used for desired records which are blocked by the record of another owner
+	InconsistencyForeignOwner InconsistencyKind = "foreign-owner"
+	// InconsistencyOutsideDomainFilter is used for desired records which do not match the domain filter
+	InconsistencyOutsideDomainFilter InconsistencyKind = "outside-domain-filter"
+	// InconsistencyOrphanedOwnership is used for ownership records whose record does not exist anymore
+	InconsistencyOrphanedOwnership InconsistencyKind = "orphaned-ownership"
+)
+
+// Inconsistency is a difference between the DNS records and the desired ones
+type Inconsistency struct {
+	Kind InconsistencyKind
+	// Endpoint is the desired record, or the current one when it is not desired
+	Endpoint *endpoint.Endpoint
+	// Current is the current record of a changed record
+	Current *endpoint.Endpoint
+	// Pipeline is the name of the pipeline which found the inconsistency, empty without pipelines
+	Pipeline string
+}
+
+// Inconsistencies returns the differences between the current and the desired records found by the calculated
+// plan: the changes it would apply or the policy held back, the desired records it skipped because of their owner or
+// the domain filter, and the desired records matching a record of another owner.
+func (p *Plan) Inconsistencies() []*Inconsistency {
+	inconsistencies := []*Inconsistency{}
+	if p.PreChanges != nil {
+		for _, ep := range p.PreChanges.WithoutSkipped(p.Skipped).Delete {
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyNotDesired, Endpoint: ep})
+		}
+	}
+	if p.Changes != nil {
+		changes := p.Changes.WithoutSkipped(p.Skipped)
+		for _, ep := range changes.Delete {
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyNotDesired, Endpoint: ep})
+		}
+		for _, ep := range changes.Create {
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyMissing, Endpoint: ep})
+		}
+		for i := range changes.UpdateNew {
+			if i >= len(changes.UpdateOld) {
+				break
+			}
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyChanged, Endpoint: changes.UpdateNew[i], Current: changes.UpdateOld[i]})
+		}
+	}
+	for _, s := range p.Skipped {
+		switch s.Reason {
+		case SkipReasonForeignOwner:
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyForeignOwner, Endpoint: s.Endpoint})
+		case SkipReasonDomainFilter:
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyOutsideDomainFilter, Endpoint: s.Endpoint})
+		case SkipReasonPolicy:
+			inconsistencies = append(inconsistencies, p.heldBackInconsistency(s))
+		}
+	}
+	return append(inconsistencies, p.unchangedForeignRecords(inconsistencies)...)
+}
+
+// heldBackInconsistency returns the inconsistency of a change held back by the policy
+func (p *Plan) heldBackInconsistency(s *SkippedEndpoint) *Inconsistency {
+	switch {
+	case s.Current == nil:
+		return &Inconsistency{Kind: InconsistencyMissing, Endpoint: s.Endpoint}
+	case p.isForeign(s.Current):
+		return &Inconsistency{Kind: InconsistencyForeignOwner, Endpoint: s.Endpoint}
+	case s.Current == s.Endpoint:
+		return &Inconsistency{Kind: InconsistencyNotDesired, Endpoint: s.Endpoint}
+	default:
+		return &Inconsistency{Kind: InconsistencyChanged, Endpoint: s.Endpoint, Current: s.Current}
+	}
+}
+
+// unchangedForeignRecords returns the desired records which are not reported yet, while a record of another owner
+// with the same name, type and set identifier exists: they need no change, but they are not under control either.
+func (p *Plan) unchangedForeignRecords(reported []*Inconsistency) []*Inconsistency {
+	if p.OwnerID == "" {
+		return nil
+	}
+	key := func(ep *endpoint.Endpoint) string {
+		return normalizeDNSName(ep.DNSName) + "/" + ep.RecordType + "/" + ep.SetIdentifier
+	}
+	foreign := map[string]bool{}
+	for _, ep := range p.Current {
+		if p.isForeign(ep) {
+			foreign[key(ep)] = true
+		}
+	}
+	done := map[string]bool{}
+	for _, i := range reported {
+		done[key(i.Endpoint)] = true
+	}
+	for _, s := range p.Skipped {
+		done[key(s.Endpoint)] = true
+	}
+
+	var inconsistencies []*Inconsistency
+	for _, ep := range p.Desired {
+		if foreign[key(ep)] && !done[key(ep)] {
+			done[key(ep)] = true
+			inconsistencies = append(inconsistencies, &Inconsistency{Kind: InconsistencyForeignOwner, Endpoint: ep})
+		}
+	}
+	return inconsistencies
+}
+
+// isForeign tells whether the current record is owned by somebody else, and neither adopted nor shared
+func (p *Plan) isForeign(current *endpoint.Endpoint) bool {
+	return p.OwnerID != "" && current.Labels[endpoint.OwnerLabelKey] != p.OwnerID && current.Labels[endpoint.AdoptLabelKey] != p.OwnerID && !isShared(current)
+}
+
+type explainedInconsistency struct {
+	explainedRecord
+	Kind     InconsistencyKind `json:"kind"`
+	Current  *explainedRecord  `json:"current,omitempty"`
+	Pipeline string            `json:"pipeline,omitempty"`
+}
+
+type checkReport struct {
+	Inconsistencies []explainedInconsistency `json:"inconsistencies"`
+}
+
+// ExplainInconsistencies writes a description of the inconsistencies to w, including the resource and owner of every
+// record. The format is either OutputFormatText or OutputFormatJSON.
+func ExplainInconsistencies(w io.Writer, format string, inconsistencies []*Inconsistency) error {
+	report := checkReport{Inconsistencies: []explainedInconsistency{}}
+	for _, i := range inconsistencies {
+		e := explainedInconsistency{explainedRecord: newExplainedRecord(i.Endpoint), Kind: i.Kind, Pipeline: i.Pipeline}
+		if i.Current != nil {
+			current := newExplainedRecord(i.Current)
+			e.Current = &current
+		}
+		report.Inconsistencies = append(report.Inconsistencies, e)
+	}
+	switch format {
+	case OutputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case OutputFormatText:
+		return report.writeText(w)
+	default:
+		return fmt.Errorf("unknown plan output format: %q", format)
+	}
+}
+
+func (r checkReport) writeText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Check: %d inconsistencies\n", len(r.Inconsistencies))
+	for _, i := range r.Inconsistencies {
+		fmt.Fprintf(&b, "! %-21s ", i.Kind)
+		if i.Current != nil {
+			fmt.Fprintf(&b, "%s -> %s%s", i.Current.describe(), i.describeChange(*i.Current), i.origin())
+		} else {
+			fmt.Fprintf(&b, "%s%s", i.describe(), i.origin())
+		}
+		if i.Pipeline != "" {
+			fmt.Fprintf(&b, " in pipeline %s", i.Pipeline)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestInconsistencies(t *testing.T) {
+	p := &Plan{
+		Changes: newExplainTestChanges(),
+		PreChanges: &Changes{
+			Delete: []*endpoint.Endpoint{newExplainTestEndpoint("qux.example.org", endpoint.RecordTypeA, "1.1.1.1", "", "default")},
+		},
+		Skipped: append(newExplainTestSkipped(),
+			&SkippedEndpoint{Endpoint: newExplainTestEndpoint("other.example.org", endpoint.RecordTypeA, "1.1.1.1", "service/default/other", ""), Reason: SkipReasonForeignOwner},
+			&SkippedEndpoint{Endpoint: newExplainTestEndpoint("mx.example.org", endpoint.RecordTypeMX, "10 mail.example.org", "", ""), Reason: SkipReasonUnmanagedType},
+		),
+	}
+
+	kinds := map[string]InconsistencyKind{}
+	for _, i := range p.Inconsistencies() {
+		kinds[i.Endpoint.DNSName] = i.Kind
+	}
+	assert.Equal(t, map[string]InconsistencyKind{
+		"qux.example.org":   InconsistencyNotDesired,
+		"baz.example.org":   InconsistencyNotDesired,
+		"foo.example.org":   InconsistencyMissing,
+		"bar.example.org":   InconsistencyChanged,
+		"other.example.org": InconsistencyForeignOwner,
+		"foo.example.com":   InconsistencyOutsideDomainFilter,
+	}, kinds)
+}
+
+func TestInconsistenciesHeldBackByPolicy(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		newExplainTestEndpoint("old.example.org", endpoint.RecordTypeA, "1.1.1.1", "", "owner"),
+		newExplainTestEndpoint("foo.example.org", endpoint.RecordTypeA, "1.1.1.1", "", "owner"),
+		newExplainTestEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1", "", "other"),
+	}
+	p := &Plan{
+		Policies: []Policy{&CreateOnlyPolicy{}},
+		Current:  current,
+		Desired: []*endpoint.Endpoint{
+			newExplainTestEndpoint("foo.example.org", endpoint.RecordTypeA, "2.2.2.2", "service/default/foo", ""),
+			newExplainTestEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1", "service/default/bar", ""),
+		},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        "owner",
+	}
+
+	inconsistencies := p.Calculate().Inconsistencies()
+	kinds := map[string]InconsistencyKind{}
+	for _, i := range inconsistencies {
+		kinds[i.Endpoint.DNSName] = i.Kind
+		if i.Kind == InconsistencyChanged {
+			assert.Equal(t, current[1], i.Current)
+		}
+	}
+	assert.Equal(t, map[string]InconsistencyKind{
+		"old.example.org": InconsistencyNotDesired,
+		"foo.example.org": InconsistencyChanged,
+		// the record of the other owner needs no change, but it is not under control either
+		"bar.example.org": InconsistencyForeignOwner,
+	}, kinds)
+}
+
+func TestExplainInconsistencies(t *testing.T) {
+	inconsistencies := (&Plan{Changes: newExplainTestChanges(), Skipped: newExplainTestSkipped()}).Inconsistencies()
+	inconsistencies = append(inconsistencies, &Inconsistency{
+		Kind:     InconsistencyOrphanedOwnership,
+		Endpoint: newExplainTestEndpoint("a-qux.example.org", endpoint.RecordTypeTXT, "heritage=external-dns", "", "default"),
+		Pipeline: "public",
+	})
+
+	b := new(bytes.Buffer)
+	require.NoError(t, ExplainInconsistencies(b, OutputFormatText, inconsistencies))
+	expected := `Check: 5 inconsistencies
+! not-desired           baz.example.org CNAME [foo.example.org] (owner=default)
+! missing               foo.example.org A [1.1.1.1] (resource=service/default/foo, owner=default)
+! changed               bar.example.org A [1.1.1.1] -> [2.2.2.2] ttl=300 (resource=ingress/default/bar, owner=default)
+! outside-domain-filter foo.example.com A [1.1.1.1] (resource=service/default/other)
+! orphaned-ownership    a-qux.example.org TXT [heritage=external-dns] (owner=default) in pipeline public
+`
+	assert.Equal(t, expected, b.String())
+
+	b.Reset()
+	require.NoError(t, ExplainInconsistencies(b, OutputFormatJSON, inconsistencies))
+	var report checkReport
+	require.NoError(t, json.Unmarshal(b.Bytes(), &report))
+	require.Len(t, report.Inconsistencies, 5)
+	assert.Equal(t, InconsistencyChanged, report.Inconsistencies[2].Kind)
+	require.NotNil(t, report.Inconsistencies[2].Current)
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, report.Inconsistencies[2].Current.Targets)
+	assert.Equal(t, endpoint.Targets{"2.2.2.2"}, report.Inconsistencies[2].Targets)
+	assert.Equal(t, "public", report.Inconsistencies[4].Pipeline)
+
+	require.NoError(t, ExplainInconsistencies(b, OutputFormatText, nil))
+	assert.Error(t, ExplainInconsistencies(b, "yaml", inconsistencies))
+}
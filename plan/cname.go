@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// nameRecords holds the records of a single dns name
+type nameRecords struct {
+	current []*endpoint.Endpoint
+	deleted map[*endpoint.Endpoint]bool
+	created []*endpoint.Endpoint
+}
+
+// separateRecordTypeChanges enforces that per RFC 1034 a CNAME record is the only record of its dns name.
+// Records to create which would end up next to a CNAME record (or a CNAME record which would end up next
+// to other records) are removed from the changes and returned as skipped.
+// When a dns name changes from or to a CNAME record, the deletions of the records of the
+// old type are moved out of the changes, as they must be applied before the creations.
+// The deletions in ignored are not applied by the registry, their records are kept.
+func separateRecordTypeChanges(current []*endpoint.Endpoint, changes *Changes, ignored map[*endpoint.Endpoint]bool) (deleteFirst []*endpoint.Endpoint, skipped []*SkippedEndpoint) {
+	names := map[string]*nameRecords{}
+	records := func(dnsName string) *nameRecords {
+		key := normalizeDNSName(dnsName)
+		if _, ok := names[key]; !ok {
+			names[key] = &nameRecords{deleted: map[*endpoint.Endpoint]bool{}}
+		}
+		return names[key]
+	}
+	for _, ep := range current {
+		records(ep.DNSName).current = append(records(ep.DNSName).current, ep)
+	}
+	for _, ep := range changes.Delete {
+		if !ignored[ep] {
+			records(ep.DNSName).deleted[ep] = true
+		}
+	}
+	for _, ep := range changes.Create {
+		records(ep.DNSName).created = append(records(ep.DNSName).created, ep)
+	}
+
+	refused := map[*endpoint.Endpoint]bool{}
+	first := map[*endpoint.Endpoint]bool{}
+	for _, n := range names {
+		if len(n.created) == 0 {
+			continue
+		}
+
+		types := map[string]bool{}
+		for _, ep := range n.current {
+			if !n.deleted[ep] {
+				types[ep.RecordType] = true
+			}
+		}
+		for _, ep := range n.created {
+			types[ep.RecordType] = true
+		}
+
+		if types[endpoint.RecordTypeCNAME] && len(types) > 1 {
+			for _, ep := range n.created {
+				log.Errorf("Refusing to create %s record %s: a CNAME record cannot coexist with other records of the same name, found %v", ep.RecordType, ep.DNSName, sortedTypes(types))
+				refused[ep] = true
+				skipped = append(skipped, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonRecordTypeConflict})
+			}
+			continue
+		}
+
+		if !types[endpoint.RecordTypeCNAME] && !n.deletesType(endpoint.RecordTypeCNAME) {
+			continue
+		}
+		for ep := range n.deleted {
+			first[ep] = true
+		}
+	}
+
+	if len(refused) > 0 {
+		var create []*endpoint.Endpoint
+		for _, ep := range changes.Create {
+			if !refused[ep] {
+				create = append(create, ep)
+			}
+		}
+		changes.Create = create
+	}
+	if len(first) > 0 {
+		var remaining []*endpoint.Endpoint
+		for _, ep := range changes.Delete {
+			if first[ep] {
+				deleteFirst = append(deleteFirst, ep)
+			} else {
+				remaining = append(remaining, ep)
+			}
+		}
+		changes.Delete = remaining
+	}
+
+	return deleteFirst, skipped
+}
+
+func (n *nameRecords) deletesType(recordType string) bool {
+	for ep := range n.deleted {
+		if ep.RecordType == recordType {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedTypes(types map[string]bool) []string {
+	var s []string
+	for t := range types {
+		s = append(s, t)
+	}
+	sort.Strings(s)
+	return s
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package plan
 
 import (
+	"math"
 	"sort"
+	"strconv"
+	"time"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -29,6 +32,14 @@ type ConflictResolver interface {
 	ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint
 }
 
+// ConflictResolvers is a registry of available conflict resolvers.
+var ConflictResolvers = map[string]ConflictResolver{
+	"per-resource":    PerResource{},
+	"merge-targets":   MergeTargets{},
+	"priority":        PriorityByAnnotation{},
+	"oldest-resource": OldestResource{},
+}
+
 // PerResource allows only one resource to own a given dns name
 type PerResource struct{}
 
@@ -67,4 +78,114 @@ func (s PerResource) less(x, y *endpoint.Endpoint) bool {
 	return x.Targets.IsLess(y.Targets)
 }
 
-// TODO: with cross-resource/cross-cluster setup alternative variations of ConflictResolver can be used
+// MergeTargets allows several resources to share a given dns name and record type
+// by combining the targets of all of them into a single record.
+// The other properties of the record (TTL, provider specific, labels) are taken from
+// the endpoint PerResource would have picked.
+// CNAME records cannot have more than one target, so they are resolved as PerResource.
+type MergeTargets struct{}
+
+// ResolveCreate is invoked when dns name is not owned by any resource
+func (s MergeTargets) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return mergeTargets(PerResource{}.ResolveCreate(candidates), candidates)
+}
+
+// ResolveUpdate is invoked when dns name is already owned by "current" endpoint
+func (s MergeTargets) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return mergeTargets(PerResource{}.ResolveUpdate(current, candidates), candidates)
+}
+
+// mergeTargets returns a copy of base holding the targets of all candidates
+func mergeTargets(base *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	if base == nil || base.RecordType == endpoint.RecordTypeCNAME || len(candidates) < 2 {
+		return base
+	}
+
+	seen := map[string]struct{}{}
+	targets := endpoint.Targets{}
+	for _, ep := range candidates {
+		for _, t := range ep.Targets {
+			if _, ok := seen[t]; ok {
+				continue
+			}
+			seen[t] = struct{}{}
+			targets = append(targets, t)
+		}
+	}
+	sort.Sort(targets)
+
+	merged := *base
+	merged.Targets = targets
+	merged.Labels = endpoint.NewLabels()
+	for k, v := range base.Labels {
+		merged.Labels[k] = v
+	}
+	return &merged
+}
+
+// PriorityByAnnotation gives a dns name to the resource with the highest priority,
+// set with the external-dns.alpha.kubernetes.io/priority annotation.
+// Resources without a valid priority have priority 0.
+// Resources with the same priority are resolved as PerResource.
+type PriorityByAnnotation struct{}
+
+// ResolveCreate is invoked when dns name is not owned by any resource
+func (s PriorityByAnnotation) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return PerResource{}.ResolveCreate(bestCandidates(candidates, priority))
+}
+
+// ResolveUpdate is invoked when dns name is already owned by "current" endpoint
+// the current resource keeps the dns name unless another resource has a higher priority
+func (s PriorityByAnnotation) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return PerResource{}.ResolveUpdate(current, bestCandidates(candidates, priority))
+}
+
+func priority(ep *endpoint.Endpoint) int64 {
+	p, err := strconv.ParseInt(ep.Labels[endpoint.PriorityLabelKey], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return p
+}
+
+// OldestResource gives a dns name to the resource which was created first.
+// Resources without a known creation timestamp are considered the newest.
+// Resources created at the same time are resolved as PerResource.
+type OldestResource struct{}
+
+// ResolveCreate is invoked when dns name is not owned by any resource
+func (s OldestResource) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return PerResource{}.ResolveCreate(bestCandidates(candidates, age))
+}
+
+// ResolveUpdate is invoked when dns name is already owned by "current" endpoint
+// the current resource keeps the dns name unless an older resource claims it
+func (s OldestResource) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return PerResource{}.ResolveUpdate(current, bestCandidates(candidates, age))
+}
+
+// age returns a score which is higher for older resources
+func age(ep *endpoint.Endpoint) int64 {
+	created, err := time.Parse(time.RFC3339, ep.Labels[endpoint.ResourceCreationTimestampLabelKey])
+	if err != nil {
+		return math.MinInt64
+	}
+	return -created.Unix()
+}
+
+// bestCandidates returns the candidates with the highest score
+func bestCandidates(candidates []*endpoint.Endpoint, score func(*endpoint.Endpoint) int64) []*endpoint.Endpoint {
+	var best []*endpoint.Endpoint
+	var max int64
+	for _, ep := range candidates {
+		s := score(ep)
+		switch {
+		case len(best) == 0 || s > max:
+			best = []*endpoint.Endpoint{ep}
+			max = s
+		case s == max:
+			best = append(best, ep)
+		}
+	}
+	return best
+}
@@ -19,12 +19,18 @@ package plan
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
-var _ ConflictResolver = PerResource{}
+var (
+	_ ConflictResolver = PerResource{}
+	_ ConflictResolver = MergeTargets{}
+	_ ConflictResolver = PriorityByAnnotation{}
+	_ ConflictResolver = OldestResource{}
+)
 
 type ResolverSuite struct {
 	// resolvers
@@ -136,3 +142,60 @@ func (suite *ResolverSuite) TestStrictResolver() {
 func TestConflictResolver(t *testing.T) {
 	suite.Run(t, new(ResolverSuite))
 }
+
+func newResolverTestEndpoint(recordType, target, resource string, labels map[string]string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint("foo", recordType, target)
+	ep.Labels[endpoint.ResourceLabelKey] = resource
+	for k, v := range labels {
+		ep.Labels[k] = v
+	}
+	return ep
+}
+
+func TestMergeTargets(t *testing.T) {
+	a := newResolverTestEndpoint(endpoint.RecordTypeA, "5.5.5.5", "service/default/a", nil)
+	b := newResolverTestEndpoint(endpoint.RecordTypeA, "1.1.1.1", "service/default/b", nil)
+	c := newResolverTestEndpoint(endpoint.RecordTypeA, "5.5.5.5", "service/default/c", nil)
+	resolver := MergeTargets{}
+
+	merged := resolver.ResolveCreate([]*endpoint.Endpoint{a, b, c})
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "5.5.5.5"}, merged.Targets)
+	assert.Equal(t, "service/default/b", merged.Labels[endpoint.ResourceLabelKey], "should take the other properties from the per-resource choice")
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, b.Targets, "should not modify the candidates")
+
+	merged = resolver.ResolveUpdate(a, []*endpoint.Endpoint{a, b})
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "5.5.5.5"}, merged.Targets)
+	assert.Equal(t, "service/default/a", merged.Labels[endpoint.ResourceLabelKey], "should keep the current resource")
+
+	assert.Equal(t, a, resolver.ResolveCreate([]*endpoint.Endpoint{a}), "should return a single candidate as is")
+
+	cnameV1 := newResolverTestEndpoint(endpoint.RecordTypeCNAME, "v1", "ingress/default/v1", nil)
+	cnameV2 := newResolverTestEndpoint(endpoint.RecordTypeCNAME, "v2", "ingress/default/v2", nil)
+	assert.Equal(t, cnameV1, resolver.ResolveCreate([]*endpoint.Endpoint{cnameV2, cnameV1}), "should not merge CNAME targets")
+}
+
+func TestPriorityByAnnotation(t *testing.T) {
+	low := newResolverTestEndpoint(endpoint.RecordTypeA, "1.1.1.1", "service/default/low", map[string]string{endpoint.PriorityLabelKey: "-1"})
+	none := newResolverTestEndpoint(endpoint.RecordTypeA, "2.2.2.2", "service/default/none", nil)
+	high := newResolverTestEndpoint(endpoint.RecordTypeA, "3.3.3.3", "service/default/high", map[string]string{endpoint.PriorityLabelKey: "10"})
+	invalid := newResolverTestEndpoint(endpoint.RecordTypeA, "0.0.0.0", "service/default/invalid", map[string]string{endpoint.PriorityLabelKey: "high"})
+	resolver := PriorityByAnnotation{}
+
+	assert.Equal(t, high, resolver.ResolveCreate([]*endpoint.Endpoint{low, none, high}))
+	assert.Equal(t, invalid, resolver.ResolveCreate([]*endpoint.Endpoint{low, none, invalid}), "should treat invalid priorities as 0 and pick the minimum")
+	assert.Equal(t, high, resolver.ResolveUpdate(none, []*endpoint.Endpoint{low, none, high}), "should hand over the name to a higher priority resource")
+	assert.Equal(t, high, resolver.ResolveUpdate(high, []*endpoint.Endpoint{low, none, high}))
+	assert.Equal(t, none, resolver.ResolveUpdate(none, []*endpoint.Endpoint{low, none, invalid}), "should keep the current resource on equal priority")
+}
+
+func TestOldestResource(t *testing.T) {
+	oldest := newResolverTestEndpoint(endpoint.RecordTypeA, "3.3.3.3", "service/default/oldest", map[string]string{endpoint.ResourceCreationTimestampLabelKey: "2020-01-01T00:00:00Z"})
+	newest := newResolverTestEndpoint(endpoint.RecordTypeA, "1.1.1.1", "service/default/newest", map[string]string{endpoint.ResourceCreationTimestampLabelKey: "2023-01-01T00:00:00Z"})
+	unknown := newResolverTestEndpoint(endpoint.RecordTypeA, "0.0.0.0", "service/default/unknown", nil)
+	resolver := OldestResource{}
+
+	assert.Equal(t, oldest, resolver.ResolveCreate([]*endpoint.Endpoint{newest, unknown, oldest}))
+	assert.Equal(t, newest, resolver.ResolveCreate([]*endpoint.Endpoint{newest, unknown}), "should consider resources without timestamp the newest")
+	assert.Equal(t, oldest, resolver.ResolveUpdate(newest, []*endpoint.Endpoint{newest, oldest}), "should hand over the name to an older resource")
+	assert.Equal(t, newest, resolver.ResolveUpdate(newest, []*endpoint.Endpoint{newest, unknown}))
+}
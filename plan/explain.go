@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Formats supported by Changes.Explain
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// SkipReason tells why a record was left out of the changes
+type SkipReason string
+
+const (
+	// SkipReasonDomainFilter is used for records which do not match the domain filter
+	SkipReasonDomainFilter SkipReason = "domain-filter"
+	// SkipReasonUnmanagedType is used for records of a type which is not managed
+	SkipReasonUnmanagedType SkipReason = "unmanaged-record-type"
+	// SkipReasonForeignOwner is used for records which are owned by somebody else
+	SkipReasonForeignOwner SkipReason = "foreign-owner"
+	// SkipReasonConflict is used for records which lost the conflict against another resource claiming the same name
+	SkipReasonConflict SkipReason = "lost-conflict"
+	// SkipReasonRecordTypeConflict is used for records which would end up next to a CNAME record of the same name
+	SkipReasonRecordTypeConflict SkipReason = "record-type-conflict"
+	// SkipReasonPolicy is used for records whose change is held back by the policy, e.g. upsert-only or create-only
+	SkipReasonPolicy SkipReason = "policy"
+	// SkipReasonRetained is used for records which are not desired anymore but kept, as they are retained on delete
+	SkipReasonRetained SkipReason = "retained"
+)
+
+// SkippedEndpoint is a record which was left out of the changes
+type SkippedEndpoint struct {
+	Endpoint *endpoint.Endpoint
+	Reason   SkipReason
+	// Current is the current record of an update or deletion held back by the policy, which is Endpoint for a deletion
+	Current *endpoint.Endpoint
+}
+
+// explainedRecord is the representation of a record in the explanation of the changes
+type explainedRecord struct {
+	DNSName       string           `json:"dnsName"`
+	RecordType    string           `json:"recordType"`
+	SetIdentifier string           `json:"setIdentifier,omitempty"`
+	Targets       endpoint.Targets `json:"targets"`
+	RecordTTL     endpoint.TTL     `json:"recordTTL,omitempty"`
+	Resource      string           `json:"resource,omitempty"`
+	Owner         string           `json:"owner,omitempty"`
+}
+
+type explainedUpdate struct {
+	Old explainedRecord `json:"old"`
+	New explainedRecord `json:"new"`
+	// AdoptedFrom is the previous owner of an adopted record
+	AdoptedFrom string `json:"adoptedFrom,omitempty"`
+}
+
+type explainedSkip struct {
+	explainedRecord
+	Reason SkipReason `json:"reason"`
+}
+
+type explanation struct {
+	Create  []explainedRecord `json:"create"`
+	Update  []explainedUpdate `json:"update"`
+	Delete  []explainedRecord `json:"delete"`
+	Skipped []explainedSkip   `json:"skipped"`
+}
+
+func newExplainedRecord(ep *endpoint.Endpoint) explainedRecord {
+	return explainedRecord{
+		DNSName:       ep.DNSName,
+		RecordType:    ep.RecordType,
+		SetIdentifier: ep.SetIdentifier,
+		Targets:       ep.Targets,
+		RecordTTL:     ep.RecordTTL,
+		Resource:      ep.Labels[endpoint.ResourceLabelKey],
+		Owner:         ep.Labels[endpoint.OwnerLabelKey],
+	}
+}
+
+// skippedEndpoints returns the set of the skipped endpoints
+func skippedEndpoints(skipped []*SkippedEndpoint) map[*endpoint.Endpoint]bool {
+	set := make(map[*endpoint.Endpoint]bool, len(skipped))
+	for _, s := range skipped {
+		set[s.Endpoint] = true
+	}
+	return set
+}
+
+// WithoutSkipped returns the changes without the records reported as skipped, i.e. without the
+// changes of the records owned by somebody else, which are left out by the registry.
+func (c *Changes) WithoutSkipped(skipped []*SkippedEndpoint) *Changes {
+	set := skippedEndpoints(skipped)
+	filtered := &Changes{}
+	for _, ep := range c.Create {
+		if !set[ep] {
+			filtered.Create = append(filtered.Create, ep)
+		}
+	}
+	for i := range c.UpdateNew {
+		if i < len(c.UpdateOld) && !set[c.UpdateNew[i]] {
+			filtered.UpdateOld = append(filtered.UpdateOld, c.UpdateOld[i])
+			filtered.UpdateNew = append(filtered.UpdateNew, c.UpdateNew[i])
+		}
+	}
+	for _, ep := range c.Delete {
+		if !set[ep] {
+			filtered.Delete = append(filtered.Delete, ep)
+		}
+	}
+	return filtered
+}
+
+func (c *Changes) explanation(skipped []*SkippedEndpoint) explanation {
+	c = c.WithoutSkipped(skipped)
+	e := explanation{
+		Create:  []explainedRecord{},
+		Update:  []explainedUpdate{},
+		Delete:  []explainedRecord{},
+		Skipped: []explainedSkip{},
+	}
+	for _, ep := range c.Create {
+		e.Create = append(e.Create, newExplainedRecord(ep))
+	}
+	for i := range c.UpdateOld {
+		if i >= len(c.UpdateNew) {
+			break
+		}
+		u := explainedUpdate{Old: newExplainedRecord(c.UpdateOld[i]), New: newExplainedRecord(c.UpdateNew[i])}
+		if u.Old.Owner != "" && u.New.Owner != "" && u.Old.Owner != u.New.Owner {
+			u.AdoptedFrom = u.Old.Owner
+		}
+		e.Update = append(e.Update, u)
+	}
+	for _, ep := range c.Delete {
+		e.Delete = append(e.Delete, newExplainedRecord(ep))
+	}
+	for _, s := range skipped {
+		e.Skipped = append(e.Skipped, explainedSkip{explainedRecord: newExplainedRecord(s.Endpoint), Reason: s.Reason})
+	}
+	return e
+}
+
+// Explain writes a description of the changes and of the skipped records to w, including
+// the resource and owner of every record and the reason why records were skipped.
+// The format is either OutputFormatText or OutputFormatJSON.
+func (c *Changes) Explain(w io.Writer, format string, skipped []*SkippedEndpoint) error {
+	e := c.explanation(skipped)
+	switch format {
+	case OutputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	case OutputFormatText:
+		return e.writeText(w)
+	default:
+		return fmt.Errorf("unknown plan output format: %q", format)
+	}
+}
+
+func (e explanation) writeText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete, %d skipped\n", len(e.Create), len(e.Update), len(e.Delete), len(e.Skipped))
+	for _, r := range e.Create {
+		fmt.Fprintf(&b, "+ create %s%s\n", r.describe(), r.origin())
+	}
+	for _, u := range e.Update {
+		fmt.Fprintf(&b, "~ update %s -> %s%s", u.Old.describe(), u.New.describeChange(u.Old), u.New.origin())
+		if u.AdoptedFrom != "" {
+			fmt.Fprintf(&b, " adopted from %s", u.AdoptedFrom)
+		}
+		b.WriteString("\n")
+	}
+	for _, r := range e.Delete {
+		fmt.Fprintf(&b, "- delete %s%s\n", r.describe(), r.origin())
+	}
+	for _, s := range e.Skipped {
+		fmt.Fprintf(&b, "! skip   %s%s: %s\n", s.describe(), s.origin(), s.Reason)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// describe returns the name, type and value of the record
+func (r explainedRecord) describe() string {
+	s := fmt.Sprintf("%s %s", r.DNSName, r.RecordType)
+	if r.SetIdentifier != "" {
+		s += fmt.Sprintf(" (set %s)", r.SetIdentifier)
+	}
+	s += " " + r.value()
+	return s
+}
+
+// describeChange returns the value of the record, compared to the old one
+func (r explainedRecord) describeChange(old explainedRecord) string {
+	if r.value() == old.value() {
+		return "unchanged"
+	}
+	return r.value()
+}
+
+func (r explainedRecord) value() string {
+	s := fmt.Sprintf("[%s]", strings.Join(r.Targets, " "))
+	if r.RecordTTL.IsConfigured() {
+		s += fmt.Sprintf(" ttl=%d", r.RecordTTL)
+	}
+	return s
+}
+
+// origin returns the resource and owner of the record
+func (r explainedRecord) origin() string {
+	var parts []string
+	if r.Resource != "" {
+		parts = append(parts, "resource="+r.Resource)
+	}
+	if r.Owner != "" {
+		parts = append(parts, "owner="+r.Owner)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
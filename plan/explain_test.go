@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newExplainTestEndpoint(name, recordType, target, resource, owner string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(name, recordType, target)
+	if resource != "" {
+		ep.Labels[endpoint.ResourceLabelKey] = resource
+	}
+	if owner != "" {
+		ep.Labels[endpoint.OwnerLabelKey] = owner
+	}
+	return ep
+}
+
+func newExplainTestChanges() *Changes {
+	updateNew := newExplainTestEndpoint("bar.example.org", endpoint.RecordTypeA, "2.2.2.2", "ingress/default/bar", "default")
+	updateNew.RecordTTL = 300
+	return &Changes{
+		Create:    []*endpoint.Endpoint{newExplainTestEndpoint("foo.example.org", endpoint.RecordTypeA, "1.1.1.1", "service/default/foo", "default")},
+		UpdateOld: []*endpoint.Endpoint{newExplainTestEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1", "ingress/default/bar", "default")},
+		UpdateNew: []*endpoint.Endpoint{updateNew},
+		Delete:    []*endpoint.Endpoint{newExplainTestEndpoint("baz.example.org", endpoint.RecordTypeCNAME, "foo.example.org", "", "default")},
+	}
+}
+
+func newExplainTestSkipped() []*SkippedEndpoint {
+	return []*SkippedEndpoint{
+		{Endpoint: newExplainTestEndpoint("foo.example.com", endpoint.RecordTypeA, "1.1.1.1", "service/default/other", ""), Reason: SkipReasonDomainFilter},
+	}
+}
+
+func TestExplainText(t *testing.T) {
+	b := new(bytes.Buffer)
+	require.NoError(t, newExplainTestChanges().Explain(b, OutputFormatText, newExplainTestSkipped()))
+
+	expected := `Plan: 1 to create, 1 to update, 1 to delete, 1 skipped
++ create foo.example.org A [1.1.1.1] (resource=service/default/foo, owner=default)
+~ update bar.example.org A [1.1.1.1] -> [2.2.2.2] ttl=300 (resource=ingress/default/bar, owner=default)
+- delete baz.example.org CNAME [foo.example.org] (owner=default)
+! skip   foo.example.com A [1.1.1.1] (resource=service/default/other): domain-filter
+`
+	assert.Equal(t, expected, b.String())
+}
+
+func TestExplainJSON(t *testing.T) {
+	b := new(bytes.Buffer)
+	require.NoError(t, newExplainTestChanges().Explain(b, OutputFormatJSON, newExplainTestSkipped()))
+
+	var e explanation
+	require.NoError(t, json.Unmarshal(b.Bytes(), &e))
+	require.Len(t, e.Create, 1)
+	assert.Equal(t, "service/default/foo", e.Create[0].Resource)
+	assert.Equal(t, "default", e.Create[0].Owner)
+	require.Len(t, e.Update, 1)
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, e.Update[0].Old.Targets)
+	assert.Equal(t, endpoint.Targets{"2.2.2.2"}, e.Update[0].New.Targets)
+	assert.Equal(t, endpoint.TTL(300), e.Update[0].New.RecordTTL)
+	require.Len(t, e.Delete, 1)
+	assert.Equal(t, "baz.example.org", e.Delete[0].DNSName)
+	require.Len(t, e.Skipped, 1)
+	assert.Equal(t, SkipReasonDomainFilter, e.Skipped[0].Reason)
+	assert.Equal(t, "foo.example.com", e.Skipped[0].DNSName)
+}
+
+func TestExplainNoChanges(t *testing.T) {
+	b := new(bytes.Buffer)
+	require.NoError(t, (&Changes{}).Explain(b, OutputFormatJSON, nil))
+	assert.JSONEq(t, `{"create":[],"update":[],"delete":[],"skipped":[]}`, b.String())
+}
+
+func TestExplainUnknownFormat(t *testing.T) {
+	assert.Error(t, (&Changes{}).Explain(new(bytes.Buffer), "yaml", nil))
+}
+
+func TestExplainAdoption(t *testing.T) {
+	changes := &Changes{
+		UpdateOld: []*endpoint.Endpoint{newExplainTestEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1", "ingress/default/bar", "old")},
+		UpdateNew: []*endpoint.Endpoint{newExplainTestEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1", "ingress/default/bar", "new")},
+	}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, changes.Explain(b, OutputFormatText, nil))
+	expected := `Plan: 0 to create, 1 to update, 0 to delete, 0 skipped
+~ update bar.example.org A [1.1.1.1] -> unchanged (resource=ingress/default/bar, owner=new) adopted from old
+`
+	assert.Equal(t, expected, b.String())
+
+	b.Reset()
+	require.NoError(t, changes.Explain(b, OutputFormatJSON, nil))
+	var e explanation
+	require.NoError(t, json.Unmarshal(b.Bytes(), &e))
+	require.Len(t, e.Update, 1)
+	assert.Equal(t, "old", e.Update[0].AdoptedFrom)
+}
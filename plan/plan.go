@@ -44,24 +44,42 @@ type Plan struct {
 	// List of changes necessary to move towards desired state
 	// Populated after calling Calculate()
 	Changes *Changes
+	// List of changes which must be applied before Changes, e.g. the deletion of
+	// a CNAME record which is replaced by an A record of the same name.
+	// Populated after calling Calculate()
+	PreChanges *Changes
+	// List of records left out of the changes, with the reason why
+	// Populated after calling Calculate()
+	Skipped []*SkippedEndpoint
 	// DomainFilter matches DNS names
 	DomainFilter endpoint.DomainFilterInterface
 	// Property comparator compares custom properties of providers
 	PropertyComparator PropertyComparator
 	// DNS record types that will be considered for management
 	ManagedRecords []string
+	// ConflictResolver decides which desired record acquires a DNS name claimed by several resources.
+	// PerResource is used when not set.
+	ConflictResolver ConflictResolver
+	// OwnerID of the registry. When set, the updates and deletes of records owned by
+	// somebody else, which the registry leaves out, are reported as skipped.
+	OwnerID string
+	// AdoptOwnerIDs are the owners whose records are taken over by OwnerID when they are desired.
+	AdoptOwnerIDs []string
+	// AdoptAnnotated lets the desired records labeled with endpoint.AdoptLabelKey take over the records of any
+	// other owner. It is opt-in, since anybody able to annotate a resource can then take over these records.
+	AdoptAnnotated bool
 }
 
 // Changes holds lists of actions to be executed by dns providers
 type Changes struct {
 	// Records that need to be created
-	Create []*endpoint.Endpoint
+	Create []*endpoint.Endpoint `json:"create,omitempty"`
 	// Records that need to be updated (current data)
-	UpdateOld []*endpoint.Endpoint
+	UpdateOld []*endpoint.Endpoint `json:"updateOld,omitempty"`
 	// Records that need to be updated (desired data)
-	UpdateNew []*endpoint.Endpoint
+	UpdateNew []*endpoint.Endpoint `json:"updateNew,omitempty"`
 	// Records that need to be deleted
-	Delete []*endpoint.Endpoint
+	Delete []*endpoint.Endpoint `json:"delete,omitempty"`
 }
 
 // planKey is a key for a row in `planTable`.
@@ -89,8 +107,11 @@ type planTable struct {
 	resolver ConflictResolver
 }
 
-func newPlanTable() planTable { // TODO: make resolver configurable
-	return planTable{map[planKey]*planTableRow{}, PerResource{}}
+func newPlanTable(resolver ConflictResolver) planTable {
+	if resolver == nil {
+		resolver = PerResource{}
+	}
+	return planTable{map[planKey]*planTableRow{}, resolver}
 }
 
 // planTableRow
@@ -138,34 +159,57 @@ func (c *Changes) HasChanges() bool {
 // state. It then passes those changes to the current policy for further
 // processing. It returns a copy of Plan with the changes populated.
 func (p *Plan) Calculate() *Plan {
-	t := newPlanTable()
+	t := newPlanTable(p.ConflictResolver)
 
 	if p.DomainFilter == nil {
 		p.DomainFilter = endpoint.MatchAllDomainFilters(nil)
 	}
 
-	for _, current := range filterRecordsForPlan(p.Current, p.DomainFilter, p.ManagedRecords) {
+	currentRecords := filterRecordsForPlan(p.Current, p.DomainFilter, p.ManagedRecords)
+	for _, current := range currentRecords {
 		t.addCurrent(current)
 	}
 	for _, desired := range filterRecordsForPlan(p.Desired, p.DomainFilter, p.ManagedRecords) {
 		t.addCandidate(desired)
 	}
+	skipped := skippedRecordsForPlan(p.Desired, p.DomainFilter, p.ManagedRecords)
 
 	changes := &Changes{}
+	var retained []*endpoint.Endpoint
 
 	for _, row := range t.rows {
 		if row.current == nil { // dns name not taken
-			changes.Create = append(changes.Create, t.resolver.ResolveCreate(row.candidates))
+			create := t.resolver.ResolveCreate(row.candidates)
+			changes.Create = append(changes.Create, create)
+			skipped = append(skipped, conflictLosers(create, row.candidates)...)
 		}
 		if row.current != nil && len(row.candidates) == 0 {
+			if p.OwnerID != "" && shouldShare(row.current, nil) {
+				p.withdraw(changes, row.current)
+				continue
+			}
+			if isRetained(row.current) {
+				retained = append(retained, row.current)
+				continue
+			}
 			changes.Delete = append(changes.Delete, row.current)
 		}
 
-		// TODO: allows record type change, which might not be supported by all dns providers
 		if row.current != nil && len(row.candidates) > 0 { // dns name is taken
 			update := t.resolver.ResolveUpdate(row.current, row.candidates)
+			skipped = append(skipped, conflictLosers(update, row.candidates)...)
+			if p.OwnerID != "" && shouldShare(row.current, update) {
+				skipped = append(skipped, p.share(changes, row.current, update)...)
+				continue
+			}
 			// compare "update" to "current" to figure out if actual update is required
-			if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || p.shouldUpdateProviderSpecific(update, row.current) {
+			if p.shouldAdopt(row.current, update) {
+				adopt(p.OwnerID, row.current, update)
+				changes.UpdateNew = append(changes.UpdateNew, update)
+				changes.UpdateOld = append(changes.UpdateOld, row.current)
+				continue
+			}
+			if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || p.shouldUpdateProviderSpecific(update, row.current) || isShared(update) != isShared(row.current) || isRetained(update) != isRetained(row.current) {
 				inheritOwner(row.current, update)
 				changes.UpdateNew = append(changes.UpdateNew, update)
 				changes.UpdateOld = append(changes.UpdateOld, row.current)
@@ -173,9 +217,33 @@ func (p *Plan) Calculate() *Plan {
 			continue
 		}
 	}
+	released, kept := t.releaseRetained(retained)
+	changes.Delete = append(changes.Delete, released...)
+	skipped = append(skipped, kept...)
+
+	planned := changes
 	for _, pol := range p.Policies {
 		changes = pol.Apply(changes)
 	}
+	skipped = append(skipped, heldBackByPolicy(planned, changes)...)
+
+	var foreign []*SkippedEndpoint
+	if p.OwnerID != "" {
+		foreign = foreignChanges(p.OwnerID, changes)
+		skipped = append(skipped, foreign...)
+		// the owner contributes all the targets of the shared records it creates, or stops sharing with others
+		for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+			if _, ok := ep.Labels[endpoint.TargetsLabelKey]; isShared(ep) && !ok {
+				ep.Labels[endpoint.TargetsLabelKey] = strings.Join(unionTargets(ep.Targets, nil), endpoint.TargetsSeparator)
+			}
+		}
+	}
+
+	// A record type change is a deletion and a creation: the deletion goes first when a CNAME is involved.
+	preChanges := &Changes{}
+	var conflicts []*SkippedEndpoint
+	preChanges.Delete, conflicts = separateRecordTypeChanges(currentRecords, changes, skippedEndpoints(foreign))
+	skipped = append(skipped, conflicts...)
 
 	// Handle the migration of the TXT records created before the new format (introduced in v0.12.0)
 	if len(p.Missing) > 0 {
@@ -186,12 +254,93 @@ func (p *Plan) Calculate() *Plan {
 		Current:        p.Current,
 		Desired:        p.Desired,
 		Changes:        changes,
+		PreChanges:     preChanges,
+		Skipped:        skipped,
 		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        p.OwnerID,
+		AdoptOwnerIDs:  p.AdoptOwnerIDs,
+		AdoptAnnotated: p.AdoptAnnotated,
 	}
 
 	return plan
 }
 
+// foreignChanges returns the updates and deletes of records which are not owned by ownerID as skipped records.
+// They are left in the changes, the registry leaves them out when applying the changes. Updates of adopted and
+// shared records are not skipped.
+func foreignChanges(ownerID string, changes *Changes) []*SkippedEndpoint {
+	var skipped []*SkippedEndpoint
+	for i := range changes.UpdateOld {
+		shared := isShared(changes.UpdateOld[i]) && isShared(changes.UpdateNew[i])
+		if changes.UpdateOld[i].Labels[endpoint.OwnerLabelKey] != ownerID && changes.UpdateOld[i].Labels[endpoint.AdoptLabelKey] != ownerID && !shared {
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: changes.UpdateNew[i], Reason: SkipReasonForeignOwner})
+		}
+	}
+	for _, ep := range changes.Delete {
+		if ep.Labels[endpoint.OwnerLabelKey] != ownerID {
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonForeignOwner})
+		}
+	}
+	return skipped
+}
+
+// conflictLosers returns the candidates whose targets are not part of the resolved record as skipped records
+func conflictLosers(resolved *endpoint.Endpoint, candidates []*endpoint.Endpoint) []*SkippedEndpoint {
+	var skipped []*SkippedEndpoint
+	for _, ep := range candidates {
+		if ep == resolved || containsTargets(resolved.Targets, ep.Targets) {
+			continue
+		}
+		skipped = append(skipped, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonConflict})
+	}
+	return skipped
+}
+
+func containsTargets(targets, subset endpoint.Targets) bool {
+	for _, s := range subset {
+		found := false
+		for _, t := range targets {
+			if strings.EqualFold(s, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldAdopt tells whether the current record, owned by another instance, is taken over by the owner of the plan.
+// This is the case when its owner is one of AdoptOwnerIDs, or when the desired record asks for it and AdoptAnnotated
+// is set.
+func (p *Plan) shouldAdopt(current, desired *endpoint.Endpoint) bool {
+	owner := current.Labels[endpoint.OwnerLabelKey]
+	if p.OwnerID == "" || owner == "" || owner == p.OwnerID {
+		return false
+	}
+	if p.AdoptAnnotated && desired.Labels[endpoint.AdoptLabelKey] == "true" {
+		return true
+	}
+	for _, id := range p.AdoptOwnerIDs {
+		if id == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// adopt labels the current record with its new owner so that the registry lets it be updated despite its
+// current owner, and labels the desired record with the new owner so that the ownership is rewritten.
+func adopt(ownerID string, current, desired *endpoint.Endpoint) {
+	current.Labels[endpoint.AdoptLabelKey] = ownerID
+	if desired.Labels == nil {
+		desired.Labels = map[string]string{}
+	}
+	desired.Labels[endpoint.OwnerLabelKey] = ownerID
+}
+
 func inheritOwner(from, to *endpoint.Endpoint) {
 	if to.Labels == nil {
 		to.Labels = map[string]string{}
@@ -249,10 +398,6 @@ func (p *Plan) shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint)
 // filterRecordsForPlan removes records that are not relevant to the planner.
 // Currently this just removes TXT records to prevent them from being
 // deleted erroneously by the planner (only the TXT registry should do this.)
-//
-// Per RFC 1034, CNAME records conflict with all other records - it is the
-// only record with this property. The behavior of the planner may need to be
-// made more sophisticated to codify this.
 func filterRecordsForPlan(records []*endpoint.Endpoint, domainFilter endpoint.DomainFilterInterface, managedRecords []string) []*endpoint.Endpoint {
 	filtered := []*endpoint.Endpoint{}
 
@@ -270,6 +415,22 @@ func filterRecordsForPlan(records []*endpoint.Endpoint, domainFilter endpoint.Do
 	return filtered
 }
 
+// skippedRecordsForPlan returns the records filterRecordsForPlan removes, along with the reason.
+func skippedRecordsForPlan(records []*endpoint.Endpoint, domainFilter endpoint.DomainFilterInterface, managedRecords []string) []*SkippedEndpoint {
+	var skipped []*SkippedEndpoint
+
+	for _, record := range records {
+		switch {
+		case !domainFilter.Match(record.DNSName):
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: record, Reason: SkipReasonDomainFilter})
+		case !IsManagedRecord(record.RecordType, managedRecords):
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: record, Reason: SkipReasonUnmanagedType})
+		}
+	}
+
+	return skipped
+}
+
 // normalizeDNSName converts a DNS name to a canonical form, so that we can use string equality
 // it: removes space, converts to lower case, ensures there is a trailing dot
 func normalizeDNSName(dnsName string) string {
@@ -465,7 +465,7 @@ func (suite *PlanTestSuite) TestIdempotency() {
 func (suite *PlanTestSuite) TestDifferentTypes() {
 	current := []*endpoint.Endpoint{suite.fooV1Cname}
 	desired := []*endpoint.Endpoint{suite.fooV2Cname, suite.fooA5}
-	expectedCreate := []*endpoint.Endpoint{suite.fooA5}
+	expectedCreate := []*endpoint.Endpoint{}
 	expectedUpdateOld := []*endpoint.Endpoint{suite.fooV1Cname}
 	expectedUpdateNew := []*endpoint.Endpoint{suite.fooV2Cname}
 	expectedDelete := []*endpoint.Endpoint{}
@@ -477,11 +477,101 @@ func (suite *PlanTestSuite) TestDifferentTypes() {
 		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
 	}
 
-	changes := p.Calculate().Changes
+	calculated := p.Calculate()
+	changes := calculated.Changes
 	validateEntries(suite.T(), changes.Create, expectedCreate)
 	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
 	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
+	suite.Equal([]*SkippedEndpoint{{Endpoint: suite.fooA5, Reason: SkipReasonRecordTypeConflict}}, calculated.Skipped)
+}
+
+func (suite *PlanTestSuite) TestRecordTypeChangeFromCNAME() {
+	current := []*endpoint.Endpoint{suite.fooV1Cname}
+	desired := []*endpoint.Endpoint{suite.fooA5, suite.fooAAAA}
+	expectedCreate := []*endpoint.Endpoint{suite.fooA5, suite.fooAAAA}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+	}
+
+	calculated := p.Calculate()
+	validateEntries(suite.T(), calculated.Changes.Create, expectedCreate)
+	validateEntries(suite.T(), calculated.Changes.Delete, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), calculated.PreChanges.Delete, []*endpoint.Endpoint{suite.fooV1Cname})
+	suite.Empty(calculated.Skipped)
+}
+
+func (suite *PlanTestSuite) TestRecordTypeChangeToCNAME() {
+	current := []*endpoint.Endpoint{suite.fooA5, suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname, suite.bar192A}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	calculated := p.Calculate()
+	validateEntries(suite.T(), calculated.Changes.Create, []*endpoint.Endpoint{suite.fooV1Cname})
+	validateEntries(suite.T(), calculated.Changes.UpdateNew, []*endpoint.Endpoint{suite.bar192A})
+	validateEntries(suite.T(), calculated.Changes.Delete, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), calculated.PreChanges.Delete, []*endpoint.Endpoint{suite.fooA5})
+}
+
+func (suite *PlanTestSuite) TestRecordTypeConflict() {
+	current := []*endpoint.Endpoint{}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname, suite.fooA5, suite.bar127A}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	calculated := p.Calculate()
+	validateEntries(suite.T(), calculated.Changes.Create, []*endpoint.Endpoint{suite.bar127A})
+	validateEntries(suite.T(), calculated.PreChanges.Delete, []*endpoint.Endpoint{})
+	suite.ElementsMatch([]*SkippedEndpoint{
+		{Endpoint: suite.fooV1Cname, Reason: SkipReasonRecordTypeConflict},
+		{Endpoint: suite.fooA5, Reason: SkipReasonRecordTypeConflict},
+	}, calculated.Skipped)
+}
+
+func (suite *PlanTestSuite) TestRecordTypeConflictWithForeignRecord() {
+	foreignCname := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "other",
+		},
+	}
+	current := []*endpoint.Endpoint{foreignCname}
+	desired := []*endpoint.Endpoint{suite.fooA5}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		OwnerID:        "owner",
+	}
+
+	calculated := p.Calculate()
+	validateEntries(suite.T(), calculated.Changes.Create, []*endpoint.Endpoint{})
+	// the foreign record is kept by the registry, so it is not deleted first
+	validateEntries(suite.T(), calculated.Changes.Delete, []*endpoint.Endpoint{foreignCname})
+	validateEntries(suite.T(), calculated.PreChanges.Delete, []*endpoint.Endpoint{})
+	suite.ElementsMatch([]*SkippedEndpoint{
+		{Endpoint: foreignCname, Reason: SkipReasonForeignOwner},
+		{Endpoint: suite.fooA5, Reason: SkipReasonRecordTypeConflict},
+	}, calculated.Skipped)
 }
 
 func (suite *PlanTestSuite) TestIgnoreTXT() {
@@ -709,6 +799,222 @@ func (suite *PlanTestSuite) TestDualStackRecords() {
 	validateEntries(suite.T(), changes.Create, expectedCreate)
 }
 
+func (suite *PlanTestSuite) TestConflictResolverMergeTargets() {
+	current := []*endpoint.Endpoint{suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.bar127A, suite.bar192A}
+	expectedUpdateOld := []*endpoint.Endpoint{suite.bar127A}
+	expectedUpdateNew := []*endpoint.Endpoint{{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"127.0.0.1", "192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+		},
+	}}
+
+	p := &Plan{
+		Policies:         []Policy{&SyncPolicy{}},
+		Current:          current,
+		Desired:          desired,
+		ManagedRecords:   []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		ConflictResolver: MergeTargets{},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{})
+}
+
+func (suite *PlanTestSuite) TestSkippedRecords() {
+	current := []*endpoint.Endpoint{}
+	desired := []*endpoint.Endpoint{suite.domainFilterExcluded, suite.domainFilterFiltered1, suite.fooAAAA}
+	expectedCreate := []*endpoint.Endpoint{suite.domainFilterFiltered1}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		DomainFilter:   endpoint.NewDomainFilterWithExclusions([]string{"domain.tld", "foo"}, []string{"ex.domain.tld"}),
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	calculated := p.Calculate()
+	validateEntries(suite.T(), calculated.Changes.Create, expectedCreate)
+	suite.Equal([]*SkippedEndpoint{
+		{Endpoint: suite.domainFilterExcluded, Reason: SkipReasonDomainFilter},
+		{Endpoint: suite.fooAAAA, Reason: SkipReasonUnmanagedType},
+	}, calculated.Skipped)
+}
+
+func (suite *PlanTestSuite) TestConflictLosersSkipped() {
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        []*endpoint.Endpoint{},
+		Desired:        []*endpoint.Endpoint{suite.fooV1Cname, suite.fooV2Cname, suite.bar127A, suite.bar192A},
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+	calculated := p.Calculate()
+	validateEntries(suite.T(), calculated.Changes.Create, []*endpoint.Endpoint{suite.fooV1Cname, suite.bar127A})
+	suite.ElementsMatch([]*SkippedEndpoint{
+		{Endpoint: suite.fooV2Cname, Reason: SkipReasonConflict},
+		{Endpoint: suite.bar192A, Reason: SkipReasonConflict},
+	}, calculated.Skipped)
+
+	p.ConflictResolver = MergeTargets{}
+	calculated = p.Calculate()
+	suite.Equal([]*SkippedEndpoint{{Endpoint: suite.fooV2Cname, Reason: SkipReasonConflict}}, calculated.Skipped, "merged targets did not lose")
+}
+
+func (suite *PlanTestSuite) TestOwnerID() {
+	foreignCurrent := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "other",
+		},
+	}
+	foreignDelete := &endpoint.Endpoint{
+		DNSName:    "baz",
+		Targets:    endpoint.Targets{"192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "other",
+		},
+	}
+	ownedDelete := &endpoint.Endpoint{
+		DNSName:    "qux",
+		Targets:    endpoint.Targets{"192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "owner",
+		},
+	}
+	current := []*endpoint.Endpoint{foreignCurrent, foreignDelete, ownedDelete}
+	desired := []*endpoint.Endpoint{suite.bar127A, suite.fooV1Cname}
+	expectedCreate := []*endpoint.Endpoint{suite.fooV1Cname}
+	expectedDelete := []*endpoint.Endpoint{ownedDelete}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		OwnerID:        "owner",
+	}
+
+	calculated := p.Calculate()
+	// the changes of the foreign records are left to the registry
+	validateEntries(suite.T(), calculated.Changes.Create, expectedCreate)
+	validateEntries(suite.T(), calculated.Changes.UpdateNew, []*endpoint.Endpoint{suite.bar127A})
+	validateEntries(suite.T(), calculated.Changes.UpdateOld, []*endpoint.Endpoint{foreignCurrent})
+	validateEntries(suite.T(), calculated.Changes.Delete, []*endpoint.Endpoint{foreignDelete, ownedDelete})
+	suite.NotEqual("owner", suite.fooV1Cname.Labels[endpoint.OwnerLabelKey], "desired records should not be modified")
+
+	applied := calculated.Changes.WithoutSkipped(calculated.Skipped)
+	validateEntries(suite.T(), applied.Create, expectedCreate)
+	validateEntries(suite.T(), applied.UpdateNew, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), applied.UpdateOld, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), applied.Delete, expectedDelete)
+	suite.ElementsMatch([]*SkippedEndpoint{
+		{Endpoint: suite.bar127A, Reason: SkipReasonForeignOwner},
+		{Endpoint: foreignDelete, Reason: SkipReasonForeignOwner},
+	}, calculated.Skipped)
+}
+
+func (suite *PlanTestSuite) TestAdoptOwnerIDs() {
+	adopted := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "old",
+		},
+	}
+	foreign := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"192.168.0.2"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "other",
+		},
+	}
+	notDesired := &endpoint.Endpoint{
+		DNSName:    "baz",
+		Targets:    endpoint.Targets{"192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "old",
+		},
+	}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        []*endpoint.Endpoint{adopted, foreign, notDesired},
+		Desired:        []*endpoint.Endpoint{suite.fooV1Cname, suite.bar127A},
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		OwnerID:        "owner",
+		AdoptOwnerIDs:  []string{"old"},
+	}
+
+	calculated := p.Calculate()
+	applied := calculated.Changes.WithoutSkipped(calculated.Skipped)
+	validateEntries(suite.T(), applied.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), applied.UpdateOld, []*endpoint.Endpoint{adopted})
+	validateEntries(suite.T(), applied.UpdateNew, []*endpoint.Endpoint{suite.fooV1Cname})
+	validateEntries(suite.T(), applied.Delete, []*endpoint.Endpoint{})
+	suite.Equal("owner", suite.fooV1Cname.Labels[endpoint.OwnerLabelKey], "adopted records should be labeled with the new owner")
+	suite.Equal("old", adopted.Labels[endpoint.OwnerLabelKey], "the current record should keep its owner")
+	suite.Equal("owner", adopted.Labels[endpoint.AdoptLabelKey], "the current record should be marked as adopted")
+	suite.ElementsMatch([]*SkippedEndpoint{
+		{Endpoint: suite.bar127A, Reason: SkipReasonForeignOwner},
+		{Endpoint: notDesired, Reason: SkipReasonForeignOwner},
+	}, calculated.Skipped)
+}
+
+func (suite *PlanTestSuite) TestAdoptLabel() {
+	foreign := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"127.0.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "other",
+		},
+	}
+	unowned := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v2"},
+		RecordType: "CNAME",
+		Labels:     map[string]string{},
+	}
+	suite.bar127A.Labels[endpoint.AdoptLabelKey] = "true"
+	suite.fooV1Cname.Labels[endpoint.AdoptLabelKey] = "true"
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        []*endpoint.Endpoint{foreign, unowned},
+		Desired:        []*endpoint.Endpoint{suite.bar127A, suite.fooV1Cname},
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		OwnerID:        "owner",
+	}
+
+	// the adopt label is ignored unless it is enabled
+	calculated := p.Calculate()
+	suite.Empty(calculated.Changes.WithoutSkipped(calculated.Skipped).UpdateOld)
+	suite.Equal("", suite.bar127A.Labels[endpoint.OwnerLabelKey])
+
+	p.AdoptAnnotated = true
+	calculated = p.Calculate()
+	applied := calculated.Changes.WithoutSkipped(calculated.Skipped)
+	validateEntries(suite.T(), applied.UpdateOld, []*endpoint.Endpoint{foreign})
+	validateEntries(suite.T(), applied.UpdateNew, []*endpoint.Endpoint{suite.bar127A})
+	suite.Equal("owner", suite.bar127A.Labels[endpoint.OwnerLabelKey])
+	// records without owner are not adopted, they were not created by external-dns
+	suite.Equal([]*SkippedEndpoint{{Endpoint: suite.fooV1Cname, Reason: SkipReasonForeignOwner}}, calculated.Skipped)
+}
+
 func TestPlan(t *testing.T) {
 	suite.Run(t, new(PlanTestSuite))
 }
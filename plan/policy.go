@@ -16,6 +16,8 @@ limitations under the License.
 
 package plan
 
+import "sigs.k8s.io/external-dns/endpoint"
+
 // Policy allows to apply different rules to a set of changes.
 type Policy interface {
 	Apply(changes *Changes) *Changes
@@ -57,3 +59,35 @@ func (p *CreateOnlyPolicy) Apply(changes *Changes) *Changes {
 		Create: changes.Create,
 	}
 }
+
+// heldBackByPolicy returns the records of the planned changes which were stripped out by the policies
+func heldBackByPolicy(planned, applied *Changes) []*SkippedEndpoint {
+	kept := map[*endpoint.Endpoint]bool{}
+	for _, ep := range applied.Create {
+		kept[ep] = true
+	}
+	for _, ep := range applied.UpdateNew {
+		kept[ep] = true
+	}
+	for _, ep := range applied.Delete {
+		kept[ep] = true
+	}
+
+	var skipped []*SkippedEndpoint
+	for _, ep := range planned.Create {
+		if !kept[ep] {
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonPolicy})
+		}
+	}
+	for i, ep := range planned.UpdateNew {
+		if !kept[ep] && i < len(planned.UpdateOld) {
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonPolicy, Current: planned.UpdateOld[i]})
+		}
+	}
+	for _, ep := range planned.Delete {
+		if !kept[ep] {
+			skipped = append(skipped, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonPolicy, Current: ep})
+		}
+	}
+	return skipped
+}
@@ -70,6 +70,41 @@ func TestApply(t *testing.T) {
 	}
 }
 
+// TestPoliciesReportHeldBackRecords tests that the records held back by a policy are reported as skipped.
+func TestPoliciesReportHeldBackRecords(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		{DNSName: "foo", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+		{DNSName: "bar", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+	}
+	fooV2 := &endpoint.Endpoint{DNSName: "foo", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"2.2.2.2"}}
+	baz := &endpoint.Endpoint{DNSName: "baz", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}}
+
+	for _, tc := range []struct {
+		policy   Policy
+		heldBack []*endpoint.Endpoint
+	}{
+		{&SyncPolicy{}, nil},
+		{&UpsertOnlyPolicy{}, []*endpoint.Endpoint{current[1]}},
+		{&CreateOnlyPolicy{}, []*endpoint.Endpoint{fooV2, current[1]}},
+	} {
+		p := &Plan{
+			Policies:       []Policy{tc.policy},
+			Current:        current,
+			Desired:        []*endpoint.Endpoint{fooV2, baz},
+			ManagedRecords: []string{endpoint.RecordTypeA},
+		}
+		var heldBack []*endpoint.Endpoint
+		for _, s := range p.Calculate().Skipped {
+			if s.Reason == SkipReasonPolicy {
+				heldBack = append(heldBack, s.Endpoint)
+			}
+		}
+		if !reflect.DeepEqual(heldBack, tc.heldBack) {
+			t.Errorf("%T: expected held back records %v, got %v", tc.policy, tc.heldBack, heldBack)
+		}
+	}
+}
+
 // TestPolicies tests that policies are correctly registered.
 func TestPolicies(t *testing.T) {
 	validatePolicy(t, Policies["sync"], &SyncPolicy{})
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// isRetained tells whether the record is kept when it is not desired anymore
+func isRetained(ep *endpoint.Endpoint) bool {
+	return ep.Labels[endpoint.RetainLabelKey] == "true"
+}
+
+// releaseRetained splits the retained records which are not desired anymore: the ones whose name is desired again
+// with a record type they cannot coexist with, i.e. a CNAME record or any record next to a retained CNAME record,
+// are released and deleted so that the new records replace them, the other ones are kept and returned as skipped.
+func (t planTable) releaseRetained(retained []*endpoint.Endpoint) (released []*endpoint.Endpoint, kept []*SkippedEndpoint) {
+	if len(retained) == 0 {
+		return nil, nil
+	}
+	desiredTypes := map[planKey]map[string]bool{}
+	for key, row := range t.rows {
+		if len(row.candidates) == 0 {
+			continue
+		}
+		name := planKey{dnsName: key.dnsName, setIdentifier: key.setIdentifier}
+		if desiredTypes[name] == nil {
+			desiredTypes[name] = map[string]bool{}
+		}
+		desiredTypes[name][key.recordType] = true
+	}
+	for _, ep := range retained {
+		types := desiredTypes[planKey{dnsName: normalizeDNSName(ep.DNSName), setIdentifier: ep.SetIdentifier}]
+		if len(types) > 0 && (types[endpoint.RecordTypeCNAME] || ep.RecordType == endpoint.RecordTypeCNAME) {
+			released = append(released, ep)
+			continue
+		}
+		kept = append(kept, &SkippedEndpoint{Endpoint: ep, Reason: SkipReasonRetained})
+	}
+	return released, kept
+}
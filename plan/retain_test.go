@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func retainedEndpoint(dnsName, recordType string, targets ...string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(dnsName, recordType, targets...)
+	ep.Labels[endpoint.OwnerLabelKey] = "owner"
+	ep.Labels[endpoint.RetainLabelKey] = "true"
+	return ep
+}
+
+func TestRetainedRecords(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		// not desired anymore
+		retainedEndpoint("retained.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		// desired again with other targets, without being retained anymore
+		retainedEndpoint("updated.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		// desired again with the same targets, without being retained anymore
+		retainedEndpoint("unpinned.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		// replaced by a CNAME record
+		retainedEndpoint("replaced.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		// next to a desired record of another type
+		retainedEndpoint("dualstack.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		// not retained
+		newExplainTestEndpoint("deleted.example.org", endpoint.RecordTypeA, "1.1.1.1", "", "owner"),
+	}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("updated.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+		endpoint.NewEndpoint("unpinned.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("replaced.example.org", endpoint.RecordTypeCNAME, "lb.example.net"),
+		endpoint.NewEndpoint("dualstack.example.org", endpoint.RecordTypeAAAA, "2001:db8::1"),
+	}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        "owner",
+	}
+	calculated := p.Calculate()
+
+	assert.ElementsMatch(t, []string{"deleted.example.org"}, dnsNames(calculated.Changes.Delete))
+	assert.ElementsMatch(t, []string{"replaced.example.org"}, dnsNames(calculated.PreChanges.Delete))
+	assert.ElementsMatch(t, []string{"replaced.example.org", "dualstack.example.org"}, dnsNames(calculated.Changes.Create))
+	assert.ElementsMatch(t, []string{"updated.example.org", "unpinned.example.org"}, dnsNames(calculated.Changes.UpdateNew))
+	for _, ep := range calculated.Changes.UpdateNew {
+		assert.False(t, isRetained(ep))
+	}
+
+	var kept []string
+	for _, s := range calculated.Skipped {
+		if s.Reason == SkipReasonRetained {
+			kept = append(kept, s.Endpoint.DNSName)
+		}
+	}
+	assert.ElementsMatch(t, []string{"retained.example.org", "dualstack.example.org"}, kept)
+}
+
+func TestRetainedRecordsPinning(t *testing.T) {
+	current := []*endpoint.Endpoint{newExplainTestEndpoint("foo.example.org", endpoint.RecordTypeA, "1.1.1.1", "", "owner")}
+	desired := []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.1.1.1")}
+	desired[0].Labels[endpoint.RetainLabelKey] = "true"
+
+	// the record is updated to store its pinning in the registry, even though its targets do not change
+	calculated := (&Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        "owner",
+	}).Calculate()
+	require.Len(t, calculated.Changes.UpdateNew, 1)
+	assert.True(t, isRetained(calculated.Changes.UpdateNew[0]))
+}
+
+func dnsNames(endpoints []*endpoint.Endpoint) []string {
+	names := []string{}
+	for _, ep := range endpoints {
+		names = append(names, ep.DNSName)
+	}
+	return names
+}
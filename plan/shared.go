@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// isShared tells whether the record is shared by several owners
+func isShared(ep *endpoint.Endpoint) bool {
+	return ep.Labels[endpoint.SharedLabelKey] == "true"
+}
+
+// sharedTargets returns the targets the other owners contribute to the shared record
+func sharedTargets(ep *endpoint.Endpoint) endpoint.Targets {
+	return splitTargets(ep.Labels[endpoint.SharedTargetsLabelKey])
+}
+
+func splitTargets(value string) endpoint.Targets {
+	targets := endpoint.Targets{}
+	for _, t := range strings.Split(value, endpoint.TargetsSeparator) {
+		if t != "" {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// unionTargets returns the sorted targets of both lists, without duplicates
+func unionTargets(a, b endpoint.Targets) endpoint.Targets {
+	seen := map[string]bool{}
+	union := endpoint.Targets{}
+	for _, t := range append(append(endpoint.Targets{}, a...), b...) {
+		if !seen[strings.ToLower(t)] {
+			seen[strings.ToLower(t)] = true
+			union = append(union, t)
+		}
+	}
+	sort.Stable(union)
+	return union
+}
+
+// shouldShare tells whether the current record is handled as a shared record: either the desired record is shared
+// as well, or other owners contribute to it, in which case it is not taken over by a desired record which is not shared.
+func shouldShare(current, desired *endpoint.Endpoint) bool {
+	if !isShared(current) {
+		return false
+	}
+	return desired == nil || isShared(desired) || len(sharedTargets(current)) > 0
+}
+
+// share computes the change of a shared record: its targets are the union of the targets of the desired record,
+// contributed by the owner of the plan, and of the targets contributed by the other owners.
+func (p *Plan) share(changes *Changes, current, desired *endpoint.Endpoint) []*SkippedEndpoint {
+	if !isShared(desired) {
+		return []*SkippedEndpoint{{Endpoint: desired, Reason: SkipReasonForeignOwner}}
+	}
+
+	update := desired.DeepCopy()
+	if update.Labels == nil {
+		update.Labels = map[string]string{}
+	}
+	update.Labels[endpoint.OwnerLabelKey] = p.OwnerID
+	update.Labels[endpoint.TargetsLabelKey] = strings.Join(unionTargets(desired.Targets, nil), endpoint.TargetsSeparator)
+	update.Targets = unionTargets(desired.Targets, sharedTargets(current))
+
+	joined := current.Labels[endpoint.OwnerLabelKey] != p.OwnerID
+	contributionChanged := current.Labels[endpoint.TargetsLabelKey] != update.Labels[endpoint.TargetsLabelKey]
+	if joined || contributionChanged || shouldUpdateTTL(update, current) || targetChanged(update, current) || p.shouldUpdateProviderSpecific(update, current) {
+		changes.UpdateNew = append(changes.UpdateNew, update)
+		changes.UpdateOld = append(changes.UpdateOld, current)
+	}
+	return nil
+}
+
+// withdraw computes the change of a shared record which is not desired anymore: the targets contributed by the
+// owner of the plan are removed from the record, which is only deleted when no other owner contributes to it.
+func (p *Plan) withdraw(changes *Changes, current *endpoint.Endpoint) {
+	if current.Labels[endpoint.OwnerLabelKey] != p.OwnerID {
+		// the owner of the plan does not contribute to the record
+		return
+	}
+	others := sharedTargets(current)
+	if len(others) == 0 {
+		changes.Delete = append(changes.Delete, current)
+		return
+	}
+
+	update := current.DeepCopy()
+	update.Targets = others
+	update.Labels[endpoint.TargetsLabelKey] = ""
+	delete(update.Labels, endpoint.SharedTargetsLabelKey)
+	changes.UpdateNew = append(changes.UpdateNew, update)
+	changes.UpdateOld = append(changes.UpdateOld, current)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func sharedEndpoint(dnsName, owner, own, others string, targets ...string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, targets...)
+	ep.Labels[endpoint.SharedLabelKey] = "true"
+	if owner != "" {
+		ep.Labels[endpoint.OwnerLabelKey] = owner
+	}
+	if own != "" {
+		ep.Labels[endpoint.TargetsLabelKey] = own
+	}
+	ep.Labels[endpoint.SharedTargetsLabelKey] = others
+	return ep
+}
+
+func desiredSharedEndpoint(dnsName string, targets ...string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, targets...)
+	ep.Labels[endpoint.SharedLabelKey] = "true"
+	return ep
+}
+
+func TestSharedRecords(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		// joined by the owner
+		sharedEndpoint("join.example.org", "other", "", "1.1.1.1", "1.1.1.1"),
+		// already contributed to by the owner
+		sharedEndpoint("same.example.org", "owner", "2.2.2.2", "1.1.1.1", "1.1.1.1", "2.2.2.2"),
+		// the contribution of the owner changes, but not the targets of the record
+		sharedEndpoint("contribution.example.org", "owner", "2.2.2.2", "1.1.1.1;2.2.2.2", "1.1.1.1", "2.2.2.2"),
+		// not desired anymore
+		sharedEndpoint("withdraw.example.org", "owner", "2.2.2.2", "1.1.1.1", "1.1.1.1", "2.2.2.2"),
+		sharedEndpoint("last.example.org", "owner", "2.2.2.2", "", "2.2.2.2"),
+		sharedEndpoint("foreign.example.org", "other", "", "1.1.1.1", "1.1.1.1"),
+		// desired without being shared
+		sharedEndpoint("conflict.example.org", "other", "", "1.1.1.1", "1.1.1.1"),
+	}
+	desired := []*endpoint.Endpoint{
+		desiredSharedEndpoint("join.example.org", "2.2.2.2"),
+		desiredSharedEndpoint("same.example.org", "2.2.2.2"),
+		desiredSharedEndpoint("contribution.example.org", "1.1.1.1", "2.2.2.2"),
+		desiredSharedEndpoint("new.example.org", "2.2.2.2", "3.3.3.3"),
+		endpoint.NewEndpoint("conflict.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+	}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        "owner",
+	}
+	calculated := p.Calculate()
+
+	require.Len(t, calculated.Changes.Create, 1)
+	assert.Equal(t, "2.2.2.2;3.3.3.3", calculated.Changes.Create[0].Labels[endpoint.TargetsLabelKey])
+
+	updates := map[string]*endpoint.Endpoint{}
+	for i, ep := range calculated.Changes.UpdateNew {
+		assert.Equal(t, ep.DNSName, calculated.Changes.UpdateOld[i].DNSName)
+		updates[ep.DNSName] = ep
+	}
+	require.Len(t, updates, 3)
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "2.2.2.2"}, updates["join.example.org"].Targets)
+	assert.Equal(t, "owner", updates["join.example.org"].Labels[endpoint.OwnerLabelKey])
+	assert.Equal(t, "2.2.2.2", updates["join.example.org"].Labels[endpoint.TargetsLabelKey])
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "2.2.2.2"}, updates["contribution.example.org"].Targets)
+	assert.Equal(t, "1.1.1.1;2.2.2.2", updates["contribution.example.org"].Labels[endpoint.TargetsLabelKey])
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, updates["withdraw.example.org"].Targets)
+	assert.Equal(t, "", updates["withdraw.example.org"].Labels[endpoint.TargetsLabelKey])
+	// the desired records are left untouched
+	assert.Equal(t, endpoint.Targets{"2.2.2.2"}, desired[0].Targets)
+
+	require.Len(t, calculated.Changes.Delete, 1)
+	assert.Equal(t, "last.example.org", calculated.Changes.Delete[0].DNSName)
+	assert.Equal(t, []*SkippedEndpoint{{Endpoint: desired[4], Reason: SkipReasonForeignOwner}}, calculated.Skipped)
+}
+
+func TestSharingChanges(t *testing.T) {
+	owned := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.1.1.1")
+	owned.Labels[endpoint.OwnerLabelKey] = "owner"
+	sharedOwned := sharedEndpoint("bar.example.org", "owner", "1.1.1.1", "", "1.1.1.1")
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        []*endpoint.Endpoint{owned, sharedOwned},
+		Desired:        []*endpoint.Endpoint{desiredSharedEndpoint("foo.example.org", "1.1.1.1"), endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.1.1.1")},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        "owner",
+	}
+	calculated := p.Calculate()
+
+	// the records of the owner start or stop being shared when no other owner contributes to them
+	require.Len(t, calculated.Changes.UpdateNew, 2)
+	for _, ep := range calculated.Changes.UpdateNew {
+		switch ep.DNSName {
+		case "foo.example.org":
+			assert.Equal(t, "true", ep.Labels[endpoint.SharedLabelKey])
+			assert.Equal(t, "1.1.1.1", ep.Labels[endpoint.TargetsLabelKey])
+		case "bar.example.org":
+			assert.NotContains(t, ep.Labels, endpoint.SharedLabelKey)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -175,6 +176,8 @@ type Route53API interface {
 type Route53Change struct {
 	route53.Change
 	OwnedRecord string
+	// sourceEndpoint is the record the change was created from, used to report failures
+	sourceEndpoint *endpoint.Endpoint
 }
 
 type Route53Changes []*Route53Change
@@ -335,10 +338,10 @@ func (p *AWSProvider) Zones(ctx context.Context) (map[string]*route53.HostedZone
 
 	err := p.client.ListHostedZonesPagesWithContext(ctx, &route53.ListHostedZonesInput{}, f)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to list hosted zones")
+		return nil, errors.Wrap(softErrorIfThrottled(err), "failed to list hosted zones")
 	}
 	if tagErr != nil {
-		return nil, errors.Wrap(tagErr, "failed to list zones tags")
+		return nil, errors.Wrap(softErrorIfThrottled(tagErr), "failed to list zones tags")
 	}
 
 	for _, zone := range zones {
@@ -369,6 +372,30 @@ func (p *AWSProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoi
 	return p.records(ctx, zones)
 }
 
+// ListZones returns the hosted zones, which are passed back to ZoneRecords
+func (p *AWSProvider) ListZones(ctx context.Context) ([]provider.Zone, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "zones retrieval failed")
+	}
+
+	list := make([]provider.Zone, 0, len(zones))
+	for id, zone := range zones {
+		list = append(list, provider.Zone{ID: id, Name: strings.TrimSuffix(aws.StringValue(zone.Name), "."), Data: zone})
+	}
+	return list, nil
+}
+
+// ZoneRecords returns the records of a hosted zone returned by ListZones
+func (p *AWSProvider) ZoneRecords(ctx context.Context, zone provider.Zone) ([]*endpoint.Endpoint, error) {
+	hostedZone, ok := zone.Data.(*route53.HostedZone)
+	if !ok {
+		return nil, fmt.Errorf("hosted zone %s not found", zone.ID)
+	}
+
+	return p.records(ctx, map[string]*route53.HostedZone{zone.ID: hostedZone})
+}
+
 func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.HostedZone) ([]*endpoint.Endpoint, error) {
 	endpoints := make([]*endpoint.Endpoint, 0)
 	f := func(resp *route53.ListResourceRecordSetsOutput, lastPage bool) (shouldContinue bool) {
@@ -451,7 +478,7 @@ func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.Hos
 		}
 
 		if err := p.client.ListResourceRecordSetsPagesWithContext(ctx, params, f); err != nil {
-			return nil, errors.Wrapf(err, "failed to list resource records sets for zone %s", *z.Id)
+			return nil, errors.Wrapf(softErrorIfThrottled(err), "failed to list resource records sets for zone %s", *z.Id)
 		}
 	}
 
@@ -560,6 +587,9 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes Route53Changes,
 	}
 
 	var failedZones []string
+	var failedRecords []*provider.RecordError
+	// batchErr is the last failure which could not be narrowed down to a record, e.g. a throttled request
+	var batchErr error
 	for z, cs := range changesByZone {
 		var failedUpdate bool
 
@@ -592,7 +622,11 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes Route53Changes,
 
 					changesByOwnership := groupChangesByNameAndOwnershipRelation(b)
 
-					if len(changesByOwnership) > 1 {
+					if isThrottled(err) {
+						// the whole batch is retried by the controller, submitting the changes one-by-one would be throttled as well
+						failedUpdate = true
+						batchErr = err
+					} else if len(changesByOwnership) > 1 {
 						log.Debug("Trying to submit change sets one-by-one instead")
 
 						for _, changes := range changesByOwnership {
@@ -606,12 +640,19 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes Route53Changes,
 								failedUpdate = true
 								log.Errorf("Failed submitting change (error: %v), it will be retried in a separate change batch in the next iteration", err)
 								p.failedChangesQueue[z] = append(p.failedChangesQueue[z], changes...)
+								if isThrottled(err) {
+									batchErr = err
+								} else {
+									failedRecords = append(failedRecords, changesRecordErrors(changes, err)...)
+								}
 							} else {
 								successfulChanges = successfulChanges + len(changes)
 							}
 						}
 					} else {
+						// the batch only holds the changes of a single record, so its failure is isolated already
 						failedUpdate = true
+						failedRecords = append(failedRecords, changesRecordErrors(b, err)...)
 					}
 				} else {
 					successfulChanges = len(b)
@@ -634,12 +675,49 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes Route53Changes,
 	}
 
 	if len(failedZones) > 0 {
-		return errors.Errorf("failed to submit all changes for the following zones: %v", failedZones)
+		log.Errorf("Failed to submit all changes for the following zones: %v", failedZones)
+		if batchErr != nil {
+			// only the failures of isolated records are reported per record, the controller retries the others with backoff
+			return errors.Wrap(softErrorIfThrottled(batchErr), "failed to submit changes")
+		}
+		return provider.NewPartialFailureError(failedRecords...)
 	}
 
 	return nil
 }
 
+// softErrorIfThrottled marks the errors of throttled or otherwise retryable requests as soft errors,
+// so that the controller retries them instead of exiting
+func softErrorIfThrottled(err error) error {
+	if isThrottled(err) {
+		return provider.NewSoftError(err)
+	}
+	return err
+}
+
+// isThrottled tells whether the request failed because it was throttled or is otherwise retryable. Only the errors
+// of the API are checked, the SDK considers any other error retryable.
+func isThrottled(err error) bool {
+	if _, ok := err.(awserr.Error); !ok {
+		return false
+	}
+	return request.IsErrorThrottle(err) || request.IsErrorRetryable(err)
+}
+
+// changesRecordErrors returns the failure of the records the given changes were created from
+func changesRecordErrors(changes Route53Changes, err error) []*provider.RecordError {
+	var failures []*provider.RecordError
+	seen := map[*endpoint.Endpoint]bool{}
+	for _, c := range changes {
+		if c.sourceEndpoint == nil || seen[c.sourceEndpoint] {
+			continue
+		}
+		seen[c.sourceEndpoint] = true
+		failures = append(failures, &provider.RecordError{Endpoint: c.sourceEndpoint, Err: err})
+	}
+	return failures
+}
+
 // newChanges returns a collection of Changes based on the given records and action.
 func (p *AWSProvider) newChanges(action string, endpoints []*endpoint.Endpoint) Route53Changes {
 	changes := make(Route53Changes, 0, len(endpoints))
@@ -650,7 +728,7 @@ func (p *AWSProvider) newChanges(action string, endpoints []*endpoint.Endpoint)
 		if dualstack {
 			// make a copy of change, modify RRS type to AAAA, then add new change
 			rrs := *change.ResourceRecordSet
-			change2 := &Route53Change{Change: route53.Change{Action: change.Action, ResourceRecordSet: &rrs}}
+			change2 := &Route53Change{Change: route53.Change{Action: change.Action, ResourceRecordSet: &rrs}, sourceEndpoint: change.sourceEndpoint}
 			change2.ResourceRecordSet.Type = aws.String(route53.RRTypeAaaa)
 			changes = append(changes, change2)
 		}
@@ -701,6 +779,7 @@ func (p *AWSProvider) newChange(action string, ep *endpoint.Endpoint) (*Route53C
 				Name: aws.String(ep.DNSName),
 			},
 		},
+		sourceEndpoint: ep,
 	}
 	dualstack := false
 	if targetHostedZone := isAWSAlias(ep); targetHostedZone != "" {
@@ -829,7 +908,7 @@ func (p *AWSProvider) tagsForZone(ctx context.Context, zoneID string) (map[strin
 		ResourceId:   aws.String(zoneID),
 	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to list tags for zone %s", zoneID)
+		return nil, errors.Wrapf(softErrorIfThrottled(err), "failed to list tags for zone %s", zoneID)
 	}
 	tagMap := map[string]string{}
 	for _, tag := range response.ResourceTagSet.Tags {
@@ -18,6 +18,7 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sort"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/stretchr/testify/assert"
@@ -1480,7 +1482,9 @@ func TestAWSsubmitChangesRetryOnError(t *testing.T) {
 	clientStub.MockMethod("ChangeResourceRecordSets", input2).Return(nil, fmt.Errorf("Mock route53 failure"))
 
 	// "success" should have been created, verify that we still get an error because "fail" failed
-	require.Error(t, provider.submitChanges(ctx, cs1, zones))
+	err = provider.submitChanges(ctx, cs1, zones)
+	require.Error(t, err)
+	assert.ElementsMatch(t, []*endpoint.Endpoint{ep2, ep2txt}, failedEndpoints(t, err))
 
 	// assert that "success" was successfully created and "fail" and its TXT record were not
 	records, err := provider.Records(ctx)
@@ -1502,6 +1506,38 @@ func TestAWSsubmitChangesRetryOnError(t *testing.T) {
 	require.True(t, containsRecordWithDNSName(records, "fail__edns_housekeeping.zone-1.ext-dns-test-2.teapot.zalan.do"))
 }
 
+func TestAWSsubmitChangesThrottled(t *testing.T) {
+	p, clientStub := newAWSProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), provider.NewZoneIDFilter([]string{}), provider.NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, nil)
+
+	ctx := context.Background()
+	zones, err := p.Zones(ctx)
+	require.NoError(t, err)
+
+	ep1 := endpoint.NewEndpointWithTTL("throttled1.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.0.0.1")
+	ep2 := endpoint.NewEndpointWithTTL("throttled2.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeA, endpoint.TTL(recordTTL), "1.0.0.2")
+
+	cs := p.newChanges(route53.ChangeActionCreate, []*endpoint.Endpoint{ep1, ep2})
+	input := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String("/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: cs.Route53Changes(),
+		},
+	}
+	clientStub.MockMethod("ChangeResourceRecordSets", input).Return(nil, awserr.New("Throttling", "Rate exceeded", nil))
+
+	// a throttled batch is not reported per record, the controller retries it with backoff
+	err = p.submitChanges(ctx, cs, zones)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, provider.ErrSoft)
+	var partial *provider.PartialFailureError
+	assert.False(t, errors.As(err, &partial))
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.False(t, containsRecordWithDNSName(records, "throttled1.zone-1.ext-dns-test-2.teapot.zalan.do"))
+	assert.False(t, containsRecordWithDNSName(records, "throttled2.zone-1.ext-dns-test-2.teapot.zalan.do"))
+}
+
 func TestAWSBatchChangeSet(t *testing.T) {
 	var cs Route53Changes
 
@@ -1624,6 +1660,26 @@ func validateAWSZone(t *testing.T, zone *route53.HostedZone, expected *route53.H
 	assert.Equal(t, aws.StringValue(expected.Name), aws.StringValue(zone.Name))
 }
 
+func TestSoftErrorIfThrottled(t *testing.T) {
+	throttled := awserr.New("Throttling", "Rate exceeded", nil)
+	assert.ErrorIs(t, softErrorIfThrottled(throttled), provider.ErrSoft)
+	assert.ErrorIs(t, softErrorIfThrottled(throttled), throttled)
+
+	denied := awserr.New("AccessDenied", "User is not authorized", nil)
+	assert.NotErrorIs(t, softErrorIfThrottled(denied), provider.ErrSoft)
+}
+
+// failedEndpoints returns the records reported as failed by a partial failure error
+func failedEndpoints(t *testing.T, err error) []*endpoint.Endpoint {
+	var partial *provider.PartialFailureError
+	require.ErrorAs(t, err, &partial)
+	var endpoints []*endpoint.Endpoint
+	for _, f := range partial.Failures {
+		endpoints = append(endpoints, f.Endpoint)
+	}
+	return endpoints
+}
+
 func validateAWSChangeRecords(t *testing.T, records Route53Changes, expected Route53Changes) {
 	require.Len(t, records, len(expected))
 
@@ -0,0 +1,291 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var (
+	cacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "provider",
+			Name:      "cache_hits_total",
+			Help:      "Number of times the records of a zone were served from the cache.",
+		},
+	)
+	cacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "provider",
+			Name:      "cache_misses_total",
+			Help:      "Number of times the records of a zone were listed from the DNS provider.",
+		},
+	)
+	cacheAge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "provider",
+			Name:      "cache_age_seconds",
+			Help:      "Age of the oldest records served from the cache by the last listing.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits)
+	prometheus.MustRegister(cacheMisses)
+	prometheus.MustRegister(cacheAge)
+}
+
+// Zone is a zone of a ZoneProvider
+type Zone struct {
+	ID   string
+	Name string
+	// Data is the zone as listed by the DNS provider, which is passed back to ZoneRecords
+	Data interface{}
+}
+
+// ZoneProvider is implemented by the providers able to list the records of their zones one at a time,
+// so that the CachedProvider only lists the zones whose records are not cached.
+type ZoneProvider interface {
+	// ListZones returns the zones of the provider
+	ListZones(ctx context.Context) ([]Zone, error)
+	// ZoneRecords returns the records of a zone returned by ListZones
+	ZoneRecords(ctx context.Context, zone Zone) ([]*endpoint.Endpoint, error)
+}
+
+// zoneCache holds the cached records of a zone. Its generation changes every time its records change, so that
+// records listed from the DNS provider while they change are not cached.
+type zoneCache struct {
+	records    []*endpoint.Endpoint
+	refreshed  time.Time
+	valid      bool
+	generation uint64
+}
+
+// CachedProvider caches the records of a provider, zone by zone when it implements ZoneProvider and as a whole
+// otherwise, for RefreshInterval. The cached records are patched with the changes applied successfully, while
+// the zones touched by failed changes are listed again on the next call to Records.
+type CachedProvider struct {
+	Provider
+	RefreshInterval time.Duration
+
+	mutex sync.Mutex
+	zones map[string]*zoneCache
+	// zoneList are the zones of a ZoneProvider, listed along with the expired records, and zoneNames their names by ID
+	zoneList   []Zone
+	zoneListed time.Time
+	zoneNames  ZoneIDName
+}
+
+// NewCachedProvider returns a CachedProvider caching the records of the provider for the given interval
+func NewCachedProvider(provider Provider, refreshInterval time.Duration) *CachedProvider {
+	return &CachedProvider{
+		Provider:        provider,
+		RefreshInterval: refreshInterval,
+		zones:           map[string]*zoneCache{},
+	}
+}
+
+// Records returns the records of the provider, which are only listed for the zones whose cache expired or was invalidated
+func (c *CachedProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	zones := []Zone{{}}
+	if zp, ok := c.Provider.(ZoneProvider); ok {
+		var err error
+		if zones, err = c.listZones(ctx, zp); err != nil {
+			return nil, err
+		}
+	}
+
+	var age time.Duration
+	records := []*endpoint.Endpoint{}
+	for _, zone := range zones {
+		zoneRecords, zoneAge, err := c.zoneRecords(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		if zoneAge > age {
+			age = zoneAge
+		}
+		// the records are copied, so that the labels set by the registries are not cached
+		for _, ep := range zoneRecords {
+			records = append(records, ep.DeepCopy())
+		}
+	}
+	cacheAge.Set(age.Seconds())
+	return records, nil
+}
+
+// listZones returns the zones of the provider, which are only listed once per RefreshInterval
+func (c *CachedProvider) listZones(ctx context.Context, zp ZoneProvider) ([]Zone, error) {
+	c.mutex.Lock()
+	if c.zoneList != nil && time.Since(c.zoneListed) < c.RefreshInterval {
+		zones := c.zoneList
+		c.mutex.Unlock()
+		return zones, nil
+	}
+	c.mutex.Unlock()
+
+	zones, err := zp.ListZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.zoneList = zones
+	c.zoneListed = time.Now()
+	c.zoneNames = ZoneIDName{}
+	for _, zone := range zones {
+		c.zoneNames.Add(zone.ID, zone.Name)
+	}
+	for zoneID := range c.zones {
+		if _, ok := c.zoneNames[zoneID]; !ok {
+			delete(c.zones, zoneID)
+		}
+	}
+	return zones, nil
+}
+
+// zoneRecords returns the records of the zone along with their age, listing them from the provider when needed
+func (c *CachedProvider) zoneRecords(ctx context.Context, z Zone) ([]*endpoint.Endpoint, time.Duration, error) {
+	c.mutex.Lock()
+	zone, ok := c.zones[z.ID]
+	if !ok {
+		zone = &zoneCache{}
+		c.zones[z.ID] = zone
+	}
+	if zone.valid && time.Since(zone.refreshed) < c.RefreshInterval {
+		records, age := zone.records, time.Since(zone.refreshed)
+		c.mutex.Unlock()
+		log.Debugf("Using cached records of zone %q", z.ID)
+		cacheHits.Inc()
+		return records, age, nil
+	}
+	generation := zone.generation
+	c.mutex.Unlock()
+
+	cacheMisses.Inc()
+	var records []*endpoint.Endpoint
+	var err error
+	if zp, ok := c.Provider.(ZoneProvider); ok {
+		records, err = zp.ZoneRecords(ctx, z)
+	} else {
+		records, err = c.Provider.Records(ctx)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if zone.generation == generation {
+		zone.records = records
+		zone.refreshed = time.Now()
+		zone.valid = true
+		zone.generation++
+	}
+	return records, 0, nil
+}
+
+// ApplyChanges applies the changes with the provider and updates the cached records of the zones they touch:
+// the changes applied successfully are patched in, and the zones of the failed changes are invalidated.
+func (c *CachedProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	// the records passed in the context might come from the cache, so the provider must list them itself
+	ctx = context.WithValue(ctx, RecordsContextKey, nil)
+	err := c.Provider.ApplyChanges(ctx, changes)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	failed := map[string]bool{}
+	var partial *PartialFailureError
+	switch {
+	case errors.As(err, &partial):
+		for _, f := range partial.Failures {
+			failed[c.zoneID(f.Endpoint)] = true
+		}
+	case err != nil:
+		// the changes applied before the failure are not known
+		for _, ep := range allChanges(changes) {
+			failed[c.zoneID(ep)] = true
+		}
+	}
+	for zoneID := range failed {
+		if zone, ok := c.zones[zoneID]; ok {
+			log.Debugf("Invalidating the cached records of zone %q", zoneID)
+			zone.valid = false
+			zone.generation++
+		}
+	}
+
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+		if zone, ok := c.zones[c.zoneID(ep)]; ok && !failed[c.zoneID(ep)] {
+			zone.remove(ep)
+		}
+	}
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if zone, ok := c.zones[c.zoneID(ep)]; ok && !failed[c.zoneID(ep)] {
+			zone.records = append(zone.records, ep.DeepCopy())
+			zone.generation++
+		}
+	}
+	return err
+}
+
+// zoneID returns the ID of the zone of the record, which is empty when the provider is not a ZoneProvider
+func (c *CachedProvider) zoneID(ep *endpoint.Endpoint) string {
+	if c.zoneNames == nil {
+		return ""
+	}
+	zoneID, _ := c.zoneNames.FindZone(strings.TrimSuffix(ep.DNSName, "."))
+	return zoneID
+}
+
+// remove removes the record with the name, type and set identifier of ep from the cached records of the zone
+func (z *zoneCache) remove(ep *endpoint.Endpoint) {
+	records := make([]*endpoint.Endpoint, 0, len(z.records))
+	for _, r := range z.records {
+		if strings.EqualFold(r.DNSName, ep.DNSName) && r.RecordType == ep.RecordType && r.SetIdentifier == ep.SetIdentifier {
+			continue
+		}
+		records = append(records, r)
+	}
+	z.records = records
+	z.generation++
+}
+
+func allChanges(changes *plan.Changes) []*endpoint.Endpoint {
+	var all []*endpoint.Endpoint
+	all = append(all, changes.Create...)
+	all = append(all, changes.UpdateOld...)
+	all = append(all, changes.UpdateNew...)
+	all = append(all, changes.Delete...)
+	return all
+}
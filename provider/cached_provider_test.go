@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// zonedProvider is a ZoneProvider counting the listings of its zones
+type zonedProvider struct {
+	BaseProvider
+	zones    ZoneIDName
+	records  map[string][]*endpoint.Endpoint
+	listings map[string]int
+	// zoneListings is the number of listings of the zones
+	zoneListings int
+	// contextRecords are the records passed in the context of the last call to ApplyChanges
+	contextRecords interface{}
+	applyErr       error
+}
+
+func newZonedProvider() *zonedProvider {
+	return &zonedProvider{
+		zones: ZoneIDName{"zone-1": "foo.org", "zone-2": "bar.org"},
+		records: map[string][]*endpoint.Endpoint{
+			"zone-1": {endpoint.NewEndpoint("a.foo.org", endpoint.RecordTypeA, "1.1.1.1")},
+			"zone-2": {endpoint.NewEndpoint("a.bar.org", endpoint.RecordTypeA, "2.2.2.2")},
+		},
+		listings: map[string]int{},
+	}
+}
+
+func (p *zonedProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return nil, errors.New("records are listed by zone")
+}
+
+func (p *zonedProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.contextRecords = ctx.Value(RecordsContextKey)
+	return p.applyErr
+}
+
+func (p *zonedProvider) ListZones(ctx context.Context) ([]Zone, error) {
+	p.zoneListings++
+	zones := []Zone{}
+	for id, name := range p.zones {
+		zones = append(zones, Zone{ID: id, Name: name, Data: p.records[id]})
+	}
+	return zones, nil
+}
+
+func (p *zonedProvider) ZoneRecords(ctx context.Context, zone Zone) ([]*endpoint.Endpoint, error) {
+	p.listings[zone.ID]++
+	return zone.Data.([]*endpoint.Endpoint), nil
+}
+
+func dnsNames(endpoints []*endpoint.Endpoint) []string {
+	names := []string{}
+	for _, ep := range endpoints {
+		names = append(names, ep.DNSName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCachedProviderRecords(t *testing.T) {
+	p := newZonedProvider()
+	c := NewCachedProvider(p, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		records, err := c.Records(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.bar.org", "a.foo.org"}, dnsNames(records))
+		// the labels set on the returned records are not cached
+		records[0].Labels[endpoint.OwnerLabelKey] = "owner"
+	}
+	assert.Equal(t, map[string]int{"zone-1": 1, "zone-2": 1}, p.listings)
+	// the zones are listed once per refresh as well
+	assert.Equal(t, 1, p.zoneListings)
+
+	records, err := c.Records(context.Background())
+	require.NoError(t, err)
+	for _, r := range records {
+		assert.NotContains(t, r.Labels, endpoint.OwnerLabelKey)
+	}
+
+	// the records of the zones are listed again once expired
+	c.RefreshInterval = 0
+	_, err = c.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"zone-1": 2, "zone-2": 2}, p.listings)
+	assert.Equal(t, 2, p.zoneListings)
+}
+
+func TestCachedProviderApplyChanges(t *testing.T) {
+	p := newZonedProvider()
+	c := NewCachedProvider(p, time.Hour)
+	records, err := c.Records(context.Background())
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), RecordsContextKey, records)
+	err = c.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("b.foo.org", endpoint.RecordTypeA, "1.1.1.2")},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("a.bar.org", endpoint.RecordTypeA, "2.2.2.2")},
+	})
+	require.NoError(t, err)
+	// the cached records passed in the context are not handed to the provider
+	assert.Nil(t, p.contextRecords)
+
+	// the changes are patched in the cached records
+	records, err = c.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.foo.org", "b.foo.org"}, dnsNames(records))
+	assert.Equal(t, map[string]int{"zone-1": 1, "zone-2": 1}, p.listings)
+}
+
+func TestCachedProviderFailedChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		applyErr error
+		listings map[string]int
+	}{
+		{
+			name: "partial failure",
+			applyErr: &PartialFailureError{Failures: []*RecordError{
+				{Endpoint: endpoint.NewEndpoint("b.foo.org", endpoint.RecordTypeA, "1.1.1.2"), Err: errors.New("failed")},
+			}},
+			// only the zone of the failed change is listed again
+			listings: map[string]int{"zone-1": 2, "zone-2": 1},
+		},
+		{
+			name:     "failure",
+			applyErr: errors.New("failed"),
+			listings: map[string]int{"zone-1": 2, "zone-2": 2},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newZonedProvider()
+			p.applyErr = tc.applyErr
+			c := NewCachedProvider(p, time.Hour)
+			_, err := c.Records(context.Background())
+			require.NoError(t, err)
+
+			err = c.ApplyChanges(context.Background(), &plan.Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint("b.foo.org", endpoint.RecordTypeA, "1.1.1.2")},
+				Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("a.bar.org", endpoint.RecordTypeA, "2.2.2.2")},
+			})
+			assert.Equal(t, tc.applyErr, err)
+
+			_, err = c.Records(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.listings, p.listings)
+		})
+	}
+}
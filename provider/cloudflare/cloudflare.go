@@ -248,6 +248,30 @@ func (p *CloudFlareProvider) Records(ctx context.Context) ([]*endpoint.Endpoint,
 	return endpoints, nil
 }
 
+// ListZones returns the zones, which are passed back to ZoneRecords
+func (p *CloudFlareProvider) ListZones(ctx context.Context) ([]provider.Zone, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]provider.Zone, 0, len(zones))
+	for _, zone := range zones {
+		list = append(list, provider.Zone{ID: zone.ID, Name: zone.Name, Data: zone})
+	}
+	return list, nil
+}
+
+// ZoneRecords returns the records of a zone returned by ListZones
+func (p *CloudFlareProvider) ZoneRecords(ctx context.Context, zone provider.Zone) ([]*endpoint.Endpoint, error) {
+	records, err := p.listDNSRecordsWithAutoPagination(ctx, zone.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByNameAndType(records), nil
+}
+
 // ApplyChanges applies a given set of changes in a given zone.
 func (p *CloudFlareProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	cloudflareChanges := []*cloudFlareChange{}
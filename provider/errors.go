@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ErrSoft is matched by the errors which are expected to go away on their own, e.g. when the
+// DNS provider throttles the requests. The controller retries them with backoff instead of exiting.
+var ErrSoft = errors.New("soft error")
+
+type softError struct {
+	err error
+}
+
+// NewSoftError marks err as an error which is expected to go away on its own, see ErrSoft
+func NewSoftError(err error) error {
+	return &softError{err: err}
+}
+
+func (e *softError) Error() string {
+	return e.err.Error()
+}
+
+func (e *softError) Unwrap() error {
+	return e.err
+}
+
+func (e *softError) Is(target error) bool {
+	return target == ErrSoft
+}
+
+// RecordError is the failure to apply the change of a single record
+type RecordError struct {
+	Endpoint *endpoint.Endpoint
+	Err      error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Endpoint.DNSName, e.Endpoint.RecordType, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// PartialFailureError is returned by ApplyChanges when the changes of some records could not be applied,
+// while the changes of all the other records were applied.
+type PartialFailureError struct {
+	Failures []*RecordError
+}
+
+// NewPartialFailureError returns a PartialFailureError for the given failed records
+func NewPartialFailureError(failures ...*RecordError) *PartialFailureError {
+	return &PartialFailureError{Failures: failures}
+}
+
+func (e *PartialFailureError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("failed to apply the changes of %d record(s): %s", len(e.Failures), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestPartialFailureError(t *testing.T) {
+	errInvalid := errors.New("invalid target")
+	err := fmt.Errorf("applying changes: %w", NewPartialFailureError(
+		&RecordError{Endpoint: endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4"), Err: errInvalid},
+		&RecordError{Endpoint: endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeCNAME, "foo.example.org"), Err: errors.New("throttled")},
+	))
+
+	var partial *PartialFailureError
+	require.True(t, errors.As(err, &partial))
+	require.Len(t, partial.Failures, 2)
+	assert.Equal(t, "foo.example.org", partial.Failures[0].Endpoint.DNSName)
+	assert.ErrorIs(t, partial.Failures[0], errInvalid)
+	assert.Equal(t, "failed to apply the changes of 2 record(s): foo.example.org A: invalid target; bar.example.org CNAME: throttled", partial.Error())
+}
+
+func TestSoftError(t *testing.T) {
+	errThrottled := errors.New("throttled")
+	err := fmt.Errorf("listing records: %w", NewSoftError(errThrottled))
+
+	assert.ErrorIs(t, err, ErrSoft)
+	assert.ErrorIs(t, err, errThrottled)
+	assert.Equal(t, "listing records: throttled", err.Error())
+	assert.NotErrorIs(t, errThrottled, ErrSoft)
+}
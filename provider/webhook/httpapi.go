@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// WebhookServer serves any provider.Provider over the webhook protocol,
+// so that it can be used by external-dns configured with --provider=webhook.
+type WebhookServer struct {
+	Provider provider.Provider
+}
+
+// Handler returns the http.Handler implementing the webhook protocol.
+func (p *WebhookServer) Handler() http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/", p.negotiateHandler)
+	m.HandleFunc("/records", p.recordsHandler)
+	m.HandleFunc("/propertyvaluesequal", p.propertyValuesEqualHandler)
+	m.HandleFunc("/adjustendpoints", p.adjustEndpointsHandler)
+	return m
+}
+
+func (p *WebhookServer) recordsHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		records, err := p.Provider.Records(req.Context())
+		if err != nil {
+			log.Errorf("Failed to get records: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, records)
+	case http.MethodPost:
+		var changes plan.Changes
+		if err := json.NewDecoder(req.Body).Decode(&changes); err != nil {
+			log.Errorf("Failed to decode changes: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := p.Provider.ApplyChanges(req.Context(), &changes); err != nil {
+			log.Errorf("Failed to apply changes: %v", err)
+			writeJSON(w, http.StatusInternalServerError, newApplyChangesErrorResponse(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		log.Errorf("Unsupported method %s for /records", req.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (p *WebhookServer) propertyValuesEqualHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	pve := PropertyValuesEqualRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&pve); err != nil {
+		log.Errorf("Failed to decode property values equal request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, PropertyValuesEqualResponse{
+		Equals: p.Provider.PropertyValuesEqual(pve.Name, pve.Previous, pve.Current),
+	})
+}
+
+func (p *WebhookServer) adjustEndpointsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var endpoints []*endpoint.Endpoint
+	if err := json.NewDecoder(req.Body).Decode(&endpoints); err != nil {
+		log.Errorf("Failed to decode endpoints to adjust: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, p.Provider.AdjustEndpoints(endpoints))
+}
+
+func (p *WebhookServer) negotiateHandler(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if accept := req.Header.Get(AcceptHeader); accept != MediaTypeFormatAndVersion {
+		log.Errorf("Unsupported webhook protocol version requested: %q", accept)
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	writeJSON(w, http.StatusOK, p.Provider.GetDomainFilter())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to encode webhook response: %v", err)
+	}
+}
+
+// StartHTTPApi serves the given provider over the webhook protocol on providerPort.
+// If startedChan is not nil, it is closed once the listener is ready.
+// The call blocks until the server stops.
+func StartHTTPApi(provider provider.Provider, startedChan chan struct{}, readTimeout, writeTimeout time.Duration, providerPort string) error {
+	p := WebhookServer{Provider: provider}
+
+	s := &http.Server{
+		Addr:         providerPort,
+		Handler:      p.Handler(),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
+
+	l, err := net.Listen("tcp", providerPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", providerPort, err)
+	}
+
+	if startedChan != nil {
+		close(startedChan)
+	}
+
+	log.Infof("Serving webhook provider API on %s", providerPort)
+	return s.Serve(l)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestWebhookServerHandler(t *testing.T) {
+	handler := (&WebhookServer{Provider: inmemory.NewInMemoryProvider()}).Handler()
+
+	for _, tc := range []struct {
+		title          string
+		method         string
+		path           string
+		accept         string
+		body           string
+		expectedStatus int
+	}{
+		{"negotiate", http.MethodGet, "/", MediaTypeFormatAndVersion, "", http.StatusOK},
+		{"negotiate unsupported version", http.MethodGet, "/", "application/external.dns.webhook+json;version=0", "", http.StatusNotAcceptable},
+		{"negotiate wrong method", http.MethodPost, "/", MediaTypeFormatAndVersion, "", http.StatusMethodNotAllowed},
+		{"unknown path", http.MethodGet, "/unknown", MediaTypeFormatAndVersion, "", http.StatusNotFound},
+		{"get records", http.MethodGet, "/records", MediaTypeFormatAndVersion, "", http.StatusOK},
+		{"apply empty changes", http.MethodPost, "/records", "", "{}", http.StatusNoContent},
+		{"apply invalid changes", http.MethodPost, "/records", "", "{", http.StatusBadRequest},
+		{"records wrong method", http.MethodPut, "/records", "", "", http.StatusMethodNotAllowed},
+		{"property values equal", http.MethodPost, "/propertyvaluesequal", "", `{"name":"a","previous":"b","current":"b"}`, http.StatusOK},
+		{"property values equal invalid", http.MethodPost, "/propertyvaluesequal", "", "{", http.StatusBadRequest},
+		{"adjust endpoints", http.MethodPost, "/adjustendpoints", "", "[]", http.StatusOK},
+		{"adjust endpoints wrong method", http.MethodGet, "/adjustendpoints", "", "", http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.accept != "" {
+				req.Header.Set(AcceptHeader, tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, MediaTypeFormatAndVersion, rec.Header().Get(ContentTypeHeader))
+			}
+		})
+	}
+}
+
+func TestStartHTTPApi(t *testing.T) {
+	started := make(chan struct{})
+	go StartHTTPApi(inmemory.NewInMemoryProvider(), started, 0, 0, "127.0.0.1:0")
+	<-started
+}
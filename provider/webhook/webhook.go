@@ -0,0 +1,386 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// MediaTypeFormatAndVersion is the content type (including the protocol version)
+	// exchanged between external-dns and a webhook provider.
+	MediaTypeFormatAndVersion = "application/external.dns.webhook+json;version=1"
+	// ContentTypeHeader is the HTTP header carrying the media type of a request or response.
+	ContentTypeHeader = "Content-Type"
+	// AcceptHeader is the HTTP header used to negotiate the protocol version.
+	AcceptHeader = "Accept"
+
+	defaultRequestTimeout = 30 * time.Second
+)
+
+var (
+	recordsErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "records_errors_total",
+			Help:      "Number of errors with the /records method of the webhook provider.",
+		},
+	)
+	applyChangesErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "applychanges_errors_total",
+			Help:      "Number of errors with the /records (POST) method of the webhook provider.",
+		},
+	)
+	propertyValuesEqualErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "propertyvaluesequal_errors_total",
+			Help:      "Number of errors with the /propertyvaluesequal method of the webhook provider.",
+		},
+	)
+	adjustEndpointsErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "adjustendpoints_errors_total",
+			Help:      "Number of errors with the /adjustendpoints method of the webhook provider.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(recordsErrorsTotal)
+	prometheus.MustRegister(applyChangesErrorsTotal)
+	prometheus.MustRegister(propertyValuesEqualErrorsTotal)
+	prometheus.MustRegister(adjustEndpointsErrorsTotal)
+}
+
+// PropertyValuesEqualRequest is the body of a /propertyvaluesequal request.
+type PropertyValuesEqualRequest struct {
+	Name     string `json:"name"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// PropertyValuesEqualResponse is the body of a /propertyvaluesequal response.
+type PropertyValuesEqualResponse struct {
+	Equals bool `json:"equals"`
+}
+
+// ApplyChangesErrorResponse is the body of a failed /records (POST) response. The failures of single records are
+// listed when the changes of the other records were applied, soft errors are expected to go away on their own.
+type ApplyChangesErrorResponse struct {
+	Message  string          `json:"message"`
+	Soft     bool            `json:"soft,omitempty"`
+	Failures []RecordFailure `json:"failures,omitempty"`
+}
+
+// RecordFailure is the failure to apply the change of a single record.
+type RecordFailure struct {
+	Endpoint *endpoint.Endpoint `json:"endpoint"`
+	Error    string             `json:"error"`
+}
+
+// newApplyChangesErrorResponse returns the response of the error returned by the ApplyChanges method of a provider.
+func newApplyChangesErrorResponse(err error) ApplyChangesErrorResponse {
+	resp := ApplyChangesErrorResponse{Message: err.Error(), Soft: errors.Is(err, provider.ErrSoft)}
+	var partial *provider.PartialFailureError
+	if errors.As(err, &partial) {
+		for _, f := range partial.Failures {
+			resp.Failures = append(resp.Failures, RecordFailure{Endpoint: f.Endpoint, Error: f.Err.Error()})
+		}
+	}
+	return resp
+}
+
+// err rebuilds the error returned by the webhook provider, a provider.PartialFailureError when single records failed.
+func (r ApplyChangesErrorResponse) err(statusCode int) error {
+	if len(r.Failures) == 0 {
+		err := fmt.Errorf("failed to apply changes with webhook provider with status code %d: %s", statusCode, r.Message)
+		if r.Soft {
+			return provider.NewSoftError(err)
+		}
+		return err
+	}
+	failures := make([]*provider.RecordError, 0, len(r.Failures))
+	for _, f := range r.Failures {
+		if f.Endpoint == nil {
+			continue
+		}
+		normalizeLabels([]*endpoint.Endpoint{f.Endpoint})
+		failures = append(failures, &provider.RecordError{Endpoint: f.Endpoint, Err: errors.New(f.Error)})
+	}
+	return provider.NewPartialFailureError(failures...)
+}
+
+// WebhookProvider is a provider.Provider that delegates every call to an
+// out-of-process provider speaking the webhook protocol over HTTP.
+type WebhookProvider struct {
+	// ctx bounds the requests of the methods which are not given a context, PropertyValuesEqual and AdjustEndpoints,
+	// so that they are canceled along with external-dns.
+	ctx             context.Context
+	client          *http.Client
+	remoteServerURL *url.URL
+	DomainFilter    endpoint.DomainFilter
+}
+
+// NewWebhookProvider negotiates the protocol version with the webhook server
+// listening at u and returns a provider talking to it.
+func NewWebhookProvider(ctx context.Context, u string) (*WebhookProvider, error) {
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create negotiation request: %w", err)
+	}
+	req.Header.Set(AcceptHeader, MediaTypeFormatAndVersion)
+
+	client := &http.Client{Timeout: defaultRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to webhook provider at %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook provider negotiation failed with status code %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get(ContentTypeHeader)
+	if contentType != MediaTypeFormatAndVersion {
+		return nil, fmt.Errorf("wrong content type returned by webhook provider: expected %q, got %q", MediaTypeFormatAndVersion, contentType)
+	}
+
+	df := endpoint.DomainFilter{}
+	if err := json.NewDecoder(resp.Body).Decode(&df); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal domain filter returned by webhook provider: %w", err)
+	}
+
+	return &WebhookProvider{
+		ctx:             ctx,
+		client:          client,
+		remoteServerURL: parsedURL,
+		DomainFilter:    df,
+	}, nil
+}
+
+// Records returns the records known to the webhook provider.
+func (p WebhookProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	u := p.remoteServerURL.JoinPath("records").String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		recordsErrorsTotal.Inc()
+		return nil, err
+	}
+	req.Header.Set(AcceptHeader, MediaTypeFormatAndVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		recordsErrorsTotal.Inc()
+		return nil, fmt.Errorf("failed to get records from webhook provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		recordsErrorsTotal.Inc()
+		return nil, fmt.Errorf("failed to get records from webhook provider with status code %d: %s", resp.StatusCode, readBody(resp))
+	}
+
+	var endpoints []*endpoint.Endpoint
+	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+		recordsErrorsTotal.Inc()
+		return nil, fmt.Errorf("failed to decode records returned by webhook provider: %w", err)
+	}
+	normalizeLabels(endpoints)
+	return endpoints, nil
+}
+
+// ApplyChanges sends the changes to the webhook provider.
+func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	u := p.remoteServerURL.JoinPath("records").String()
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(changes); err != nil {
+		applyChangesErrorsTotal.Inc()
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, b)
+	if err != nil {
+		applyChangesErrorsTotal.Inc()
+		return err
+	}
+	req.Header.Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		applyChangesErrorsTotal.Inc()
+		return fmt.Errorf("failed to apply changes with webhook provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		applyChangesErrorsTotal.Inc()
+		if resp.Header.Get(ContentTypeHeader) == MediaTypeFormatAndVersion {
+			var r ApplyChangesErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&r); err == nil {
+				return r.err(resp.StatusCode)
+			}
+		}
+		return fmt.Errorf("failed to apply changes with webhook provider with status code %d: %s", resp.StatusCode, readBody(resp))
+	}
+	return nil
+}
+
+// PropertyValuesEqual asks the webhook provider whether the two values of the
+// provider specific property are equal. Failures are logged and the values are
+// compared as plain strings, since the interface does not allow returning an error.
+func (p WebhookProvider) PropertyValuesEqual(name string, previous string, current string) bool {
+	u := p.remoteServerURL.JoinPath("propertyvaluesequal").String()
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(&PropertyValuesEqualRequest{Name: name, Previous: previous, Current: current}); err != nil {
+		propertyValuesEqualErrorsTotal.Inc()
+		log.Errorf("Failed to encode property values equal request: %v", err)
+		return previous == current
+	}
+
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodPost, u, b)
+	if err != nil {
+		propertyValuesEqualErrorsTotal.Inc()
+		log.Errorf("Failed to create property values equal request: %v", err)
+		return previous == current
+	}
+	req.Header.Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+	req.Header.Set(AcceptHeader, MediaTypeFormatAndVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		propertyValuesEqualErrorsTotal.Inc()
+		log.Errorf("Failed to call property values equal on webhook provider: %v", err)
+		return previous == current
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		propertyValuesEqualErrorsTotal.Inc()
+		log.Errorf("Failed to call property values equal on webhook provider with status code %d: %s", resp.StatusCode, readBody(resp))
+		return previous == current
+	}
+
+	r := PropertyValuesEqualResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		propertyValuesEqualErrorsTotal.Inc()
+		log.Errorf("Failed to decode property values equal response: %v", err)
+		return previous == current
+	}
+	return r.Equals
+}
+
+// AdjustEndpoints lets the webhook provider modify the desired endpoints.
+// On failure the endpoints are returned unmodified.
+func (p WebhookProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	u := p.remoteServerURL.JoinPath("adjustendpoints").String()
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(endpoints); err != nil {
+		adjustEndpointsErrorsTotal.Inc()
+		log.Errorf("Failed to encode endpoints to adjust: %v", err)
+		return endpoints
+	}
+
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodPost, u, b)
+	if err != nil {
+		adjustEndpointsErrorsTotal.Inc()
+		log.Errorf("Failed to create adjust endpoints request: %v", err)
+		return endpoints
+	}
+	req.Header.Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+	req.Header.Set(AcceptHeader, MediaTypeFormatAndVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		adjustEndpointsErrorsTotal.Inc()
+		log.Errorf("Failed to call adjust endpoints on webhook provider: %v", err)
+		return endpoints
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		adjustEndpointsErrorsTotal.Inc()
+		log.Errorf("Failed to call adjust endpoints on webhook provider with status code %d: %s", resp.StatusCode, readBody(resp))
+		return endpoints
+	}
+
+	var adjusted []*endpoint.Endpoint
+	if err := json.NewDecoder(resp.Body).Decode(&adjusted); err != nil {
+		adjustEndpointsErrorsTotal.Inc()
+		log.Errorf("Failed to decode adjusted endpoints: %v", err)
+		return endpoints
+	}
+	normalizeLabels(adjusted)
+	return adjusted
+}
+
+// normalizeLabels sets the labels omitted on the wire when empty, the rest of the pipeline expects them to be set.
+func normalizeLabels(endpoints []*endpoint.Endpoint) {
+	for _, ep := range endpoints {
+		if ep.Labels == nil {
+			ep.Labels = endpoint.NewLabels()
+		}
+	}
+}
+
+// GetDomainFilter returns the domain filter negotiated with the webhook provider.
+func (p WebhookProvider) GetDomainFilter() endpoint.DomainFilterInterface {
+	return p.DomainFilter
+}
+
+// readBody returns at most the first kilobyte of the response body for error reporting.
+func readBody(resp *http.Response) string {
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+var _ provider.Provider = WebhookProvider{}
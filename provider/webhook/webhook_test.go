@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func newInMemoryWebhookServer(t *testing.T) (*inmemory.InMemoryProvider, *httptest.Server) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.org"}))
+	srv := httptest.NewServer((&WebhookServer{Provider: p}).Handler())
+	t.Cleanup(srv.Close)
+	return p, srv
+}
+
+func TestNewWebhookProviderNegotiation(t *testing.T) {
+	_, srv := newInMemoryWebhookServer(t)
+
+	p, err := NewWebhookProvider(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.False(t, p.GetDomainFilter().IsConfigured())
+}
+
+func TestNewWebhookProviderWrongContentType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentTypeHeader, "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewWebhookProvider(context.Background(), srv.URL)
+	assert.Error(t, err)
+}
+
+func TestNewWebhookProviderDomainFilter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, MediaTypeFormatAndVersion, r.Header.Get(AcceptHeader))
+		w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+		w.Write([]byte(`{"include":["example.org"]}`))
+	}))
+	defer srv.Close()
+
+	p, err := NewWebhookProvider(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.True(t, p.GetDomainFilter().Match("foo.example.org"))
+	assert.False(t, p.GetDomainFilter().Match("foo.example.com"))
+}
+
+func TestWebhookProviderRecordsAndApplyChanges(t *testing.T) {
+	backend, srv := newInMemoryWebhookServer(t)
+
+	p, err := NewWebhookProvider(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeCNAME, "foo.example.org"),
+		},
+	}
+	require.NoError(t, p.ApplyChanges(ctx, changes))
+
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	for _, record := range records {
+		// empty labels are omitted on the wire
+		assert.NotNil(t, record.Labels)
+	}
+
+	backendRecords, err := backend.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, backendRecords, 2)
+
+	// creating the same records twice is rejected by the in-memory provider
+	assert.Error(t, p.ApplyChanges(ctx, changes))
+}
+
+func TestWebhookProviderRecordsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	p, err := NewWebhookProvider(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	_, err = p.Records(context.Background())
+	assert.Error(t, err)
+	assert.Error(t, p.ApplyChanges(context.Background(), &plan.Changes{}))
+
+	// AdjustEndpoints and PropertyValuesEqual fall back to local behaviour
+	endpoints := []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")}
+	assert.Equal(t, endpoints, p.AdjustEndpoints(endpoints))
+	assert.True(t, p.PropertyValuesEqual("name", "value", "value"))
+	assert.False(t, p.PropertyValuesEqual("name", "value", "other"))
+}
+
+func TestWebhookProviderAdjustEndpointsAndPropertyValuesEqual(t *testing.T) {
+	_, srv := newInMemoryWebhookServer(t)
+
+	p, err := NewWebhookProvider(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	endpoints := []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")}
+	assert.Equal(t, endpoints, p.AdjustEndpoints(endpoints))
+	assert.True(t, p.PropertyValuesEqual("name", "value", "value"))
+	assert.False(t, p.PropertyValuesEqual("name", "value", "other"))
+}
+
+func TestWebhookProviderCanceledContext(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`{}`))
+		case "/propertyvaluesequal":
+			calls++
+			w.Write([]byte(`{"equals":true}`))
+		case "/adjustendpoints":
+			calls++
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p, err := NewWebhookProvider(ctx, srv.URL)
+	require.NoError(t, err)
+	assert.True(t, p.PropertyValuesEqual("name", "value", "other"))
+	assert.Equal(t, 1, calls)
+
+	// the requests are canceled along with the context of the provider, which falls back to local behaviour
+	cancel()
+	endpoints := []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")}
+	assert.Equal(t, endpoints, p.AdjustEndpoints(endpoints))
+	assert.False(t, p.PropertyValuesEqual("name", "value", "other"))
+	assert.Equal(t, 1, calls)
+}
+
+// failingProvider fails to apply the changes with the given error
+type failingProvider struct {
+	*inmemory.InMemoryProvider
+	err error
+}
+
+func (p *failingProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return p.err
+}
+
+func TestWebhookProviderApplyChangesPartialFailure(t *testing.T) {
+	failed := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	backend := &failingProvider{
+		InMemoryProvider: inmemory.NewInMemoryProvider(),
+		err:              provider.NewPartialFailureError(&provider.RecordError{Endpoint: failed, Err: errors.New("quota exceeded")}),
+	}
+	srv := httptest.NewServer((&WebhookServer{Provider: backend}).Handler())
+	defer srv.Close()
+
+	p, err := NewWebhookProvider(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	err = p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{failed}})
+	var partial *provider.PartialFailureError
+	require.ErrorAs(t, err, &partial)
+	require.Len(t, partial.Failures, 1)
+	assert.Equal(t, "foo.example.org", partial.Failures[0].Endpoint.DNSName)
+	assert.Equal(t, endpoint.RecordTypeA, partial.Failures[0].Endpoint.RecordType)
+	assert.EqualError(t, partial.Failures[0].Err, "quota exceeded")
+
+	// the other errors are returned as such
+	backend.err = errors.New("zone not found")
+	err = p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{failed}})
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &partial))
+	assert.NotErrorIs(t, err, provider.ErrSoft)
+	assert.Contains(t, err.Error(), "zone not found")
+
+	backend.err = provider.NewSoftError(errors.New("rate limited"))
+	err = p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{failed}})
+	assert.ErrorIs(t, err, provider.ErrSoft)
+	assert.Contains(t, err.Error(), "rate limited")
+}
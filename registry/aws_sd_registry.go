@@ -72,13 +72,19 @@ func (sdr *AWSSDRegistry) MissingRecords() []*endpoint.Endpoint {
 	return nil
 }
 
+// OwnershipChanges returns nil because there is no ownership records to change for AWSSD registry
+func (sdr *AWSSDRegistry) OwnershipChanges() *plan.Changes {
+	return nil
+}
+
 // ApplyChanges filters out records not owned the External-DNS, additionally it adds the required label
 // inserted in the AWS SD instance as a CreateID field
 func (sdr *AWSSDRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	updateOld, updateNew := filterOwnedUpdates(sdr.ownerID, changes.UpdateOld, changes.UpdateNew)
 	filteredChanges := &plan.Changes{
 		Create:    changes.Create,
-		UpdateNew: filterOwnedRecords(sdr.ownerID, changes.UpdateNew),
-		UpdateOld: filterOwnedRecords(sdr.ownerID, changes.UpdateOld),
+		UpdateNew: updateNew,
+		UpdateOld: updateOld,
 		Delete:    filterOwnedRecords(sdr.ownerID, changes.Delete),
 	}
 
@@ -0,0 +1,293 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// configMapEntry is the ownership information of a record stored in the ConfigMap
+type configMapEntry struct {
+	DNSName       string          `json:"dnsName"`
+	RecordType    string          `json:"recordType"`
+	SetIdentifier string          `json:"setIdentifier,omitempty"`
+	Labels        endpoint.Labels `json:"labels"`
+}
+
+// ConfigMapRegistry implements registry interface with ownership information stored in a Kubernetes ConfigMap,
+// so that no TXT record is created next to the records.
+type ConfigMapRegistry struct {
+	provider  provider.Provider
+	ownerID   string
+	client    kubernetes.Interface
+	namespace string
+	name      string
+
+	// configMap is the ConfigMap read by the last call to Records, nil when it doesn't exist
+	configMap *corev1.ConfigMap
+	// stale holds the keys of the entries of the owner left without record, found by the last call to Records
+	stale []string
+}
+
+// NewConfigMapRegistry returns a registry storing the ownership information in the given ConfigMap, which is created when missing
+func NewConfigMapRegistry(provider provider.Provider, client kubernetes.Interface, namespace, name, ownerID string) (*ConfigMapRegistry, error) {
+	if ownerID == "" {
+		return nil, errors.New("owner id cannot be empty")
+	}
+	if name == "" {
+		return nil, errors.New("configmap name cannot be empty")
+	}
+	return &ConfigMapRegistry{
+		provider:  provider,
+		ownerID:   ownerID,
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}, nil
+}
+
+func (im *ConfigMapRegistry) GetDomainFilter() endpoint.DomainFilterInterface {
+	return im.provider.GetDomainFilter()
+}
+
+// configMapKey returns the key of the ConfigMap holding the ownership information of a record.
+// The key is made of the record type and DNS name when they form a valid key, and of their hash otherwise.
+func configMapKey(dnsName, recordType, setIdentifier string) string {
+	key := strings.ToLower(recordType) + "." + strings.ToLower(dnsName)
+	if setIdentifier == "" && len(validation.IsConfigMapKey(key)) == 0 {
+		return key
+	}
+	hash := sha256.Sum256([]byte(strings.ToLower(dnsName) + "/" + setIdentifier))
+	return strings.ToLower(recordType) + "." + hex.EncodeToString(hash[:16])
+}
+
+// Records returns the current records of the DNS provider, labeled with the ownership information of the ConfigMap
+func (im *ConfigMapRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := im.client.CoreV1().ConfigMaps(im.namespace).Get(ctx, im.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		im.configMap, im.stale = nil, nil
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	im.configMap = cm
+
+	found := make(map[string]bool, len(records))
+	for _, record := range records {
+		key := configMapKey(record.DNSName, record.RecordType, record.SetIdentifier)
+		value, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		found[key] = true
+		var entry configMapEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			log.Warnf("Ignoring invalid ownership information of %s %s in ConfigMap %s/%s: %v", record.DNSName, record.RecordType, im.namespace, im.name, err)
+			continue
+		}
+		if record.Labels == nil {
+			record.Labels = endpoint.NewLabels()
+		}
+		for k, v := range entry.Labels {
+			record.Labels[k] = v
+		}
+	}
+
+	// the entries of the owner whose record was deleted behind its back are pruned with the next changes,
+	// so that they don't claim a record created later by somebody else
+	im.stale = nil
+	for key, value := range cm.Data {
+		var entry configMapEntry
+		if found[key] || json.Unmarshal([]byte(value), &entry) != nil {
+			continue
+		}
+		if entry.Labels[endpoint.OwnerLabelKey] == im.ownerID && im.provider.GetDomainFilter().Match(entry.DNSName) {
+			im.stale = append(im.stale, key)
+		}
+	}
+	return records, nil
+}
+
+// MissingRecords returns nil because there is no missing records for ConfigMap registry
+func (im *ConfigMapRegistry) MissingRecords() []*endpoint.Endpoint {
+	return nil
+}
+
+// OwnershipChanges returns nil because there is no ownership records to change for ConfigMap registry
+func (im *ConfigMapRegistry) OwnershipChanges() *plan.Changes {
+	return nil
+}
+
+// ApplyChanges filters out the records not owned by this instance and applies the changes. The ownership of the
+// records is stored once the DNS provider applied their changes, along with the removal of the stale entries.
+func (im *ConfigMapRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	updateOld, updateNew := filterOwnedUpdates(im.ownerID, changes.UpdateOld, changes.UpdateNew)
+	filteredChanges := &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: updateNew,
+		UpdateOld: updateOld,
+		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
+	}
+	for _, r := range filteredChanges.Create {
+		if r.Labels == nil {
+			r.Labels = endpoint.NewLabels()
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	}
+
+	err := im.provider.ApplyChanges(ctx, filteredChanges)
+	var partial *provider.PartialFailureError
+	if err != nil && !errors.As(err, &partial) {
+		return err
+	}
+
+	owned := append(append([]*endpoint.Endpoint{}, filteredChanges.Create...), filteredChanges.UpdateNew...)
+	deleted := filteredChanges.Delete
+	if partial != nil {
+		owned = withoutFailedRecords(owned, partial.Failures)
+		deleted = withoutFailedRecords(deleted, partial.Failures)
+	}
+	if updateErr := im.updateOwnership(ctx, owned, deleted); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// withoutFailedRecords returns the endpoints whose change did not fail
+func withoutFailedRecords(endpoints []*endpoint.Endpoint, failures []*provider.RecordError) []*endpoint.Endpoint {
+	failed := map[string]bool{}
+	for _, f := range failures {
+		failed[configMapKey(f.Endpoint.DNSName, f.Endpoint.RecordType, f.Endpoint.SetIdentifier)] = true
+	}
+	result := []*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		if !failed[configMapKey(ep.DNSName, ep.RecordType, ep.SetIdentifier)] {
+			result = append(result, ep)
+		}
+	}
+	return result
+}
+
+// updateOwnership stores the ownership information of the owned records and removes the one of the deleted records
+// and the stale entries. The ConfigMap read by Records is updated, and only read again after a conflict.
+func (im *ConfigMapRegistry) updateOwnership(ctx context.Context, owned, deleted []*endpoint.Endpoint) error {
+	entries := make(map[string]string, len(owned))
+	for _, ep := range owned {
+		labels := endpoint.NewLabels()
+		for k, v := range ep.Labels {
+			// these labels are only used for planning
+			if k != endpoint.PriorityLabelKey && k != endpoint.ResourceCreationTimestampLabelKey && k != endpoint.AdoptLabelKey && k != endpoint.SharedTargetsLabelKey {
+				labels[k] = v
+			}
+		}
+		value, err := json.Marshal(configMapEntry{DNSName: ep.DNSName, RecordType: ep.RecordType, SetIdentifier: ep.SetIdentifier, Labels: labels})
+		if err != nil {
+			return err
+		}
+		entries[configMapKey(ep.DNSName, ep.RecordType, ep.SetIdentifier)] = string(value)
+	}
+	removed := append([]string{}, im.stale...)
+	for _, ep := range deleted {
+		removed = append(removed, configMapKey(ep.DNSName, ep.RecordType, ep.SetIdentifier))
+	}
+
+	configMaps := im.client.CoreV1().ConfigMaps(im.namespace)
+	cm := im.configMap
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		data := map[string]string{}
+		if cm != nil {
+			for key, value := range cm.Data {
+				data[key] = value
+			}
+		}
+		for key, value := range entries {
+			data[key] = value
+		}
+		for _, key := range removed {
+			delete(data, key)
+		}
+		if (cm != nil && equality.Semantic.DeepEqual(data, cm.Data)) || (cm == nil && len(data) == 0) {
+			return nil
+		}
+
+		var updated *corev1.ConfigMap
+		var err error
+		if cm == nil {
+			updated, err = configMaps.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: im.name, Namespace: im.namespace}, Data: data}, metav1.CreateOptions{})
+		} else {
+			updated = cm.DeepCopy()
+			updated.Data = data
+			updated, err = configMaps.Update(ctx, updated, metav1.UpdateOptions{})
+		}
+		if retriable(err) {
+			// somebody else changed the ConfigMap, read it again
+			cm, err = configMaps.Get(ctx, im.name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				cm = nil
+			} else if err != nil {
+				return err
+			}
+			return apierrors.NewConflict(corev1.Resource("configmaps"), im.name, errors.New("the ConfigMap was changed"))
+		}
+		if err != nil {
+			return err
+		}
+		cm = updated
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	im.configMap, im.stale = cm, nil
+	return nil
+}
+
+// PropertyValuesEqual compares two attribute values for equality
+func (im *ConfigMapRegistry) PropertyValuesEqual(name string, previous string, current string) bool {
+	return im.provider.PropertyValuesEqual(name, previous, current)
+}
+
+// AdjustEndpoints modifies the endpoints as needed by the specific provider
+func (im *ConfigMapRegistry) AdjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	return im.provider.AdjustEndpoints(endpoints)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+var _ Registry = &ConfigMapRegistry{}
+
+func TestConfigMapRegistry(t *testing.T) {
+	t.Run("NewConfigMapRegistry", testConfigMapInit)
+	t.Run("ApplyChanges", testConfigMapApplyChanges)
+	t.Run("ForeignRecords", testConfigMapForeignRecords)
+	t.Run("FailedRecords", testConfigMapFailedRecords)
+	t.Run("StaleEntries", testConfigMapStaleEntries)
+}
+
+// partialFailureProvider fails to apply the changes of the records in failing
+type partialFailureProvider struct {
+	*inmemory.InMemoryProvider
+	failing map[string]bool
+}
+
+func (p *partialFailureProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	var failures []*provider.RecordError
+	applied := &plan.Changes{}
+	for _, ep := range changes.Create {
+		if p.failing[ep.DNSName] {
+			failures = append(failures, &provider.RecordError{Endpoint: ep, Err: errors.New("invalid record")})
+		} else {
+			applied.Create = append(applied.Create, ep)
+		}
+	}
+	if err := p.InMemoryProvider.ApplyChanges(ctx, applied); err != nil {
+		return err
+	}
+	return provider.NewPartialFailureError(failures...)
+}
+
+func testConfigMapInit(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	client := fake.NewSimpleClientset()
+
+	_, err := NewConfigMapRegistry(p, client, "default", "external-dns", "")
+	require.Error(t, err)
+	_, err = NewConfigMapRegistry(p, client, "default", "", "owner")
+	require.Error(t, err)
+
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns", "owner")
+	require.NoError(t, err)
+	assert.Equal(t, p, r.provider)
+	assert.Equal(t, "owner", r.ownerID)
+}
+
+func testConfigMapApplyChanges(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.org"))
+	client := fake.NewSimpleClientset()
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns", "owner")
+	require.NoError(t, err)
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	foo := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	foo.Labels[endpoint.ResourceLabelKey] = "ingress/default/foo"
+	foo.Labels[endpoint.PriorityLabelKey] = "10"
+	wildcard := endpoint.NewEndpoint("*.example.org", endpoint.RecordTypeCNAME, "lb.example.com")
+	weighted := endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "5.6.7.8").WithSetIdentifier("eu")
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{foo, wildcard, weighted}}))
+
+	// only the ownership information is stored in the ConfigMap, no TXT record is created
+	cm, err := client.CoreV1().ConfigMaps("default").Get(ctx, "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cm.Data, 3)
+	assert.JSONEq(t, `{"dnsName":"foo.example.org","recordType":"A","labels":{"owner":"owner","resource":"ingress/default/foo"}}`, cm.Data["a.foo.example.org"])
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	for _, record := range records {
+		assert.NotEqual(t, endpoint.RecordTypeTXT, record.RecordType)
+		assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey], record.DNSName)
+	}
+
+	updated := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "4.3.2.1")
+	updated.Labels[endpoint.OwnerLabelKey] = "owner"
+	updated.Labels[endpoint.ResourceLabelKey] = "ingress/default/other"
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{foo},
+		UpdateNew: []*endpoint.Endpoint{updated},
+		Delete:    []*endpoint.Endpoint{wildcard, weighted},
+	}))
+
+	cm, err = client.CoreV1().ConfigMaps("default").Get(ctx, "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cm.Data, 1)
+	assert.JSONEq(t, `{"dnsName":"foo.example.org","recordType":"A","labels":{"owner":"owner","resource":"ingress/default/other"}}`, cm.Data["a.foo.example.org"])
+}
+
+func testConfigMapForeignRecords(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.org"))
+	client := fake.NewSimpleClientset()
+
+	other, err := NewConfigMapRegistry(p, client, "default", "external-dns", "other")
+	require.NoError(t, err)
+	foreign := endpoint.NewEndpoint("foreign.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{foreign}}))
+	unmanaged := endpoint.NewEndpoint("unmanaged.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{unmanaged}}))
+
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns", "owner")
+	require.NoError(t, err)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	owners := map[string]string{}
+	for _, record := range records {
+		owners[record.DNSName] = record.Labels[endpoint.OwnerLabelKey]
+	}
+	assert.Equal(t, map[string]string{"foreign.example.org": "other", "unmanaged.example.org": ""}, owners)
+
+	// the records of other owners are neither deleted nor is their ownership removed
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Delete: records}))
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	cm, err := client.CoreV1().ConfigMaps("default").Get(ctx, "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data, "a.foreign.example.org")
+}
+
+func testConfigMapFailedRecords(t *testing.T) {
+	ctx := context.Background()
+	p := &partialFailureProvider{InMemoryProvider: inmemory.NewInMemoryProvider(), failing: map[string]bool{"bad.example.org": true}}
+	require.NoError(t, p.CreateZone("example.org"))
+	client := fake.NewSimpleClientset()
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns", "owner")
+	require.NoError(t, err)
+
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	good := endpoint.NewEndpoint("good.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	bad := endpoint.NewEndpoint("bad.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	err = r.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{good, bad}})
+	var partial *provider.PartialFailureError
+	require.ErrorAs(t, err, &partial)
+
+	// only the ownership of the created record is stored
+	cm, err := client.CoreV1().ConfigMaps("default").Get(ctx, "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data, "a.good.example.org")
+	assert.NotContains(t, cm.Data, "a.bad.example.org")
+}
+
+func testConfigMapStaleEntries(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.org"))
+	updates := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updates++
+		return false, nil, nil
+	})
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns", "owner")
+	require.NoError(t, err)
+
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	foo := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	bar := endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{foo, bar}}))
+
+	// the ConfigMap is not written when the ownership is left unchanged
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{UpdateOld: records[:1], UpdateNew: records[:1]}))
+	assert.Zero(t, updates)
+
+	// the entry of a record deleted behind the back of the registry is pruned with the next changes
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{bar}}))
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	baz := endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{baz}}))
+	assert.Equal(t, 1, updates)
+	cm, err := client.CoreV1().ConfigMaps("default").Get(ctx, "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data, "a.foo.example.org")
+	assert.Contains(t, cm.Data, "a.baz.example.org")
+	assert.NotContains(t, cm.Data, "a.bar.example.org")
+}
+
+func TestConfigMapKey(t *testing.T) {
+	assert.Equal(t, "a.foo.example.org", configMapKey("foo.example.org", endpoint.RecordTypeA, ""))
+	assert.Equal(t, "aaaa.foo.example.org", configMapKey("Foo.Example.org", endpoint.RecordTypeAAAA, ""))
+
+	wildcard := configMapKey("*.example.org", endpoint.RecordTypeCNAME, "")
+	assert.Regexp(t, `^cname\.[0-9a-f]{32}$`, wildcard)
+	assert.NotEqual(t, wildcard, configMapKey("_.example.org", endpoint.RecordTypeCNAME, ""))
+
+	eu := configMapKey("foo.example.org", endpoint.RecordTypeA, "eu")
+	assert.Regexp(t, `^a\.[0-9a-f]{32}$`, eu)
+	assert.NotEqual(t, eu, configMapKey("foo.example.org", endpoint.RecordTypeA, "us"))
+}
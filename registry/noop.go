@@ -50,6 +50,11 @@ func (im *NoopRegistry) MissingRecords() []*endpoint.Endpoint {
 	return nil
 }
 
+// OwnershipChanges returns nil because there is no ownership records to change for Noop registry
+func (im *NoopRegistry) OwnershipChanges() *plan.Changes {
+	return nil
+}
+
 // ApplyChanges propagates changes to the dns provider
 func (im *NoopRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	return im.provider.ApplyChanges(ctx, changes)
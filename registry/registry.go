@@ -29,6 +29,7 @@ import (
 // Records() returns ALL records registered with DNS provider
 // each entry includes owner information
 // ApplyChanges(changes *plan.Changes) propagates the changes to the DNS Provider API and correspondingly updates ownership depending on type of registry being used
+// OwnershipChanges() returns the changes of the ownership records the registry needs on its own, e.g. to re-encrypt them, collected during the run of Records()
 type Registry interface {
 	Records(ctx context.Context) ([]*endpoint.Endpoint, error)
 	ApplyChanges(ctx context.Context, changes *plan.Changes) error
@@ -36,12 +37,25 @@ type Registry interface {
 	AdjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint
 	GetDomainFilter() endpoint.DomainFilterInterface
 	MissingRecords() []*endpoint.Endpoint
+	OwnershipChanges() *plan.Changes
+}
+
+// OrphanedRecordsLister is implemented by the registries which can tell the ownership records left without any record,
+// e.g. after the record was deleted out-of-band, whether they delete them or not
+type OrphanedRecordsLister interface {
+	OrphanedRecords() []*endpoint.Endpoint
 }
 
 // TODO(ideahitme): consider moving this to Plan
+// filterOwnedRecords keeps the records owned by ownerID and the records the planner adopted for it
 func filterOwnedRecords(ownerID string, eps []*endpoint.Endpoint) []*endpoint.Endpoint {
 	filtered := []*endpoint.Endpoint{}
 	for _, ep := range eps {
+		if ep.Labels[endpoint.AdoptLabelKey] == ownerID {
+			log.Infof(`Adopting endpoint %v owned by "%s" for "%s"`, ep, ep.Labels[endpoint.OwnerLabelKey], ownerID)
+			filtered = append(filtered, ep)
+			continue
+		}
 		if endpointOwner, ok := ep.Labels[endpoint.OwnerLabelKey]; !ok || endpointOwner != ownerID {
 			log.Debugf(`Skipping endpoint %v because owner id does not match, found: "%s", required: "%s"`, ep, endpointOwner, ownerID)
 			continue
@@ -50,3 +64,27 @@ func filterOwnedRecords(ownerID string, eps []*endpoint.Endpoint) []*endpoint.En
 	}
 	return filtered
 }
+
+// filterOwnedUpdates keeps the updates of the records owned by ownerID, of the records the planner adopted for it and
+// of the records shared before and after the update. The current record of an update decides whether it is kept, along
+// with the desired one, so that the two lists keep matching by index.
+func filterOwnedUpdates(ownerID string, updateOld, updateNew []*endpoint.Endpoint) ([]*endpoint.Endpoint, []*endpoint.Endpoint) {
+	filteredOld := []*endpoint.Endpoint{}
+	filteredNew := []*endpoint.Endpoint{}
+	for i := range updateOld {
+		if i >= len(updateNew) {
+			log.Errorf("Skipping update of endpoint %v without desired endpoint", updateOld[i])
+			continue
+		}
+		current, desired := updateOld[i], updateNew[i]
+		shared := current.Labels[endpoint.SharedLabelKey] == "true" && desired.Labels[endpoint.SharedLabelKey] == "true"
+		if shared && current.Labels[endpoint.OwnerLabelKey] != ownerID {
+			log.Debugf(`Sharing endpoint %v owned by "%s" with "%s"`, current, current.Labels[endpoint.OwnerLabelKey], ownerID)
+		} else if len(filterOwnedRecords(ownerID, []*endpoint.Endpoint{current})) == 0 {
+			continue
+		}
+		filteredOld = append(filteredOld, current)
+		filteredNew = append(filteredNew, desired)
+	}
+	return filteredOld, filteredNew
+}
@@ -20,9 +20,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
-	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -32,17 +34,37 @@ import (
 
 const recordTemplate = "%{record_type}"
 
+var retiredKeyRecords = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "registry",
+		Name:      "retired_key_records",
+		Help:      "Number of TXT registry records of the owner which are encrypted with a retired key.",
+	},
+	[]string{"owner"},
+)
+
+var orphanedTXTRecordsGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "registry",
+		Name:      "orphaned_records",
+		Help:      "Number of TXT registry records of the owner which do not own any record anymore.",
+	},
+	[]string{"owner"},
+)
+
+func init() {
+	prometheus.MustRegister(retiredKeyRecords)
+	prometheus.MustRegister(orphanedTXTRecordsGauge)
+}
+
 // TXTRegistry implements registry interface with ownership implemented via associated TXT records
 type TXTRegistry struct {
 	provider provider.Provider
 	ownerID  string // refers to the owner id of the current instance
 	mapper   nameMapper
 
-	// cache the records in memory and update on an interval instead.
-	recordsCache            []*endpoint.Endpoint
-	recordsCacheRefreshTime time.Time
-	cacheInterval           time.Duration
-
 	// optional string to use to replace the asterisk in wildcard entries - without using this,
 	// registry TXT records corresponding to wildcard records will be invalid (and rejected by most providers), due to
 	// having a '*' appear (not as the first character) - see https://tools.ietf.org/html/rfc1034#section-4.3.3
@@ -53,49 +75,163 @@ type TXTRegistry struct {
 	// missingTXTRecords stores TXT records which are missing after the migration to the new format
 	missingTXTRecords []*endpoint.Endpoint
 
+	// txtRecordNames stores the names of the TXT records of the registry, which cannot be used by the TXT records to manage
+	txtRecordNames map[string]struct{}
+
+	// sharedTXTRecords stores the TXT records of the shared records, holding the ownership of every owner
+	sharedTXTRecords map[string]sharedTXTRecord
+
+	// txtFormat is the format of the labels stored in the TXT records, the ones in another format are rewritten
+	txtFormat endpoint.LabelsFormat
+
 	// encrypt text records
 	txtEncryptEnabled bool
 	txtEncryptAESKey  []byte
+	// retired keys, only used to decrypt the text records which are then re-encrypted with txtEncryptAESKey
+	txtDecryptAESKeys [][]byte
+
+	// ownershipChanges stores the changes of the TXT records encrypted with a retired key, in another format or orphaned
+	ownershipChanges *plan.Changes
+
+	// deleteOrphans enables the deletion of the TXT records of the owner which do not own any record anymore,
+	// including the TXT records in the legacy format, which are not created anymore
+	deleteOrphans bool
+
+	// orphanedRecords stores the TXT records of the owner which do not own any record anymore, deleted or not
+	orphanedRecords []*endpoint.Endpoint
+
+	// apexWarned tells whether the apex name template was reported as unused because of a missing domain filter
+	apexWarned bool
+}
+
+// ownedTXTRecord is a TXT record of the owner of the registry, along with its labels
+type ownedTXTRecord struct {
+	record       *endpoint.Endpoint
+	labels       endpoint.Labels
+	endpointName string
+	recordType   string
+	retired      bool
+	format       endpoint.LabelsFormat
 }
 
-const keySuffixAAAA = ":AAAA"
+// sharedTXTRecord is the TXT record of a shared record, with one value per owner
+type sharedTXTRecord struct {
+	record *endpoint.Endpoint
+	values map[string]string
+	labels map[string]endpoint.Labels
+}
+
+// TXTConfig is the configuration of a TXTRegistry
+type TXTConfig struct {
+	// Prefix and Suffix are added to the names of the records to name their TXT records, and are mutually exclusive
+	Prefix string
+	Suffix string
+	// OwnerID identifies the records owned by the registry
+	OwnerID string
+	// WildcardReplacement replaces the asterisk of the wildcard records in the names of their TXT records
+	WildcardReplacement string
+	// ManagedRecordTypes are the types of the records owned by the registry
+	ManagedRecordTypes []string
+	// EncryptEnabled encrypts the TXT records with EncryptAESKey, while DecryptAESKeys are the retired keys
+	// the TXT records can still be decrypted with
+	EncryptEnabled bool
+	EncryptAESKey  []byte
+	DecryptAESKeys [][]byte
+	// DeleteOrphans deletes the TXT records of the owner which do not own any record anymore
+	DeleteOrphans bool
+	// NameTemplate names the TXT records instead of the prefix or suffix, and ApexNameTemplate the ones of the
+	// records at a zone apex
+	NameTemplate     string
+	ApexNameTemplate string
+	// Format is the format of the labels stored in the TXT records, the default one when empty
+	Format endpoint.LabelsFormat
+}
 
 // NewTXTRegistry returns new TXTRegistry object
-func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID string, cacheInterval time.Duration, txtWildcardReplacement string, managedRecordTypes []string, txtEncryptEnabled bool, txtEncryptAESKey []byte) (*TXTRegistry, error) {
-	if ownerID == "" {
+func NewTXTRegistry(provider provider.Provider, config TXTConfig) (*TXTRegistry, error) {
+	if config.OwnerID == "" {
 		return nil, errors.New("owner id cannot be empty")
 	}
+	txtEncryptAESKey := config.EncryptAESKey
 	if len(txtEncryptAESKey) == 0 {
 		txtEncryptAESKey = nil
 	} else if len(txtEncryptAESKey) != 32 {
 		return nil, errors.New("the AES Encryption key must have a length of 32 bytes")
 	}
-	if txtEncryptEnabled && txtEncryptAESKey == nil {
+	if config.EncryptEnabled && txtEncryptAESKey == nil {
 		return nil, errors.New("the AES Encryption key must be set when TXT record encryption is enabled")
 	}
+	for _, key := range config.DecryptAESKeys {
+		if len(key) != 32 {
+			return nil, errors.New("the AES Decryption keys must have a length of 32 bytes")
+		}
+	}
 
-	if len(txtPrefix) > 0 && len(txtSuffix) > 0 {
+	if len(config.Prefix) > 0 && len(config.Suffix) > 0 {
 		return nil, errors.New("txt-prefix and txt-suffix are mutual exclusive")
 	}
 
-	mapper := newaffixNameMapper(txtPrefix, txtSuffix, txtWildcardReplacement)
+	if config.ApexNameTemplate != "" && config.NameTemplate == "" {
+		return nil, errors.New("txt-apex-name-template requires txt-name-template")
+	}
+	txtFormat := config.Format
+	if txtFormat == "" {
+		txtFormat = endpoint.LabelsFormatDefault
+	}
+	if txtFormat != endpoint.LabelsFormatDefault && txtFormat != endpoint.LabelsFormatCompact {
+		return nil, fmt.Errorf("unknown TXT format: %s", txtFormat)
+	}
+
+	var mapper nameMapper = newaffixNameMapper(config.Prefix, config.Suffix, config.WildcardReplacement).withRecordTypes(config.ManagedRecordTypes)
+	if config.NameTemplate != "" {
+		var err error
+		// the prefix or suffix only name the TXT records to migrate to the template
+		mapper, err = newTemplateNameMapper(config.NameTemplate, config.ApexNameTemplate, config.OwnerID, config.WildcardReplacement, mapper.(affixNameMapper))
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &TXTRegistry{
 		provider:            provider,
-		ownerID:             ownerID,
+		ownerID:             config.OwnerID,
 		mapper:              mapper,
-		cacheInterval:       cacheInterval,
-		wildcardReplacement: txtWildcardReplacement,
-		managedRecordTypes:  managedRecordTypes,
-		txtEncryptEnabled:   txtEncryptEnabled,
+		txtFormat:           txtFormat,
+		wildcardReplacement: config.WildcardReplacement,
+		managedRecordTypes:  config.ManagedRecordTypes,
+		txtEncryptEnabled:   config.EncryptEnabled,
 		txtEncryptAESKey:    txtEncryptAESKey,
+		txtDecryptAESKeys:   config.DecryptAESKeys,
+		deleteOrphans:       config.DeleteOrphans,
 	}, nil
 }
 
 func getSupportedTypes() []string {
+	return append(getLegacyTypes(), getNewTypes()...)
+}
+
+// getLegacyTypes returns the types whose TXT records have always been named after the record type. The type prefix of
+// the other types is only recognized when they are managed or set by an explicit %{record_type}, so that the TXT record
+// in the old format of e.g. ptr-zone.example.com is not mistaken for the one of the PTR record zone.example.com.
+func getLegacyTypes() []string {
 	return []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS}
 }
 
+func getNewTypes() []string {
+	return []string{endpoint.RecordTypeMX, endpoint.RecordTypeSRV, endpoint.RecordTypeTXT, endpoint.RecordTypePTR}
+}
+
+// labelKey returns the key of the labels of a record. The records of the types which share their TXT record in the
+// legacy format, without record type, share the key of their name as well, the other types have a key of their own.
+func labelKey(endpointName, setIdentifier, recordType string) string {
+	key := fmt.Sprintf("%s::%s", endpointName, setIdentifier)
+	switch recordType {
+	case "", endpoint.RecordTypeA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS:
+		return key
+	}
+	return key + ":" + recordType
+}
+
 func (im *TXTRegistry) GetDomainFilter() endpoint.DomainFilterInterface {
 	return im.provider.GetDomainFilter()
 }
@@ -104,11 +240,13 @@ func (im *TXTRegistry) GetDomainFilter() endpoint.DomainFilterInterface {
 // If TXT records was created previously to indicate ownership its corresponding value
 // will be added to the endpoints Labels map
 func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	// If we have the zones cached AND we have refreshed the cache since the
-	// last given interval, then just use the cached results.
-	if im.recordsCache != nil && time.Since(im.recordsCacheRefreshTime) < im.cacheInterval {
-		log.Debug("Using cached records.")
-		return im.recordsCache, nil
+	if mapper, ok := im.mapper.(templateNameMapper); ok && mapper.apexTemplate != "" {
+		// the zones of the provider may change, the apexes are updated before the TXT records are named
+		apexes := mapper.updateApexes(im.provider.GetDomainFilter())
+		if apexes == 0 && !im.apexWarned {
+			log.Warn("The TXT apex name template is not used: the provider has no domain filter telling the zone apexes, set --domain-filter")
+		}
+		im.apexWarned = apexes == 0
 	}
 
 	records, err := im.provider.Records(ctx)
@@ -120,7 +258,10 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 	missingEndpoints := []*endpoint.Endpoint{}
 
 	labelMap := map[string]endpoint.Labels{}
+	legacyLabelMap := map[string]endpoint.Labels{}
+	sharedRecords := map[string]sharedTXTRecord{}
 	txtRecordsMap := map[string]struct{}{}
+	ownedTXTRecords := []ownedTXTRecord{}
 
 	for _, record := range records {
 		if record.RecordType != endpoint.RecordTypeTXT {
@@ -128,7 +269,7 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 			continue
 		}
 		// We simply assume that TXT records for the registry will always have only one target.
-		labels, err := endpoint.NewLabelsFromString(record.Targets[0], im.txtEncryptAESKey)
+		labels, format, retired, err := endpoint.NewLabelsFromStringWithFormat(record.Targets[0], im.txtEncryptAESKey, im.txtDecryptAESKeys)
 		if err == endpoint.ErrInvalidHeritage {
 			// if no heritage is found or it is invalid
 			// case when value of txt record cannot be identified
@@ -139,30 +280,37 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		if err != nil {
 			return nil, err
 		}
-		endpointName, isAAAA := im.mapper.toEndpointName(record.DNSName)
-		key := fmt.Sprintf("%s::%s", endpointName, record.SetIdentifier)
-		if isAAAA {
-			key += keySuffixAAAA
+		endpointName, recordType := im.mapper.toEndpointName(record.DNSName)
+		key := labelKey(endpointName, record.SetIdentifier, recordType)
+		if labels[endpoint.SharedLabelKey] == "true" {
+			sharedRecords[key] = im.parseSharedTXTRecord(record)
+			txtRecordsMap[record.DNSName] = struct{}{}
+			continue
 		}
 		labelMap[key] = labels
+		if recordType == "" {
+			legacyLabelMap[key] = labels
+		}
 		txtRecordsMap[record.DNSName] = struct{}{}
+		if labels[endpoint.OwnerLabelKey] == im.ownerID {
+			ownedTXTRecords = append(ownedTXTRecords, ownedTXTRecord{record: record, labels: labels, endpointName: endpointName, recordType: recordType, retired: retired, format: format})
+		}
 	}
 
 	for _, ep := range endpoints {
 		if ep.Labels == nil {
 			ep.Labels = endpoint.NewLabels()
 		}
-		dnsNameSplit := strings.Split(ep.DNSName, ".")
-		// If specified, replace a leading asterisk in the generated txt record name with some other string
-		if im.wildcardReplacement != "" && dnsNameSplit[0] == "*" {
-			dnsNameSplit[0] = im.wildcardReplacement
+		key := im.endpointLabelKey(ep)
+		labels, ok := labelMap[key]
+		if shared, found := sharedRecords[key]; found {
+			labels, ok = im.sharedRecordLabels(shared), true
+		} else if !ok && ep.RecordType != endpoint.RecordTypeAAAA && ep.RecordType != endpoint.RecordTypeTXT {
+			// the records of the other types created before their TXT record in the new format were read
+			// are owned by their TXT record in the legacy format, until the missing one is created
+			labels, ok = legacyLabelMap[im.endpointLabelKey(&endpoint.Endpoint{DNSName: ep.DNSName, SetIdentifier: ep.SetIdentifier})]
 		}
-		dnsName := strings.Join(dnsNameSplit, ".")
-		key := fmt.Sprintf("%s::%s", dnsName, ep.SetIdentifier)
-		if ep.RecordType == endpoint.RecordTypeAAAA {
-			key += keySuffixAAAA
-		}
-		if labels, ok := labelMap[key]; ok {
+		if ok {
 			for k, v := range labels {
 				ep.Labels[k] = v
 			}
@@ -180,22 +328,47 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 						missingDesiredTXTs = append(missingDesiredTXTs, desiredTXT)
 					}
 				}
-				if len(desiredTXTs) > len(missingDesiredTXTs) {
+				if len(desiredTXTs) > len(missingDesiredTXTs) || im.hasLegacyTXTRecord(ep, txtRecordsMap) || !im.mapper.legacyFormat() {
 					// Add missing TXT records only if those are managed (by externaldns) ones.
 					// The unmanaged record has both of the desired TXT records missing.
+					// With a name template, the records owned by a TXT record named with the prefix or suffix are migrated.
 					missingEndpoints = append(missingEndpoints, missingDesiredTXTs...)
 				}
 			}
 		}
 	}
 
-	// Update the cache.
-	if im.cacheInterval > 0 {
-		im.recordsCache = endpoints
-		im.recordsCacheRefreshTime = time.Now()
+	orphans := im.orphanedTXTRecords(endpoints, ownedTXTRecords)
+	orphanedRecords := []*endpoint.Endpoint{}
+	ownershipChanges := &plan.Changes{}
+	retired := 0
+	for _, txt := range ownedTXTRecords {
+		if orphans[txt.record] {
+			log.Debugf("TXT record %s of owner %q does not own any record", txt.record.DNSName, im.ownerID)
+			orphanedRecords = append(orphanedRecords, im.labelOwnedTXTRecord(txt.record, txt.endpointName))
+			if im.deleteOrphans {
+				ownershipChanges.Delete = append(ownershipChanges.Delete, txt.record)
+				continue
+			}
+		}
+		if txt.retired {
+			retired++
+		}
+		if txt.retired || txt.format != im.txtFormat {
+			im.addReencryption(ownershipChanges, txt.record, txt.labels, txt.endpointName)
+		}
 	}
 
 	im.missingTXTRecords = missingEndpoints
+	im.txtRecordNames = txtRecordsMap
+	im.sharedTXTRecords = sharedRecords
+	im.ownershipChanges = ownershipChanges
+	im.orphanedRecords = orphanedRecords
+	retiredKeyRecords.WithLabelValues(im.ownerID).Set(float64(retired))
+	orphanedTXTRecordsGauge.WithLabelValues(im.ownerID).Set(float64(len(orphanedRecords)))
+	if len(orphanedRecords) > 0 && !im.deleteOrphans {
+		log.Infof("%d TXT record(s) of owner %q do not own any record anymore, set --txt-delete-orphans to delete them", len(orphanedRecords), im.ownerID)
+	}
 
 	return endpoints, nil
 }
@@ -206,20 +379,208 @@ func (im *TXTRegistry) MissingRecords() []*endpoint.Endpoint {
 	return im.missingTXTRecords
 }
 
+// OwnershipChanges returns the updates of the TXT records of the owner which are encrypted with a retired key or
+// stored in another format, rewriting them with the current key and format, and the deletions of the orphaned TXT records of the owner when enabled.
+// The changes are collected during the run of Records method.
+func (im *TXTRegistry) OwnershipChanges() *plan.Changes {
+	return im.ownershipChanges
+}
+
+// OrphanedRecords returns the TXT records of the owner which do not own any record anymore, whether they are deleted
+// or not. The orphaned records are collected during the run of Records method.
+func (im *TXTRegistry) OrphanedRecords() []*endpoint.Endpoint {
+	return im.orphanedRecords
+}
+
+// serializeLabels serializes the labels stored in the TXT records, in the format of the registry
+func (im *TXTRegistry) serializeLabels(labels endpoint.Labels) string {
+	return labels.SerializeWithFormat(true, im.txtEncryptEnabled, im.txtEncryptAESKey, im.txtFormat)
+}
+
+// endpointLabelKey returns the key of the labels of the record
+func (im *TXTRegistry) endpointLabelKey(ep *endpoint.Endpoint) string {
+	dnsNameSplit := strings.Split(ep.DNSName, ".")
+	// If specified, replace a leading asterisk in the generated txt record name with some other string
+	if im.wildcardReplacement != "" && dnsNameSplit[0] == "*" {
+		dnsNameSplit[0] = im.wildcardReplacement
+	}
+	return labelKey(strings.Join(dnsNameSplit, "."), ep.SetIdentifier, ep.RecordType)
+}
+
+// parseSharedTXTRecord reads the ownership of every owner of a shared record from its TXT record
+func (im *TXTRegistry) parseSharedTXTRecord(record *endpoint.Endpoint) sharedTXTRecord {
+	shared := sharedTXTRecord{record: record, values: map[string]string{}, labels: map[string]endpoint.Labels{}}
+	for _, value := range record.Targets {
+		labels, _, err := endpoint.NewLabelsFromStringWithKeys(value, im.txtEncryptAESKey, im.txtDecryptAESKeys)
+		if err != nil {
+			log.Warnf("Ignoring an ownership of the shared record of TXT record %s: %v", record.DNSName, err)
+			continue
+		}
+		shared.values[labels[endpoint.OwnerLabelKey]] = value
+		shared.labels[labels[endpoint.OwnerLabelKey]] = labels
+	}
+	return shared
+}
+
+// sharedRecordLabels returns the labels of a shared record: the ones of the owner of the registry when it contributes
+// to the record, and of the first other owner otherwise, along with the targets contributed by the other owners.
+func (im *TXTRegistry) sharedRecordLabels(shared sharedTXTRecord) endpoint.Labels {
+	owners := make([]string, 0, len(shared.labels))
+	for owner := range shared.labels {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	own, contributes := shared.labels[im.ownerID]
+	if !contributes && len(owners) > 0 {
+		own = shared.labels[owners[0]]
+	}
+	labels := endpoint.NewLabels()
+	for k, v := range own {
+		if contributes || k != endpoint.TargetsLabelKey {
+			labels[k] = v
+		}
+	}
+
+	var others []string
+	for _, owner := range owners {
+		if owner != im.ownerID && shared.labels[owner][endpoint.TargetsLabelKey] != "" {
+			others = append(others, shared.labels[owner][endpoint.TargetsLabelKey])
+		}
+	}
+	labels[endpoint.SharedLabelKey] = "true"
+	labels[endpoint.SharedTargetsLabelKey] = strings.Join(others, endpoint.TargetsSeparator)
+	return labels
+}
+
+// addReencryption adds the update of a TXT record encrypted with a retired key or stored in another format to the changes
+func (im *TXTRegistry) addReencryption(changes *plan.Changes, record *endpoint.Endpoint, labels endpoint.Labels, endpointName string) {
+	txt := endpoint.NewEndpoint(record.DNSName, endpoint.RecordTypeTXT, im.serializeLabels(labels))
+	if txt == nil {
+		return
+	}
+	txt.WithSetIdentifier(record.SetIdentifier)
+	txt.RecordTTL = record.RecordTTL
+	txt.ProviderSpecific = record.ProviderSpecific
+	txt.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	txt.Labels[endpoint.OwnedRecordLabelKey] = endpointName
+
+	changes.UpdateOld = append(changes.UpdateOld, im.labelOwnedTXTRecord(record, endpointName))
+	changes.UpdateNew = append(changes.UpdateNew, txt)
+}
+
+// labelOwnedTXTRecord labels a TXT record read from the provider like the TXT records generated by the registry,
+// so that it is changed by ApplyChanges
+func (im *TXTRegistry) labelOwnedTXTRecord(record *endpoint.Endpoint, endpointName string) *endpoint.Endpoint {
+	if record.Labels == nil {
+		record.Labels = endpoint.NewLabels()
+	}
+	record.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	record.Labels[endpoint.OwnedRecordLabelKey] = endpointName
+	return record
+}
+
+// orphanedTXTRecords returns the TXT records of the owner which do not own any of the records. When the orphans are
+// deleted, the TXT records in the legacy format, or named without the name template, are orphaned as well, once all
+// the records of their name have a TXT record in the new format. The TXT records owning a record of a type which is
+// not managed are never orphaned, as the DNS provider might not return the records of that type, e.g. MX or PTR.
+func (im *TXTRegistry) orphanedTXTRecords(endpoints []*endpoint.Endpoint, txtRecords []ownedTXTRecord) map[*endpoint.Endpoint]bool {
+	txtKey := func(dnsName, setIdentifier string) string {
+		return strings.ToLower(dnsName) + "::" + setIdentifier
+	}
+	present := map[string]bool{}
+	for _, txt := range txtRecords {
+		present[txtKey(txt.record.DNSName, txt.record.SetIdentifier)] = true
+	}
+
+	expected := map[string]bool{}
+	// migrated tells for every previous TXT record name if the records of the name have all been migrated
+	migrated := map[string]bool{}
+	for _, ep := range endpoints {
+		key := txtKey(im.mapper.toNewTXTName(ep.DNSName, ep.RecordType), ep.SetIdentifier)
+		expected[key] = true
+		for _, name := range im.previousTXTNames(ep) {
+			previousKey := txtKey(name, ep.SetIdentifier)
+			if !im.deleteOrphans {
+				// the previous TXT records are kept along with the new ones
+				expected[previousKey] = true
+				continue
+			}
+			done, ok := migrated[previousKey]
+			migrated[previousKey] = (done || !ok) && present[key]
+		}
+	}
+
+	orphans := map[*endpoint.Endpoint]bool{}
+	for _, txt := range txtRecords {
+		key := txtKey(txt.record.DNSName, txt.record.SetIdentifier)
+		if expected[key] {
+			continue
+		}
+		recordType := txt.recordType
+		if recordType == "" {
+			// the name of an unmanaged type is not parsed, but it might be the one of its TXT record as well
+			_, recordType = extractRecordType(txt.endpointName, getNewTypes()...)
+		}
+		if recordType != "" && !plan.IsManagedRecord(recordType, im.managedRecordTypes) {
+			continue
+		}
+		if done, ok := migrated[key]; ok && !done {
+			continue
+		}
+		orphans[txt.record] = true
+	}
+	return orphans
+}
+
+// previousTXTNames returns the names of the TXT records of the record which are not generated anymore: the name in
+// the legacy format, and the names with the prefix or suffix when a name template is used
+func (im *TXTRegistry) previousTXTNames(ep *endpoint.Endpoint) []string {
+	var names []string
+	if im.hasLegacyFormat(ep.RecordType) {
+		names = append(names, im.mapper.toTXTName(ep.DNSName))
+	}
+	if mapper, ok := im.mapper.(templateNameMapper); ok {
+		names = append(names, mapper.fallback.toNewTXTName(ep.DNSName, ep.RecordType))
+		if ep.RecordType != endpoint.RecordTypeAAAA && ep.RecordType != endpoint.RecordTypeTXT {
+			names = append(names, mapper.fallback.toTXTName(ep.DNSName))
+		}
+	}
+	return names
+}
+
+// hasLegacyTXTRecord tells if the record has a TXT record in the legacy format, which is not generated anymore
+// when the orphaned TXT records are deleted
+func (im *TXTRegistry) hasLegacyTXTRecord(ep *endpoint.Endpoint, txtRecordsMap map[string]struct{}) bool {
+	if !im.deleteOrphans || !im.hasLegacyFormat(ep.RecordType) {
+		return false
+	}
+	_, exists := txtRecordsMap[im.mapper.toTXTName(ep.DNSName)]
+	return exists
+}
+
+// hasLegacyFormat tells if the records of the type have a TXT record in the legacy format as well. The TXT records
+// to manage have none, as the legacy name of their TXT record is their own name when there is no prefix nor suffix.
+func (im *TXTRegistry) hasLegacyFormat(recordType string) bool {
+	return im.mapper.legacyFormat() && recordType != endpoint.RecordTypeAAAA && recordType != endpoint.RecordTypeTXT
+}
+
 // generateTXTRecord generates both "old" and "new" TXT records.
 // Once we decide to drop old format we need to drop toTXTName() and rename toNewTXTName
 func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) []*endpoint.Endpoint {
 	// Missing TXT records are added to the set of changes.
 	// Obviously, we don't need any other TXT record for them.
-	if r.RecordType == endpoint.RecordTypeTXT {
+	if r.RecordType == endpoint.RecordTypeTXT && r.Labels[endpoint.OwnedRecordLabelKey] != "" {
 		return nil
 	}
 
 	endpoints := make([]*endpoint.Endpoint, 0)
 
-	if r.RecordType != endpoint.RecordTypeAAAA {
+	// the TXT records in the legacy format are deleted with the orphans, so they are not created anymore
+	// shared records have a single TXT record in the new format, holding the ownership of every owner
+	if im.hasLegacyFormat(r.RecordType) && !im.deleteOrphans && r.Labels[endpoint.SharedLabelKey] != "true" {
 		// old TXT record format
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true, im.txtEncryptEnabled, im.txtEncryptAESKey))
+		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, im.serializeLabels(r.Labels))
 		if txt != nil {
 			txt.WithSetIdentifier(r.SetIdentifier)
 			txt.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
@@ -228,7 +589,7 @@ func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) []*endpoint.Endpo
 		}
 	}
 	// new TXT record format (containing record type)
-	txtNew := endpoint.NewEndpoint(im.mapper.toNewTXTName(r.DNSName, r.RecordType), endpoint.RecordTypeTXT, r.Labels.Serialize(true, im.txtEncryptEnabled, im.txtEncryptAESKey))
+	txtNew := endpoint.NewEndpoint(im.mapper.toNewTXTName(r.DNSName, r.RecordType), endpoint.RecordTypeTXT, im.serializeLabels(r.Labels))
 	if txtNew != nil {
 		txtNew.WithSetIdentifier(r.SetIdentifier)
 		txtNew.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
@@ -239,13 +600,51 @@ func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) []*endpoint.Endpo
 	return endpoints
 }
 
+// currentTXTRecords returns the TXT records of a current record, which are read from the DNS provider for a shared record
+func (im *TXTRegistry) currentTXTRecords(r *endpoint.Endpoint) []*endpoint.Endpoint {
+	if shared, ok := im.sharedTXTRecords[im.endpointLabelKey(r)]; ok && r.Labels[endpoint.SharedLabelKey] == "true" {
+		return []*endpoint.Endpoint{shared.record}
+	}
+	return im.generateTXTRecord(r)
+}
+
+// desiredTXTRecords returns the TXT records of an updated record. The TXT record of a shared record keeps the
+// ownership of the other owners, and holds the one of the owner of the registry while it contributes targets.
+func (im *TXTRegistry) desiredTXTRecords(r *endpoint.Endpoint) []*endpoint.Endpoint {
+	if r.Labels[endpoint.SharedLabelKey] != "true" {
+		return im.generateTXTRecord(r)
+	}
+
+	var values []string
+	if shared, ok := im.sharedTXTRecords[im.endpointLabelKey(r)]; ok {
+		for owner, value := range shared.values {
+			if owner != im.ownerID {
+				values = append(values, value)
+			}
+		}
+	}
+	if r.Labels[endpoint.TargetsLabelKey] != "" {
+		values = append(values, im.serializeLabels(r.Labels))
+	}
+	sort.Strings(values)
+	txt := endpoint.NewEndpoint(im.mapper.toNewTXTName(r.DNSName, r.RecordType), endpoint.RecordTypeTXT, values...)
+	if txt == nil {
+		return nil
+	}
+	txt.WithSetIdentifier(r.SetIdentifier)
+	txt.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
+	txt.ProviderSpecific = r.ProviderSpecific
+	return []*endpoint.Endpoint{txt}
+}
+
 // ApplyChanges updates dns provider with the changes
 // for each created/deleted record it will also take into account TXT records for creation/deletion
 func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	updateOld, updateNew := filterOwnedUpdates(im.ownerID, changes.UpdateOld, changes.UpdateNew)
 	filteredChanges := &plan.Changes{
-		Create:    changes.Create,
-		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
-		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
+		Create:    im.filterTXTNameCollisions(changes.Create),
+		UpdateNew: updateNew,
+		UpdateOld: updateOld,
 		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
 	}
 	for _, r := range filteredChanges.Create {
@@ -255,48 +654,56 @@ func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes)
 		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
 
 		filteredChanges.Create = append(filteredChanges.Create, im.generateTXTRecord(r)...)
-
-		if im.cacheInterval > 0 {
-			im.addToCache(r)
-		}
 	}
 
 	for _, r := range filteredChanges.Delete {
 		// when we delete TXT records for which value has changed (due to new label) this would still work because
 		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
 		// !!! After migration to the new TXT registry format we can drop records in old format here!!!
-		filteredChanges.Delete = append(filteredChanges.Delete, im.generateTXTRecord(r)...)
-
-		if im.cacheInterval > 0 {
-			im.removeFromCache(r)
-		}
+		filteredChanges.Delete = append(filteredChanges.Delete, im.currentTXTRecords(r)...)
 	}
 
 	// make sure TXT records are consistently updated as well
 	for _, r := range filteredChanges.UpdateOld {
 		// when we updateOld TXT records for which value has changed (due to new label) this would still work because
 		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, im.generateTXTRecord(r)...)
-		// remove old version of record from cache
-		if im.cacheInterval > 0 {
-			im.removeFromCache(r)
-		}
+		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, im.currentTXTRecords(r)...)
 	}
 
 	// make sure TXT records are consistently updated as well
 	for _, r := range filteredChanges.UpdateNew {
-		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, im.generateTXTRecord(r)...)
-		// add new version of record to cache
-		if im.cacheInterval > 0 {
-			im.addToCache(r)
+		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, im.desiredTXTRecords(r)...)
+	}
+
+	return im.provider.ApplyChanges(ctx, filteredChanges)
+}
+
+// filterTXTNameCollisions filters out the TXT records to create whose name is the one of a TXT record of the registry.
+// Both would end up in the same TXT record set, where the ownership of the records cannot be told apart.
+func (im *TXTRegistry) filterTXTNameCollisions(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	names := map[string]bool{}
+	for name := range im.txtRecordNames {
+		names[strings.ToLower(name)] = true
+	}
+	for _, ep := range endpoints {
+		if ep.Labels[endpoint.OwnedRecordLabelKey] != "" {
+			names[strings.ToLower(ep.DNSName)] = true
+			continue
+		}
+		for _, txt := range im.generateTXTRecord(ep) {
+			names[strings.ToLower(txt.DNSName)] = true
 		}
 	}
 
-	// when caching is enabled, disable the provider from using the cache
-	if im.cacheInterval > 0 {
-		ctx = context.WithValue(ctx, provider.RecordsContextKey, nil)
+	filtered := []*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		if ep.RecordType == endpoint.RecordTypeTXT && ep.Labels[endpoint.OwnedRecordLabelKey] == "" && names[strings.ToLower(ep.DNSName)] {
+			log.Warnf("Skipping TXT record %s as its name is used by the TXT records of the registry", ep.DNSName)
+			continue
+		}
+		filtered = append(filtered, ep)
 	}
-	return im.provider.ApplyChanges(ctx, filteredChanges)
+	return filtered
 }
 
 // PropertyValuesEqual compares two attribute values for equality
@@ -319,15 +726,19 @@ func (im *TXTRegistry) AdjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoi
 */
 
 type nameMapper interface {
-	toEndpointName(string) (endpointName string, isAAAA bool)
+	toEndpointName(string) (endpointName, recordType string)
 	toTXTName(string) string
 	toNewTXTName(string, string) string
+	// legacyFormat tells whether the records have a TXT record in the legacy format, named by toTXTName, as well
+	legacyFormat() bool
 }
 
 type affixNameMapper struct {
 	prefix              string
 	suffix              string
 	wildcardReplacement string
+	// recordTypes are the types other than the legacy ones whose type prefix is recognized in the TXT record names
+	recordTypes []string
 }
 
 var _ nameMapper = affixNameMapper{}
@@ -336,9 +747,25 @@ func newaffixNameMapper(prefix, suffix, wildcardReplacement string) affixNameMap
 	return affixNameMapper{prefix: strings.ToLower(prefix), suffix: strings.ToLower(suffix), wildcardReplacement: strings.ToLower(wildcardReplacement)}
 }
 
-func extractRecordType(name string) (baseName, recordType string) {
+// withRecordTypes returns the mapper recognizing the type prefix of the TXT records of the managed types
+func (pr affixNameMapper) withRecordTypes(managedRecordTypes []string) affixNameMapper {
+	pr.recordTypes = nil
+	for _, t := range getNewTypes() {
+		if pr.recordTypeInAffix() || plan.IsManagedRecord(t, managedRecordTypes) {
+			pr.recordTypes = append(pr.recordTypes, t)
+		}
+	}
+	return pr
+}
+
+func (pr affixNameMapper) legacyFormat() bool {
+	return true
+}
+
+// extractRecordType splits the type prefix of the legacy types, or of the given types, from the name
+func extractRecordType(name string, recordTypes ...string) (baseName, recordType string) {
 	nameS := strings.Split(name, "-")
-	for _, t := range getSupportedTypes() {
+	for _, t := range append(getLegacyTypes(), recordTypes...) {
 		if nameS[0] == strings.ToLower(t) {
 			return strings.TrimPrefix(name, nameS[0]+"-"), t
 		}
@@ -386,20 +813,20 @@ func (pr affixNameMapper) isSuffix() bool {
 	return len(pr.prefix) == 0 && len(pr.suffix) > 0
 }
 
-func (pr affixNameMapper) toEndpointName(txtDNSName string) (endpointName string, isAAAA bool) {
-	lowerDNSName, recordType := extractRecordType(strings.ToLower(txtDNSName))
+func (pr affixNameMapper) toEndpointName(txtDNSName string) (endpointName, recordType string) {
+	lowerDNSName, recordType := extractRecordType(strings.ToLower(txtDNSName), pr.recordTypes...)
 
 	// drop prefix
 	if strings.HasPrefix(lowerDNSName, pr.prefix) && pr.isPrefix() {
-		return pr.dropAffix(lowerDNSName), recordType == endpoint.RecordTypeAAAA
+		return pr.dropAffix(lowerDNSName), recordType
 	}
 
 	// drop suffix
 	if pr.isSuffix() {
 		DNSName := strings.SplitN(lowerDNSName, ".", 2)
-		return pr.dropAffix(DNSName[0]) + "." + DNSName[1], recordType == endpoint.RecordTypeAAAA
+		return pr.dropAffix(DNSName[0]) + "." + DNSName[1], recordType
 	}
-	return "", false
+	return "", ""
 }
 
 func (pr affixNameMapper) toTXTName(endpointDNSName string) string {
@@ -459,23 +886,178 @@ func (pr affixNameMapper) toNewTXTName(endpointDNSName, recordType string) strin
 	return prefix + DNSName[0] + suffix + "." + DNSName[1]
 }
 
-func (im *TXTRegistry) addToCache(ep *endpoint.Endpoint) {
-	if im.recordsCache != nil {
-		im.recordsCache = append(im.recordsCache, ep)
+const (
+	ownerIDTemplate = "%{owner_id}"
+	labelTemplate   = "%{label}"
+	domainTemplate  = "%{domain}"
+	nameTemplate    = "%{name}"
+)
+
+var templatePlaceholder = regexp.MustCompile(`%\{[a-z_]+\}`)
+
+// templateNameMapper maps the records to the names of their TXT records with a name template, where:
+//   - %{record_type} is the lowercase type of the record,
+//   - %{owner_id} is the owner ID of the registry,
+//   - %{label} is the first label of the name of the record, and %{domain} the other labels,
+//   - %{name} is the name of the record.
+//
+// The records at a zone apex, whose name is one of the domains of the domain filter of the provider, use the apex
+// template when set, so that their TXT record stays in their zone. There are no TXT records in the legacy format, and the TXT
+// records named with the prefix or suffix are still read, so that their records are migrated to the template.
+type templateNameMapper struct {
+	template            string
+	apexTemplate        string
+	ownerID             string
+	wildcardReplacement string
+	apexes              map[string]bool
+	// patterns match the TXT record names of the apex template first, of the template then
+	patterns []*regexp.Regexp
+	// fallback reads the names of the TXT records named with the prefix or suffix, which are migrated to the template
+	fallback affixNameMapper
+}
+
+var _ nameMapper = templateNameMapper{}
+
+func newTemplateNameMapper(template, apexTemplate, ownerID, wildcardReplacement string, fallback affixNameMapper) (templateNameMapper, error) {
+	mapper := templateNameMapper{
+		template:            strings.ToLower(template),
+		apexTemplate:        strings.ToLower(apexTemplate),
+		ownerID:             strings.ToLower(ownerID),
+		wildcardReplacement: strings.ToLower(wildcardReplacement),
+		apexes:              map[string]bool{},
+		fallback:            fallback,
+	}
+
+	for _, t := range []string{mapper.apexTemplate, mapper.template} {
+		if t == "" {
+			continue
+		}
+		pattern, err := compileNameTemplate(t)
+		if err != nil {
+			return templateNameMapper{}, err
+		}
+		mapper.patterns = append(mapper.patterns, pattern)
 	}
+	return mapper, nil
 }
 
-func (im *TXTRegistry) removeFromCache(ep *endpoint.Endpoint) {
-	if im.recordsCache == nil || ep == nil {
-		// return early.
-		return
+// updateApexes updates the zone apexes with the domains of the domain filter and returns their number
+func (pr templateNameMapper) updateApexes(domainFilter endpoint.DomainFilterInterface) int {
+	var domains []string
+	switch df := domainFilter.(type) {
+	case endpoint.DomainFilter:
+		domains = df.Filters
+	case *endpoint.DomainFilter:
+		if df != nil {
+			domains = df.Filters
+		}
+	}
+	for apex := range pr.apexes {
+		delete(pr.apexes, apex)
+	}
+	for _, domain := range domains {
+		pr.apexes[strings.ToLower(strings.Trim(domain, "."))] = true
+	}
+	return len(pr.apexes)
+}
+
+// compileNameTemplate validates the name template and returns the regular expression matching the names it generates
+func compileNameTemplate(template string) (*regexp.Regexp, error) {
+	if !strings.Contains(template, recordTemplate) {
+		return nil, fmt.Errorf("the TXT name template %q must contain %s", template, recordTemplate)
+	}
+	if !strings.Contains(template, nameTemplate) && !(strings.Contains(template, labelTemplate) && strings.Contains(template, domainTemplate)) {
+		return nil, fmt.Errorf("the TXT name template %q must contain either %s or both %s and %s", template, nameTemplate, labelTemplate, domainTemplate)
+	}
+
+	types := make([]string, 0, len(getSupportedTypes()))
+	for _, t := range getSupportedTypes() {
+		types = append(types, strings.ToLower(t))
+	}
+	groups := map[string]string{
+		recordTemplate:  `(?P<record_type>` + strings.Join(types, "|") + `)`,
+		ownerIDTemplate: `(?P<owner_id>[^.]+?)`,
+		labelTemplate:   `(?P<label>[^.]+)`,
+		domainTemplate:  `(?P<domain>.+)`,
+		nameTemplate:    `(?P<name>.+)`,
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	used := map[string]bool{}
+	last := 0
+	for _, loc := range templatePlaceholder.FindAllStringIndex(template, -1) {
+		placeholder := template[loc[0]:loc[1]]
+		group, ok := groups[placeholder]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder %s in the TXT name template %q", placeholder, template)
+		}
+		if used[placeholder] {
+			return nil, fmt.Errorf("placeholder %s is used more than once in the TXT name template %q", placeholder, template)
+		}
+		used[placeholder] = true
+		expr.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		expr.WriteString(group)
+		last = loc[1]
 	}
+	expr.WriteString(regexp.QuoteMeta(template[last:]))
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+func (pr templateNameMapper) legacyFormat() bool {
+	return false
+}
 
-	for i, e := range im.recordsCache {
-		if e.DNSName == ep.DNSName && e.RecordType == ep.RecordType && e.SetIdentifier == ep.SetIdentifier && e.Targets.Same(ep.Targets) {
-			// We found a match delete the endpoint from the cache.
-			im.recordsCache = append(im.recordsCache[:i], im.recordsCache[i+1:]...)
-			return
+func (pr templateNameMapper) toEndpointName(txtDNSName string) (endpointName, recordType string) {
+	lowerDNSName := strings.ToLower(txtDNSName)
+	for _, pattern := range pr.patterns {
+		match := pattern.FindStringSubmatch(lowerDNSName)
+		if match == nil {
+			continue
+		}
+		parts := map[string]string{}
+		for i, name := range pattern.SubexpNames() {
+			parts[name] = match[i]
+		}
+		for _, t := range getSupportedTypes() {
+			if strings.ToLower(t) == parts["record_type"] {
+				recordType = t
+			}
+		}
+		if name, ok := parts["name"]; ok {
+			return name, recordType
 		}
+		return parts["label"] + "." + parts["domain"], recordType
+	}
+	return pr.fallback.toEndpointName(txtDNSName)
+}
+
+// toTXTName is only used for the TXT records in the legacy format, which the templates do not generate
+func (pr templateNameMapper) toTXTName(endpointDNSName string) string {
+	return pr.toNewTXTName(endpointDNSName, "")
+}
+
+func (pr templateNameMapper) toNewTXTName(endpointDNSName, recordType string) string {
+	DNSName := strings.SplitN(endpointDNSName, ".", 2)
+	// If specified, replace a leading asterisk in the generated txt record name with some other string
+	if pr.wildcardReplacement != "" && DNSName[0] == "*" {
+		DNSName[0] = pr.wildcardReplacement
+	}
+	label, domain := DNSName[0], ""
+	if len(DNSName) == 2 {
+		domain = DNSName[1]
+	}
+
+	template := pr.template
+	if pr.apexTemplate != "" && pr.apexes[strings.ToLower(strings.TrimSuffix(endpointDNSName, "."))] {
+		template = pr.apexTemplate
 	}
+	return strings.NewReplacer(
+		recordTemplate, strings.ToLower(recordType),
+		ownerIDTemplate, pr.ownerID,
+		labelTemplate, label,
+		domainTemplate, domain,
+		nameTemplate, strings.Join(DNSName, "."),
+	).Replace(template)
 }
@@ -18,11 +18,12 @@ package registry
 
 import (
 	"context"
-	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -46,20 +47,20 @@ func TestTXTRegistry(t *testing.T) {
 
 func testTXTRegistryNew(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
-	_, err := NewTXTRegistry(p, "txt", "", "", time.Hour, "", []string{}, false, nil)
+	_, err := NewTXTRegistry(p, TXTConfig{Prefix: "txt", ManagedRecordTypes: []string{}})
 	require.Error(t, err)
 
-	_, err = NewTXTRegistry(p, "", "txt", "", time.Hour, "", []string{}, false, nil)
+	_, err = NewTXTRegistry(p, TXTConfig{Suffix: "txt", ManagedRecordTypes: []string{}})
 	require.Error(t, err)
 
-	r, err := NewTXTRegistry(p, "txt", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, err := NewTXTRegistry(p, TXTConfig{Prefix: "txt", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	require.NoError(t, err)
 	assert.Equal(t, p, r.provider)
 
-	r, err = NewTXTRegistry(p, "", "txt", "owner", time.Hour, "", []string{}, false, nil)
+	r, err = NewTXTRegistry(p, TXTConfig{Suffix: "txt", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	require.NoError(t, err)
 
-	_, err = NewTXTRegistry(p, "txt", "txt", "owner", time.Hour, "", []string{}, false, nil)
+	_, err = NewTXTRegistry(p, TXTConfig{Prefix: "txt", Suffix: "txt", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	require.Error(t, err)
 
 	_, ok := r.mapper.(affixNameMapper)
@@ -68,20 +69,26 @@ func testTXTRegistryNew(t *testing.T) {
 	assert.Equal(t, p, r.provider)
 
 	aesKey := []byte(";k&l)nUC/33:{?d{3)54+,AD?]SX%yh^")
-	_, err = NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	_, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 	require.NoError(t, err)
 
-	_, err = NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, aesKey)
+	_, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptAESKey: aesKey})
 	require.NoError(t, err)
 
-	_, err = NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, true, nil)
+	_, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptEnabled: true})
 	require.Error(t, err)
 
-	r, err = NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, true, aesKey)
+	r, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptEnabled: true, EncryptAESKey: aesKey})
 	require.NoError(t, err)
 
 	_, ok = r.mapper.(affixNameMapper)
 	assert.True(t, ok)
+
+	_, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptEnabled: true, EncryptAESKey: aesKey, DecryptAESKeys: [][]byte{aesKey}})
+	require.NoError(t, err)
+
+	_, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptEnabled: true, EncryptAESKey: aesKey, DecryptAESKeys: [][]byte{[]byte("short")}})
+	require.Error(t, err)
 }
 
 func testTXTRegistryRecords(t *testing.T) {
@@ -213,13 +220,13 @@ func testTXTRegistryRecordsPrefixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "wc", []string{}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{Prefix: "txt.", OwnerID: "owner", WildcardReplacement: "wc", ManagedRecordTypes: []string{}})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "TxT.", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ = NewTXTRegistry(p, TXTConfig{Prefix: "TxT.", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -338,13 +345,13 @@ func testTXTRegistryRecordsSuffixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{Suffix: "-txt", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "", "-TxT", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ = NewTXTRegistry(p, TXTConfig{Suffix: "-TxT", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -439,7 +446,7 @@ func testTXTRegistryRecordsNoPrefix(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
@@ -482,7 +489,7 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 			newEndpointWithOwner("txt.cname-multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(provider.NewCachedProvider(p, time.Hour), TXTConfig{Prefix: "txt.", OwnerID: "owner", ManagedRecordTypes: []string{}})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -571,7 +578,7 @@ func testTXTRegistryApplyChangesWithTemplatedPrefix(t *testing.T) {
 	p.ApplyChanges(ctx, &plan.Changes{
 		Create: []*endpoint.Endpoint{},
 	})
-	r, _ := NewTXTRegistry(p, "prefix%{record_type}.", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(provider.NewCachedProvider(p, time.Hour), TXTConfig{Prefix: "prefix%{record_type}.", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
 			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress"),
@@ -615,7 +622,7 @@ func testTXTRegistryApplyChangesWithTemplatedSuffix(t *testing.T) {
 	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
 		assert.Equal(t, ctxEndpoints, ctx.Value(provider.RecordsContextKey))
 	}
-	r, _ := NewTXTRegistry(p, "", "-%{record_type}suffix", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(provider.NewCachedProvider(p, time.Hour), TXTConfig{Suffix: "-%{record_type}suffix", OwnerID: "owner", ManagedRecordTypes: []string{}})
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
 			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress"),
@@ -681,7 +688,7 @@ func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 			newEndpointWithOwner("cname-multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour, "wildcard", []string{}, false, nil)
+	r, _ := NewTXTRegistry(provider.NewCachedProvider(p, time.Hour), TXTConfig{Suffix: "-txt", OwnerID: "owner", WildcardReplacement: "wildcard", ManagedRecordTypes: []string{}})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -785,7 +792,7 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 			newEndpointWithOwner("cname-foobar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(provider.NewCachedProvider(p, time.Hour), TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -951,7 +958,7 @@ func testTXTRegistryMissingRecordsNoPrefix(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "wc", []string{endpoint.RecordTypeCNAME, endpoint.RecordTypeA, endpoint.RecordTypeNS}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", WildcardReplacement: "wc", ManagedRecordTypes: []string{endpoint.RecordTypeCNAME, endpoint.RecordTypeA, endpoint.RecordTypeNS}})
 	records, _ := r.Records(ctx)
 	missingRecords := r.MissingRecords()
 
@@ -1055,7 +1062,7 @@ func testTXTRegistryMissingRecordsWithPrefix(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "wc", []string{endpoint.RecordTypeCNAME, endpoint.RecordTypeA, endpoint.RecordTypeNS}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{Prefix: "txt.", OwnerID: "owner", WildcardReplacement: "wc", ManagedRecordTypes: []string{endpoint.RecordTypeCNAME, endpoint.RecordTypeA, endpoint.RecordTypeNS}})
 	records, _ := r.Records(ctx)
 	missingRecords := r.MissingRecords()
 
@@ -1063,73 +1070,6 @@ func testTXTRegistryMissingRecordsWithPrefix(t *testing.T) {
 	assert.True(t, testutils.SameEndpoints(missingRecords, expectedMissingRecords))
 }
 
-func TestCacheMethods(t *testing.T) {
-	cache := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-	}
-	registry := &TXTRegistry{
-		recordsCache:  cache,
-		cacheInterval: time.Hour,
-	}
-
-	expectedCacheAfterAdd := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "2001:DB8::1", "AAAA", "owner"),
-		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
-	}
-
-	expectedCacheAfterUpdate := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
-		newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"),
-		newEndpointWithOwner("thing4.com", "2001:DB8::2", "AAAA", "owner"),
-	}
-
-	expectedCacheAfterDelete := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
-	}
-	// test add cache
-	registry.addToCache(newEndpointWithOwner("thing4.com", "2001:DB8::1", "AAAA", "owner"))
-	registry.addToCache(newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"))
-
-	if !reflect.DeepEqual(expectedCacheAfterAdd, registry.recordsCache) {
-		t.Fatalf("expected endpoints should match endpoints from cache: expected %v, but got %v", expectedCacheAfterAdd, registry.recordsCache)
-	}
-
-	// test update cache
-	registry.removeFromCache(newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"))
-	registry.addToCache(newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"))
-	registry.removeFromCache(newEndpointWithOwner("thing4.com", "2001:DB8::1", "AAAA", "owner"))
-	registry.addToCache(newEndpointWithOwner("thing4.com", "2001:DB8::2", "AAAA", "owner"))
-	// ensure it was updated
-	if !reflect.DeepEqual(expectedCacheAfterUpdate, registry.recordsCache) {
-		t.Fatalf("expected endpoints should match endpoints from cache: expected %v, but got %v", expectedCacheAfterUpdate, registry.recordsCache)
-	}
-
-	// test deleting a record
-	registry.removeFromCache(newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"))
-	registry.removeFromCache(newEndpointWithOwner("thing4.com", "2001:DB8::2", "AAAA", "owner"))
-	// ensure it was deleted
-	if !reflect.DeepEqual(expectedCacheAfterDelete, registry.recordsCache) {
-		t.Fatalf("expected endpoints should match endpoints from cache: expected %v, but got %v", expectedCacheAfterDelete, registry.recordsCache)
-	}
-}
-
 func TestDropPrefix(t *testing.T) {
 	mapper := newaffixNameMapper("foo-%{record_type}-", "", "")
 	cnameRecord := "foo-cname-test.example.com"
@@ -1172,6 +1112,11 @@ func TestExtractRecordType(t *testing.T) {
 			expectedName: "ptr-zone.example.com",
 			expectedType: "",
 		},
+		{
+			input:        "mxzone.example.com",
+			expectedName: "mxzone.example.com",
+			expectedType: "",
+		},
 		{
 			input:        "zone.example.com",
 			expectedName: "zone.example.com",
@@ -1187,6 +1132,24 @@ func TestExtractRecordType(t *testing.T) {
 	}
 }
 
+func TestAffixNameMapperRecordTypes(t *testing.T) {
+	legacy := newaffixNameMapper("", "", "")
+	name, recordType := legacy.toEndpointName("mx-mail.example.com")
+	assert.Equal(t, "mx-mail.example.com", name)
+	assert.Equal(t, "", recordType)
+
+	managed := newaffixNameMapper("", "", "").withRecordTypes([]string{endpoint.RecordTypeA, endpoint.RecordTypeMX})
+	name, recordType = managed.toEndpointName("mx-mail.example.com")
+	assert.Equal(t, "mail.example.com", name)
+	assert.Equal(t, endpoint.RecordTypeMX, recordType)
+	name, recordType = managed.toEndpointName("ptr-zone.example.com")
+	assert.Equal(t, "ptr-zone.example.com", name)
+	assert.Equal(t, "", recordType)
+
+	templated := newaffixNameMapper("%{record_type}-", "", "").withRecordTypes([]string{endpoint.RecordTypeA})
+	assert.Contains(t, templated.recordTypes, endpoint.RecordTypePTR)
+}
+
 func TestNewTXTScheme(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
@@ -1209,7 +1172,7 @@ func TestNewTXTScheme(t *testing.T) {
 			newEndpointWithOwner("cname-foobar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(provider.NewCachedProvider(p, time.Hour), TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -1285,7 +1248,7 @@ func TestGenerateTXT(t *testing.T) {
 	}
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 	gotTXT := r.generateTXTRecord(record)
 	assert.Equal(t, expectedTXT, gotTXT)
 }
@@ -1304,7 +1267,7 @@ func TestGenerateTXTForAAAA(t *testing.T) {
 	}
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 	gotTXT := r.generateTXTRecord(record)
 	assert.Equal(t, expectedTXT, gotTXT)
 }
@@ -1321,17 +1284,554 @@ func TestFailGenerateTXT(t *testing.T) {
 	expectedTXT := []*endpoint.Endpoint{}
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, false, nil)
+	r, _ := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
 	gotTXT := r.generateTXTRecord(cnameRecord)
 	assert.Equal(t, expectedTXT, gotTXT)
 }
 
+func TestTXTRegistryAdoption(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	old, err := NewTXTRegistry(p, TXTConfig{OwnerID: "old", ManagedRecordTypes: []string{}})
+	require.NoError(t, err)
+	require.NoError(t, old.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "ingress/default/foo")},
+	}))
+
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
+	require.NoError(t, err)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	desired := newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "ingress/default/foo")
+	calculated := (&plan.Plan{
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		Current:        records,
+		Desired:        []*endpoint.Endpoint{desired},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        "owner",
+		AdoptOwnerIDs:  []string{"old"},
+	}).Calculate()
+	require.NoError(t, r.ApplyChanges(ctx, calculated.Changes))
+
+	// the TXT records are rewritten in place, with the new owner
+	providerRecords, err := p.Records(ctx)
+	require.NoError(t, err)
+	values := map[string]string{}
+	for _, record := range providerRecords {
+		values[record.RecordType+" "+record.DNSName] = record.Targets.String()
+	}
+	assert.Equal(t, map[string]string{
+		"A foo.test-zone.example.org":     "1.2.3.4",
+		"TXT foo.test-zone.example.org":   `"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/foo"`,
+		"TXT a-foo.test-zone.example.org": `"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/foo"`,
+	}, values)
+
+	// the previous owner does not consider the records as its own anymore
+	records, err = old.Records(ctx)
+	require.NoError(t, err)
+	for _, record := range records {
+		assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey])
+	}
+}
+
+func TestTXTRegistryKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey := []byte(";k&l)nUC/33:{?d{3)54+,AD?]SX%yh^")
+	newKey := []byte("0123456789abcdef0123456789abcdef")
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+
+	old, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptEnabled: true, EncryptAESKey: oldKey})
+	require.NoError(t, err)
+	require.NoError(t, old.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "ingress/default/foo")},
+	}))
+	other, err := NewTXTRegistry(p, TXTConfig{OwnerID: "other", ManagedRecordTypes: []string{}, EncryptEnabled: true, EncryptAESKey: oldKey})
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwnerResource("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "ingress/default/bar")},
+	}))
+
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, EncryptEnabled: true, EncryptAESKey: newKey, DecryptAESKeys: [][]byte{oldKey}})
+	require.NoError(t, err)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	owners := map[string]string{}
+	for _, record := range records {
+		owners[record.DNSName] = record.Labels[endpoint.OwnerLabelKey]
+	}
+	assert.Equal(t, map[string]string{"foo.test-zone.example.org": "owner", "bar.test-zone.example.org": "other"}, owners)
+
+	// only the TXT records of the owner are re-encrypted, the ones of other owners are left to them
+	changes := r.OwnershipChanges()
+	require.Len(t, changes.UpdateOld, 2)
+	require.Len(t, changes.UpdateNew, 2)
+	for _, txt := range changes.UpdateNew {
+		assert.Equal(t, endpoint.RecordTypeTXT, txt.RecordType)
+		assert.Equal(t, "foo.test-zone.example.org", txt.Labels[endpoint.OwnedRecordLabelKey])
+		labels, err := endpoint.NewLabelsFromString(txt.Targets[0], newKey)
+		require.NoError(t, err)
+		assert.Equal(t, "ingress/default/foo", labels[endpoint.ResourceLabelKey])
+	}
+	assert.Equal(t, 2.0, gaugeValue(t, retiredKeyRecords.WithLabelValues("owner")))
+	require.NoError(t, r.ApplyChanges(ctx, changes))
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, r.OwnershipChanges().UpdateNew)
+	assert.Equal(t, 0.0, gaugeValue(t, retiredKeyRecords.WithLabelValues("owner")))
+	for _, record := range records {
+		if record.DNSName == "foo.test-zone.example.org" {
+			assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+}
+
+func TestTXTRegistryDeleteOrphans(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	legacy, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}})
+	require.NoError(t, err)
+	require.NoError(t, legacy.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, ""),
+		},
+	}))
+	other, err := NewTXTRegistry(p, TXTConfig{OwnerID: "other", ManagedRecordTypes: []string{}})
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")},
+	}))
+	// the records are deleted out-of-band, and a record created before the new format has a TXT record in the legacy format only
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("old.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("old.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA}, DeleteOrphans: true})
+	require.NoError(t, err)
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	// the TXT record in the legacy format is kept until the record has a TXT record in the new format
+	assert.Equal(t, []string{"a-old.test-zone.example.org"}, dnsNames(r.MissingRecords()))
+	assert.ElementsMatch(t, []string{
+		"foo.test-zone.example.org",
+		"bar.test-zone.example.org",
+		"a-bar.test-zone.example.org",
+		"aaaa-bar.test-zone.example.org",
+	}, dnsNames(r.OwnershipChanges().Delete))
+	assert.Empty(t, r.OwnershipChanges().UpdateNew)
+
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Create: r.MissingRecords()}))
+	require.NoError(t, r.ApplyChanges(ctx, r.OwnershipChanges()))
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, r.MissingRecords())
+	assert.Equal(t, []string{"old.test-zone.example.org"}, dnsNames(r.OwnershipChanges().Delete))
+	require.NoError(t, r.ApplyChanges(ctx, r.OwnershipChanges()))
+
+	// the orphaned TXT records of other owners are left alone, and no TXT record in the legacy format is created anymore
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")},
+	}))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	var txtRecords []*endpoint.Endpoint
+	for _, record := range records {
+		if record.RecordType == endpoint.RecordTypeTXT {
+			txtRecords = append(txtRecords, record)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"a-foo.test-zone.example.org",
+		"a-old.test-zone.example.org",
+		"a-new.test-zone.example.org",
+		"baz.test-zone.example.org",
+		"a-baz.test-zone.example.org",
+	}, dnsNames(txtRecords))
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, r.OwnershipChanges().Delete)
+}
+
+func TestTXTRegistryOrphanedRecords(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+	// the record is deleted out-of-band
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")},
+	}))
+	// the TXT record of a MX record, which is not managed and might not be returned by the DNS provider
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("mx-mail.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "")},
+	}))
+
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	// the orphaned TXT records are listed but not deleted, and the TXT records in the legacy format are kept
+	assert.ElementsMatch(t, []string{"bar.test-zone.example.org", "a-bar.test-zone.example.org"}, dnsNames(r.OrphanedRecords()))
+	assert.Equal(t, 2.0, gaugeValue(t, orphanedTXTRecordsGauge.WithLabelValues("owner")))
+	for _, record := range r.OrphanedRecords() {
+		assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey])
+		assert.Equal(t, "bar.test-zone.example.org", record.Labels[endpoint.OwnedRecordLabelKey])
+	}
+	assert.Empty(t, r.OwnershipChanges().Delete)
+}
+
+func TestTXTRegistryRecordTypes(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	other, err := NewTXTRegistry(p, TXTConfig{OwnerID: "other", ManagedRecordTypes: []string{}})
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")},
+	}))
+	// a MX record created before it had a TXT record in the new format
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("old.test-zone.example.org", "10 mail.example.org", endpoint.RecordTypeMX, ""),
+			newEndpointWithOwner("old.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+
+	managed := []string{endpoint.RecordTypeA, endpoint.RecordTypeMX, endpoint.RecordTypeSRV, endpoint.RecordTypeTXT, endpoint.RecordTypePTR}
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: managed, DeleteOrphans: true})
+	require.NoError(t, err)
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "10 mail.example.org", endpoint.RecordTypeMX, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "\"v=spf1 -all\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("_sip._tcp.test-zone.example.org", "0 50 5060 sip.example.org", endpoint.RecordTypeSRV, ""),
+			newEndpointWithOwner("4.3.2.1.in-addr.test-zone.example.org", "foo.example.org", endpoint.RecordTypePTR, ""),
+			// the names of the TXT records owning the records are not available to the TXT records to manage
+			newEndpointWithOwner("bar.test-zone.example.org", "\"user\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("mx-foo.test-zone.example.org", "\"user\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	owners := map[string]string{}
+	for _, record := range records {
+		owners[record.RecordType+" "+record.DNSName] = record.Labels[endpoint.OwnerLabelKey]
+	}
+	assert.Equal(t, map[string]string{
+		"A bar.test-zone.example.org":               "other",
+		"A foo.test-zone.example.org":               "owner",
+		"MX foo.test-zone.example.org":              "owner",
+		"TXT foo.test-zone.example.org":             "owner",
+		"SRV _sip._tcp.test-zone.example.org":       "owner",
+		"PTR 4.3.2.1.in-addr.test-zone.example.org": "owner",
+		"MX old.test-zone.example.org":              "owner",
+	}, owners)
+	assert.Equal(t, []string{"mx-old.test-zone.example.org"}, dnsNames(r.MissingRecords()))
+
+	// every record has a TXT record of its own type, and the TXT record to manage no TXT record in the legacy format
+	var deleted []*endpoint.Endpoint
+	for _, record := range records {
+		if record.Labels[endpoint.OwnerLabelKey] == "owner" && record.DNSName != "old.test-zone.example.org" {
+			deleted = append(deleted, record)
+		}
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Delete: deleted}))
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"bar.test-zone.example.org",
+		"bar.test-zone.example.org",
+		"a-bar.test-zone.example.org",
+		"old.test-zone.example.org",
+		"old.test-zone.example.org",
+	}, dnsNames(records))
+}
+
+func TestTXTRegistrySharedRecords(t *testing.T) {
+	ctx := context.Background()
+	ownerA := "\"heritage=external-dns,external-dns/owner=a,external-dns/shared=true,external-dns/targets=1.1.1.1\""
+	ownerB := "\"heritage=external-dns,external-dns/owner=b,external-dns/shared=true,external-dns/targets=2.2.2.2\""
+	var applied *plan.Changes
+	newProvider := func() provider.Provider {
+		return newInMemoryProvider([]*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1", "2.2.2.2"),
+			endpoint.NewEndpoint("a-foo.test-zone.example.org", endpoint.RecordTypeTXT, ownerB, ownerA),
+		}, func(changes *plan.Changes) {
+			applied = changes
+		})
+	}
+
+	a, err := NewTXTRegistry(newProvider(), TXTConfig{OwnerID: "a", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+	records, err := a.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, endpoint.Labels{
+		endpoint.OwnerLabelKey:         "a",
+		endpoint.SharedLabelKey:        "true",
+		endpoint.TargetsLabelKey:       "1.1.1.1",
+		endpoint.SharedTargetsLabelKey: "2.2.2.2",
+	}, records[0].Labels)
+	assert.Empty(t, a.MissingRecords())
+
+	// the owner withdrawing from the record keeps the ownership of the other owner
+	withdrawn := records[0].DeepCopy()
+	withdrawn.Targets = endpoint.Targets{"2.2.2.2"}
+	withdrawn.Labels[endpoint.TargetsLabelKey] = ""
+	require.NoError(t, a.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{records[0]}, UpdateNew: []*endpoint.Endpoint{withdrawn}}))
+	require.Len(t, applied.UpdateNew, 2)
+	assert.Equal(t, endpoint.Targets{ownerB}, applied.UpdateNew[1].Targets)
+	assert.Equal(t, endpoint.Targets{ownerB, ownerA}, applied.UpdateOld[1].Targets)
+
+	// another owner joins the record
+	c, err := NewTXTRegistry(newProvider(), TXTConfig{OwnerID: "c", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+	records, err = c.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, endpoint.Labels{
+		endpoint.OwnerLabelKey:         "a",
+		endpoint.SharedLabelKey:        "true",
+		endpoint.SharedTargetsLabelKey: "1.1.1.1;2.2.2.2",
+	}, records[0].Labels)
+
+	joined := endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1", "2.2.2.2", "3.3.3.3")
+	joined.Labels[endpoint.OwnerLabelKey] = "c"
+	joined.Labels[endpoint.SharedLabelKey] = "true"
+	joined.Labels[endpoint.TargetsLabelKey] = "3.3.3.3"
+	require.NoError(t, c.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{records[0]}, UpdateNew: []*endpoint.Endpoint{joined}}))
+	require.Len(t, applied.UpdateNew, 2)
+	assert.Equal(t, endpoint.Targets{
+		ownerA,
+		ownerB,
+		"\"heritage=external-dns,external-dns/owner=c,external-dns/shared=true,external-dns/targets=3.3.3.3\"",
+	}, applied.UpdateNew[1].Targets)
+	assert.Equal(t, "a-foo.test-zone.example.org", applied.UpdateNew[1].DNSName)
+}
+
+func TestTXTRegistryFilterUpdates(t *testing.T) {
+	var applied *plan.Changes
+	p := newInMemoryProvider(nil, func(changes *plan.Changes) {
+		applied = changes
+	})
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "a", ManagedRecordTypes: []string{endpoint.RecordTypeA}})
+	require.NoError(t, err)
+
+	newRecord := func(dnsName, target, owner string, shared bool) *endpoint.Endpoint {
+		ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, target)
+		ep.Labels[endpoint.OwnerLabelKey] = owner
+		if shared {
+			ep.Labels[endpoint.SharedLabelKey] = "true"
+		}
+		return ep
+	}
+	changes := &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			// the record shared by another owner is only updated when the desired record is shared as well
+			newRecord("shared.test-zone.example.org", "1.1.1.1", "b", true),
+			newRecord("joined.test-zone.example.org", "1.1.1.1", "b", true),
+			newRecord("foreign.test-zone.example.org", "1.1.1.1", "b", false),
+			newRecord("owned.test-zone.example.org", "1.1.1.1", "a", false),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newRecord("shared.test-zone.example.org", "2.2.2.2", "a", false),
+			newRecord("joined.test-zone.example.org", "2.2.2.2", "a", true),
+			newRecord("foreign.test-zone.example.org", "2.2.2.2", "a", false),
+			newRecord("owned.test-zone.example.org", "2.2.2.2", "a", false),
+		},
+	}
+	require.NoError(t, r.ApplyChanges(context.Background(), changes))
+	require.Len(t, applied.UpdateOld, len(applied.UpdateNew))
+	for i := range applied.UpdateOld {
+		assert.Equal(t, applied.UpdateOld[i].DNSName, applied.UpdateNew[i].DNSName)
+		assert.Equal(t, applied.UpdateOld[i].RecordType, applied.UpdateNew[i].RecordType)
+	}
+	assert.ElementsMatch(t, []string{
+		"joined.test-zone.example.org",
+		"a-joined.test-zone.example.org",
+		"owned.test-zone.example.org",
+		"owned.test-zone.example.org",
+		"a-owned.test-zone.example.org",
+	}, dnsNames(applied.UpdateNew))
+}
+
+func TestTemplateNameMapper(t *testing.T) {
+	mapper, err := newTemplateNameMapper("%{record_type}-%{label}._owner-%{owner_id}.%{domain}", "_owner.%{record_type}.%{name}", "Owner", "wc", newaffixNameMapper("txt.", "", "wc"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, mapper.updateApexes(endpoint.NewDomainFilter([]string{"example.org."})))
+
+	for _, tc := range []struct {
+		name       string
+		recordType string
+		txtName    string
+	}{
+		{"foo.example.org", endpoint.RecordTypeA, "a-foo._owner-owner.example.org"},
+		{"foo.bar.example.org", endpoint.RecordTypeCNAME, "cname-foo._owner-owner.bar.example.org"},
+		{"*.example.org", endpoint.RecordTypeAAAA, "aaaa-wc._owner-owner.example.org"},
+		{"example.org", endpoint.RecordTypeMX, "_owner.mx.example.org"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			txtName := mapper.toNewTXTName(tc.name, tc.recordType)
+			assert.Equal(t, tc.txtName, txtName)
+			endpointName, recordType := mapper.toEndpointName(txtName)
+			assert.Equal(t, strings.Replace(tc.name, "*", "wc", 1), endpointName)
+			assert.Equal(t, tc.recordType, recordType)
+		})
+	}
+
+	// the TXT records named with the prefix are still read
+	endpointName, recordType := mapper.toEndpointName("txt.foo.example.org")
+	assert.Equal(t, "foo.example.org", endpointName)
+	assert.Equal(t, "", recordType)
+
+	for _, template := range []string{
+		"%{label}.%{domain}",
+		"%{record_type}-%{label}",
+		"%{record_type}-%{name}-%{zone}",
+		"%{record_type}-%{name}.%{name}",
+	} {
+		_, err := newTemplateNameMapper(template, "", "owner", "", affixNameMapper{})
+		assert.Error(t, err, template)
+	}
+}
+
+func TestTXTRegistryNameTemplate(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	prefixed, err := NewTXTRegistry(p, TXTConfig{Prefix: "txt.", OwnerID: "owner", ManagedRecordTypes: []string{}})
+	require.NoError(t, err)
+	require.NoError(t, prefixed.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")},
+	}))
+
+	managed := []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA}
+	r, err := NewTXTRegistry(p, TXTConfig{Prefix: "txt.", OwnerID: "owner", ManagedRecordTypes: managed, DeleteOrphans: true, NameTemplate: "_owner.%{record_type}.%{name}", Format: endpoint.LabelsFormatCompact})
+	require.NoError(t, err)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "owner", records[0].Labels[endpoint.OwnerLabelKey])
+	// the records owned by the TXT records named with the prefix are migrated to the template
+	require.Len(t, r.MissingRecords(), 1)
+	assert.Equal(t, "_owner.a.foo.test-zone.example.org", r.MissingRecords()[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"\"external-dns=v2,o=owner\""}, r.MissingRecords()[0].Targets)
+	// the TXT records named with the prefix are kept until their records are migrated
+	assert.Empty(t, r.OwnershipChanges().Delete)
+
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: append(r.MissingRecords(), newEndpointWithOwner("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "")),
+	}))
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, r.MissingRecords())
+	assert.ElementsMatch(t, []string{"txt.foo.test-zone.example.org", "txt.a-foo.test-zone.example.org"}, dnsNames(r.OwnershipChanges().Delete))
+	require.NoError(t, r.ApplyChanges(ctx, r.OwnershipChanges()))
+
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	var txtRecords []*endpoint.Endpoint
+	for _, record := range records {
+		if record.RecordType == endpoint.RecordTypeTXT {
+			txtRecords = append(txtRecords, record)
+		}
+	}
+	assert.ElementsMatch(t, []string{"_owner.a.foo.test-zone.example.org", "_owner.aaaa.bar.test-zone.example.org"}, dnsNames(txtRecords))
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	for _, record := range records {
+		assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey], record.DNSName)
+	}
+	assert.Empty(t, r.OwnershipChanges().Delete)
+}
+
+func TestTXTRegistryApexNameTemplateWithoutDomainFilter(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", NameTemplate: "%{record_type}-%{label}.%{domain}", ApexNameTemplate: "_owner.%{record_type}.%{name}"})
+	require.NoError(t, err)
+
+	// the provider has no domain filter to tell the zone apexes, which is reported once
+	_, err = r.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, r.apexWarned)
+	assert.Equal(t, "a-test-zone.example.org", r.mapper.toNewTXTName("test-zone.example.org", endpoint.RecordTypeA))
+}
+
+func TestTXTRegistryFormat(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone(testZone))
+	r, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, DeleteOrphans: true})
+	require.NoError(t, err)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "ingress/default/foo")},
+	}))
+
+	// the TXT records of the owner in the other format are rewritten
+	compact, err := NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, DeleteOrphans: true, Format: endpoint.LabelsFormatCompact})
+	require.NoError(t, err)
+	records, err := compact.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "ingress/default/foo", records[0].Labels[endpoint.ResourceLabelKey])
+	require.Len(t, compact.OwnershipChanges().UpdateNew, 1)
+	assert.Equal(t, endpoint.Targets{"\"external-dns=v2,o=owner,r=ingress/default/foo\""}, compact.OwnershipChanges().UpdateNew[0].Targets)
+	require.NoError(t, compact.ApplyChanges(ctx, compact.OwnershipChanges()))
+
+	records, err = compact.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "owner", records[0].Labels[endpoint.OwnerLabelKey])
+	assert.Empty(t, compact.OwnershipChanges().UpdateNew)
+
+	_, err = NewTXTRegistry(p, TXTConfig{OwnerID: "owner", ManagedRecordTypes: []string{}, DeleteOrphans: true, Format: "json"})
+	assert.Error(t, err)
+}
+
+func dnsNames(endpoints []*endpoint.Endpoint) []string {
+	names := []string{}
+	for _, ep := range endpoints {
+		names = append(names, ep.DNSName)
+	}
+	return names
+}
+
 /**
 
 helper methods
 
 */
 
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, gauge.Write(m))
+	return m.GetGauge().GetValue()
+}
+
 func newEndpointWithOwner(dnsName, target, recordType, ownerID string) *endpoint.Endpoint {
 	return newEndpointWithOwnerAndLabels(dnsName, target, recordType, ownerID, nil)
 }
@@ -248,6 +248,7 @@ func (sc *httpProxySource) setResourceLabel(httpProxy *projectcontour.HTTPProxy,
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("HTTPProxy/%s/%s", httpProxy.Namespace, httpProxy.Name)
 	}
+	setPlanningLabels(httpProxy, endpoints)
 }
 
 // endpointsFromHTTPProxyConfig extracts the endpoints from a Contour HTTPProxy object
@@ -303,3 +304,7 @@ func (sc *httpProxySource) AddEventHandler(ctx context.Context, handler func())
 	// https://github.com/kubernetes/kubernetes/issues/79610
 	sc.httpProxyInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
+
+func (sc *httpProxySource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "HTTPProxy", store: sc.httpProxyInformer.Informer().GetStore()}}
+}
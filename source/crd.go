@@ -27,6 +27,8 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -49,6 +51,9 @@ type crdSource struct {
 	annotationFilter string
 	labelSelector    labels.Selector
 	informer         *cache.SharedInformer
+	gvk              schema.GroupVersionKind
+	// listed holds the DNSEndpoints listed last, to resolve the references to them without informer
+	listed cache.Store
 }
 
 func addKnownTypes(scheme *runtime.Scheme, groupVersion schema.GroupVersion) error {
@@ -116,6 +121,8 @@ func NewCRDSource(crdClient rest.Interface, namespace, kind string, annotationFi
 		labelSelector:    labelSelector,
 		crdClient:        crdClient,
 		codec:            runtime.NewParameterCodec(scheme),
+		gvk:              crdClient.APIVersion().WithKind(kind),
+		listed:           cache.NewStore(cache.MetaNamespaceKeyFunc),
 	}
 	if startInformer {
 		// external-dns already runs its sync-handler periodically (controlled by `--interval` flag) to ensure any
@@ -159,6 +166,14 @@ func (cs *crdSource) AddEventHandler(ctx context.Context, handler func()) {
 	}
 }
 
+func (cs *crdSource) referenceStores() []referenceStore {
+	store := cs.listed
+	if cs.informer != nil {
+		store = (*cs.informer).GetStore()
+	}
+	return []referenceStore{{kind: "crd", gvk: cs.gvk, store: store}}
+}
+
 // Endpoints returns endpoint objects.
 func (cs *crdSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	endpoints := []*endpoint.Endpoint{}
@@ -179,24 +194,20 @@ func (cs *crdSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 		return nil, err
 	}
 
+	listed := make([]interface{}, 0, len(result.Items))
+	for i := range result.Items {
+		listed = append(listed, &result.Items[i])
+	}
+	if err := cs.listed.Replace(listed, ""); err != nil {
+		return nil, err
+	}
+
 	for _, dnsEndpoint := range result.Items {
 		// Make sure that all endpoints have targets for A or CNAME type
 		crdEndpoints := []*endpoint.Endpoint{}
 		for _, ep := range dnsEndpoint.Spec.Endpoints {
-			if (ep.RecordType == "CNAME" || ep.RecordType == "A" || ep.RecordType == "AAAA") && len(ep.Targets) < 1 {
-				log.Warnf("Endpoint %s with DNSName %s has an empty list of targets", dnsEndpoint.ObjectMeta.Name, ep.DNSName)
-				continue
-			}
-
-			illegalTarget := false
-			for _, target := range ep.Targets {
-				if strings.HasSuffix(target, ".") {
-					illegalTarget = true
-					break
-				}
-			}
-			if illegalTarget {
-				log.Warnf("Endpoint %s with DNSName %s has an illegal target. The subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com')", dnsEndpoint.ObjectMeta.Name, ep.DNSName)
+			if msg := invalidEndpointMessage(ep); msg != "" {
+				log.Warnf("Endpoint %s with DNSName %s %s", dnsEndpoint.ObjectMeta.Name, ep.DNSName, msg)
 				continue
 			}
 
@@ -209,26 +220,125 @@ func (cs *crdSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 
 		cs.setResourceLabel(&dnsEndpoint, crdEndpoints)
 		endpoints = append(endpoints, crdEndpoints...)
+	}
+
+	return endpoints, nil
+}
 
-		if dnsEndpoint.Status.ObservedGeneration == dnsEndpoint.Generation {
+// invalidEndpointMessage tells why the endpoint is invalid, it returns an empty string for valid endpoints
+func invalidEndpointMessage(ep *endpoint.Endpoint) string {
+	if (ep.RecordType == "CNAME" || ep.RecordType == "A" || ep.RecordType == "AAAA") && len(ep.Targets) < 1 {
+		return "has an empty list of targets"
+	}
+	for _, target := range ep.Targets {
+		if strings.HasSuffix(target, ".") {
+			return "has an illegal target. The subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com')"
+		}
+	}
+	return ""
+}
+
+// ReportStatus writes the outcome of the synchronization of their endpoints to the status of the DNSEndpoints
+func (cs *crdSource) ReportStatus(ctx context.Context, results []*endpoint.SyncResult) {
+	byResource := map[string][]*endpoint.SyncResult{}
+	for _, r := range results {
+		resource := r.Endpoint.Labels[endpoint.ResourceLabelKey]
+		if strings.HasPrefix(resource, "crd/") {
+			byResource[resource] = append(byResource[resource], r)
+		}
+	}
+
+	list, err := cs.List(ctx, &metav1.ListOptions{LabelSelector: cs.labelSelector.String()})
+	if err == nil {
+		list, err = cs.filterByAnnotations(list)
+	}
+	if err != nil {
+		log.Warnf("Could not list the CRDs to update their status: %v", err)
+		return
+	}
+
+	for i := range list.Items {
+		dnsEndpoint := &list.Items[i]
+		resource := fmt.Sprintf("crd/%s/%s", dnsEndpoint.Namespace, dnsEndpoint.Name)
+		status := dnsEndpointStatus(dnsEndpoint, byResource[resource])
+		if equality.Semantic.DeepEqual(status, dnsEndpoint.Status) {
 			continue
 		}
+		dnsEndpoint.Status = status
+		if _, err := cs.UpdateStatus(ctx, dnsEndpoint); err != nil {
+			log.Warnf("Could not update the status of the CRD %s/%s: %v", dnsEndpoint.Namespace, dnsEndpoint.Name, err)
+		}
+	}
+}
 
-		dnsEndpoint.Status.ObservedGeneration = dnsEndpoint.Generation
-		// Update the ObservedGeneration
-		_, err = cs.UpdateStatus(ctx, &dnsEndpoint)
-		if err != nil {
-			log.Warnf("Could not update ObservedGeneration of the CRD: %v", err)
+// dnsEndpointStatus returns the status of the DNSEndpoint given the outcome of the synchronization of its endpoints
+func dnsEndpointStatus(dnsEndpoint *endpoint.DNSEndpoint, results []*endpoint.SyncResult) endpoint.DNSEndpointStatus {
+	status := *dnsEndpoint.Status.DeepCopy()
+	status.ObservedGeneration = dnsEndpoint.Generation
+	status.Endpoints = nil
+
+	counts := map[endpoint.EndpointState]int{}
+	var invalid []string
+	for _, ep := range dnsEndpoint.Spec.Endpoints {
+		s := endpoint.EndpointStatus{DNSName: ep.DNSName, RecordType: ep.RecordType, SetIdentifier: ep.SetIdentifier}
+		if msg := invalidEndpointMessage(ep); msg != "" {
+			s.State, s.Message = endpoint.EndpointStateInvalid, msg
+			invalid = append(invalid, ep.DNSName)
+		} else if r := findSyncResult(ep, results); r != nil {
+			s.State, s.Message = r.State, r.Message
+		} else {
+			s.State, s.Message = endpoint.EndpointStateFiltered, "not part of the desired records"
 		}
+		counts[s.State]++
+		status.Endpoints = append(status.Endpoints, s)
 	}
 
-	return endpoints, nil
+	setCondition := func(conditionType string, ok bool, reason, message string) {
+		c := metav1.Condition{Type: conditionType, Status: metav1.ConditionFalse, Reason: reason, Message: message, ObservedGeneration: dnsEndpoint.Generation}
+		if ok {
+			c.Status = metav1.ConditionTrue
+		}
+		meta.SetStatusCondition(&status.Conditions, c)
+	}
+	if len(invalid) == 0 {
+		setCondition(endpoint.DNSEndpointAccepted, true, "Accepted", "All endpoints are valid")
+	} else {
+		setCondition(endpoint.DNSEndpointAccepted, false, "InvalidEndpoints", fmt.Sprintf("Invalid endpoints: %s", strings.Join(invalid, ", ")))
+	}
+	accepted := len(dnsEndpoint.Spec.Endpoints) - counts[endpoint.EndpointStateInvalid]
+	if counts[endpoint.EndpointStateProgrammed] == accepted {
+		setCondition(endpoint.DNSEndpointProgrammed, true, "Programmed", "All accepted endpoints are published")
+	} else {
+		setCondition(endpoint.DNSEndpointProgrammed, false, "NotProgrammed", fmt.Sprintf("%d of %d accepted endpoints are published, %d failed", counts[endpoint.EndpointStateProgrammed], accepted, counts[endpoint.EndpointStateFailed]))
+	}
+	if n := counts[endpoint.EndpointStateConflict]; n > 0 {
+		setCondition(endpoint.DNSEndpointConflict, true, "Conflict", fmt.Sprintf("%d endpoint(s) lost a conflict for their record", n))
+	} else {
+		setCondition(endpoint.DNSEndpointConflict, false, "NoConflict", "No endpoint lost a conflict for its record")
+	}
+	if n := counts[endpoint.EndpointStateFiltered]; n > 0 {
+		setCondition(endpoint.DNSEndpointFiltered, true, "Filtered", fmt.Sprintf("%d endpoint(s) are excluded by the domain filter or the managed record types", n))
+	} else {
+		setCondition(endpoint.DNSEndpointFiltered, false, "NotFiltered", "No endpoint is excluded")
+	}
+	return status
+}
+
+// findSyncResult returns the result of the synchronization of the given endpoint of a DNSEndpoint
+func findSyncResult(ep *endpoint.Endpoint, results []*endpoint.SyncResult) *endpoint.SyncResult {
+	for _, r := range results {
+		if r.Endpoint.DNSName == ep.DNSName && r.Endpoint.RecordType == ep.RecordType && r.Endpoint.SetIdentifier == ep.SetIdentifier {
+			return r
+		}
+	}
+	return nil
 }
 
 func (cs *crdSource) setResourceLabel(crd *endpoint.DNSEndpoint, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("crd/%s/%s", crd.ObjectMeta.Namespace, crd.ObjectMeta.Name)
 	}
+	setPlanningLabels(crd, endpoints)
 }
 
 func (cs *crdSource) watch(ctx context.Context, opts *metav1.ListOptions) (watch.Interface, error) {
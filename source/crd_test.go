@@ -26,6 +26,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -103,7 +104,7 @@ func fakeRESTClient(endpoints []*endpoint.Endpoint, apiVersion, kind, namespace,
 
 				var body endpoint.DNSEndpoint
 				decoder.Decode(&body)
-				dnsEndpoint.Status.ObservedGeneration = body.Status.ObservedGeneration
+				dnsEndpoint.Status = body.Status
 				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: objBody(codec, dnsEndpoint)}, nil
 			default:
 				return nil, fmt.Errorf("unexpected request: %#v\n%#v", req.URL, req)
@@ -118,6 +119,7 @@ func TestCRDSource(t *testing.T) {
 	suite.Run(t, new(CRDSuite))
 	t.Run("Interface", testCRDSourceImplementsSource)
 	t.Run("Endpoints", testCRDSourceEndpoints)
+	t.Run("ReportStatus", testCRDSourceReportStatus)
 }
 
 // testCRDSourceImplementsSource tests that crdSource is a valid Source.
@@ -427,6 +429,55 @@ func testCRDSourceEndpoints(t *testing.T) {
 	}
 }
 
+// testCRDSourceReportStatus tests that the outcome of the synchronization is written to the status.
+func testCRDSourceReportStatus(t *testing.T) {
+	apiVersion, kind := "test.k8s.io/v1alpha1", "DNSEndpoint"
+	programmed := &endpoint.Endpoint{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}
+	filtered := &endpoint.Endpoint{DNSName: "abc.example.com", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}
+	invalid := &endpoint.Endpoint{DNSName: "invalid.example.org", RecordType: endpoint.RecordTypeCNAME}
+	restClient := fakeRESTClient([]*endpoint.Endpoint{programmed, filtered, invalid}, apiVersion, kind, "foo", "test", nil, nil, t)
+
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	require.NoError(t, err)
+	scheme := runtime.NewScheme()
+	require.NoError(t, addKnownTypes(scheme, groupVersion))
+
+	src, err := NewCRDSource(restClient, "foo", kind, "", labels.Everything(), scheme, false)
+	require.NoError(t, err)
+	cs := src.(*crdSource)
+
+	eps, err := cs.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, eps, 2)
+	cs.ReportStatus(context.Background(), []*endpoint.SyncResult{
+		{Endpoint: eps[0], State: endpoint.EndpointStateProgrammed},
+		{Endpoint: eps[1], State: endpoint.EndpointStateFiltered, Message: "domain-filter"},
+		{Endpoint: &endpoint.Endpoint{DNSName: "abc.example.org", RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "crd/foo/other"}}, State: endpoint.EndpointStateFailed},
+	})
+
+	result, err := cs.List(context.Background(), &metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	status := result.Items[0].Status
+	assert.Equal(t, int64(1), status.ObservedGeneration)
+	assert.Equal(t, []endpoint.EndpointStatus{
+		{DNSName: "abc.example.org", RecordType: endpoint.RecordTypeA, State: endpoint.EndpointStateProgrammed},
+		{DNSName: "abc.example.com", RecordType: endpoint.RecordTypeA, State: endpoint.EndpointStateFiltered, Message: "domain-filter"},
+		{DNSName: "invalid.example.org", RecordType: endpoint.RecordTypeCNAME, State: endpoint.EndpointStateInvalid, Message: "has an empty list of targets"},
+	}, status.Endpoints)
+
+	conditions := map[string]metav1.ConditionStatus{}
+	for _, c := range status.Conditions {
+		conditions[c.Type] = c.Status
+	}
+	assert.Equal(t, map[string]metav1.ConditionStatus{
+		endpoint.DNSEndpointAccepted:   metav1.ConditionFalse,
+		endpoint.DNSEndpointProgrammed: metav1.ConditionFalse,
+		endpoint.DNSEndpointConflict:   metav1.ConditionFalse,
+		endpoint.DNSEndpointFiltered:   metav1.ConditionTrue,
+	}, conditions)
+}
+
 func validateCRDResource(t *testing.T, src Source, expectError bool) {
 	cs := src.(*crdSource)
 	result, err := cs.List(context.Background(), &metav1.ListOptions{})
@@ -436,9 +487,8 @@ func validateCRDResource(t *testing.T, src Source, expectError bool) {
 		require.NoErrorf(t, err, "Received err %v", err)
 	}
 
+	// the status is only written by ReportStatus, once the changes are applied
 	for _, dnsEndpoint := range result.Items {
-		if dnsEndpoint.Status.ObservedGeneration != dnsEndpoint.Generation {
-			require.Errorf(t, err, "Unexpected CRD resource result: ObservedGenerations <%v> is not equal to Generation<%v>", dnsEndpoint.Status.ObservedGeneration, dnsEndpoint.Generation)
-		}
+		assert.Zerof(t, dnsEndpoint.Status.ObservedGeneration, "Unexpected CRD resource result: ObservedGeneration <%v> set before the changes are applied", dnsEndpoint.Status.ObservedGeneration)
 	}
 }
@@ -142,6 +142,10 @@ func (vs *f5VirtualServerSource) AddEventHandler(ctx context.Context, handler fu
 	vs.virtualServerInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
 
+func (vs *f5VirtualServerSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "f5-virtualserver", store: vs.virtualServerInformer.Informer().GetStore()}}
+}
+
 // endpointsFromVirtualServers extracts the endpoints from a slice of VirtualServers
 func (vs *f5VirtualServerSource) endpointsFromVirtualServers(virtualServers []*f5.VirtualServer) ([]*endpoint.Endpoint, error) {
 	var endpoints []*endpoint.Endpoint
@@ -237,4 +241,5 @@ func (vs *f5VirtualServerSource) filterByAnnotations(virtualServers []*f5.Virtua
 
 func (vs *f5VirtualServerSource) setResourceLabel(virtualServer *f5.VirtualServer, ep *endpoint.Endpoint) {
 	ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("f5-virtualserver/%s/%s", virtualServer.Namespace, virtualServer.Name)
+	setPlanningLabels(virtualServer, []*endpoint.Endpoint{ep})
 }
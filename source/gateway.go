@@ -19,15 +19,19 @@ package source
 import (
 	"context"
 	"fmt"
+	"net"
 	"sort"
 	"strings"
 	"text/template"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
@@ -45,6 +49,20 @@ const (
 	gatewayKind  = "Gateway"
 )
 
+var gatewayRouteParents = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "source",
+		Name:      "gateway_route_parents",
+		Help:      "Number of parent Gateways of the Route, by whether they accept it and publish its hostnames.",
+	},
+	[]string{"kind", "namespace", "name", "accepted"},
+)
+
+func init() {
+	prometheus.MustRegister(gatewayRouteParents)
+}
+
 type gatewayRoute interface {
 	// Object returns the underlying route object to be used by templates.
 	Object() kubeObject
@@ -85,6 +103,7 @@ type gatewayRouteSource struct {
 	gwInformer  informers_v1b1.GatewayInformer
 
 	rtKind        string
+	rtGVK         schema.GroupVersionKind
 	rtNamespace   string
 	rtLabels      labels.Selector
 	rtAnnotations labels.Selector
@@ -97,7 +116,7 @@ type gatewayRouteSource struct {
 	ignoreHostnameAnnotation bool
 }
 
-func newGatewayRouteSource(clients ClientGenerator, config *Config, kind string, newInformerFn newGatewayRouteInformerFunc) (Source, error) {
+func newGatewayRouteSource(clients ClientGenerator, config *Config, gvk schema.GroupVersionKind, newInformerFn newGatewayRouteInformerFunc) (Source, error) {
 	ctx := context.TODO()
 
 	gwLabels, err := getLabelSelector(config.GatewayLabelFilter)
@@ -163,7 +182,8 @@ func newGatewayRouteSource(clients ClientGenerator, config *Config, kind string,
 		gwLabels:    gwLabels,
 		gwInformer:  gwInformer,
 
-		rtKind:        kind,
+		rtKind:        gvk.Kind,
+		rtGVK:         gvk,
 		rtNamespace:   config.Namespace,
 		rtLabels:      rtLabels,
 		rtAnnotations: rtAnnotations,
@@ -186,6 +206,10 @@ func (src *gatewayRouteSource) AddEventHandler(ctx context.Context, handler func
 	src.nsInformer.Informer().AddEventHandler(eventHandler)
 }
 
+func (src *gatewayRouteSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: strings.ToLower(src.rtKind), gvk: src.rtGVK, store: src.rtInformer.Informer().GetStore()}}
+}
+
 func (src *gatewayRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	var endpoints []*endpoint.Endpoint
 	routes, err := src.rtInformer.List(src.rtNamespace, src.rtLabels)
@@ -202,6 +226,8 @@ func (src *gatewayRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpo
 	}
 	kind := strings.ToLower(src.rtKind)
 	resolver := newGatewayRouteResolver(src, gateways, namespaces)
+	// The Routes which were deleted or are skipped since the last call must not be reported anymore.
+	gatewayRouteParents.DeletePartialMatch(prometheus.Labels{"kind": src.rtKind})
 	for _, rt := range routes {
 		// Filter by annotations.
 		meta := rt.Metadata()
@@ -239,6 +265,7 @@ func (src *gatewayRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpo
 			for _, ep := range eps {
 				ep.Labels[endpoint.ResourceLabelKey] = resourceKey
 			}
+			setPlanningLabels(meta, eps)
 			endpoints = append(endpoints, eps...)
 		}
 		log.Debugf("Endpoints generated from %s %s/%s: %v", src.rtKind, meta.Namespace, meta.Name, endpoints)
@@ -259,6 +286,9 @@ type gatewayRouteResolver struct {
 type gatewayListeners struct {
 	gateway   *v1beta1.Gateway
 	listeners map[v1beta1.SectionName][]v1beta1.Listener
+	// supportedKinds are the Route kinds supported by each Listener, as reported by the Gateway status.
+	supportedKinds map[v1beta1.SectionName][]v1beta1.RouteGroupKind
+	targets        endpoint.Targets
 }
 
 func newGatewayRouteResolver(src *gatewayRouteSource, gateways []*v1beta1.Gateway, namespaces []*corev1.Namespace) *gatewayRouteResolver {
@@ -270,9 +300,17 @@ func newGatewayRouteResolver(src *gatewayRouteSource, gateways []*v1beta1.Gatewa
 			lss[lis.Name] = gw.Spec.Listeners[i : i+1]
 		}
 		lss[""] = gw.Spec.Listeners
+		kinds := make(map[v1beta1.SectionName][]v1beta1.RouteGroupKind, len(gw.Status.Listeners))
+		for _, ls := range gw.Status.Listeners {
+			if len(ls.SupportedKinds) > 0 {
+				kinds[ls.Name] = ls.SupportedKinds
+			}
+		}
 		gws[namespacedName(gw.Namespace, gw.Name)] = gatewayListeners{
-			gateway:   gw,
-			listeners: lss,
+			gateway:        gw,
+			listeners:      lss,
+			supportedKinds: kinds,
+			targets:        gwAddressTargets(gw),
 		}
 	}
 	// Create Namespace lookup table.
@@ -293,6 +331,7 @@ func (c *gatewayRouteResolver) resolve(rt gatewayRoute) (map[string]endpoint.Tar
 		return nil, err
 	}
 	hostTargets := make(map[string]endpoint.Targets)
+	parents, accepted := 0, 0
 
 	meta := rt.Metadata()
 	for _, rps := range rt.RouteStatus().Parents {
@@ -304,6 +343,7 @@ func (c *gatewayRouteResolver) resolve(rt gatewayRoute) (map[string]endpoint.Tar
 			log.Debugf("Unsupported parent %s/%s for %s %s/%s", group, kind, c.src.rtKind, meta.Namespace, meta.Name)
 			continue
 		}
+		parents++
 		// Lookup the Gateway and its Listeners.
 		namespace := strVal((*string)(ref.Namespace), meta.Namespace)
 		gw, ok := c.gws[namespacedName(namespace, string(ref.Name))]
@@ -335,6 +375,11 @@ func (c *gatewayRouteResolver) resolve(rt gatewayRoute) (map[string]endpoint.Tar
 			if !c.routeIsAllowed(gw.gateway, lis, rt) {
 				continue
 			}
+			// Confirm that the Gateway supports the Route kind on the Listener.
+			if !gw.supportsKind(lis.Name, c.src.rtKind) {
+				log.Debugf("Gateway %s/%s section %q does not support %s %s/%s", namespace, ref.Name, lis.Name, c.src.rtKind, meta.Namespace, meta.Name)
+				continue
+			}
 			// Find all overlapping hostnames between the Route and Listener.
 			// For {TCP,UDP}Routes, all annotation-generated hostnames should match since the Listener doesn't specify a hostname.
 			// For {HTTP,TLS}Routes, hostnames (including any annotation-generated) will be required to match any Listeners specified hostname.
@@ -352,16 +397,20 @@ func (c *gatewayRouteResolver) resolve(rt gatewayRoute) (map[string]endpoint.Tar
 				if !ok {
 					continue
 				}
-				for _, addr := range gw.gateway.Status.Addresses {
-					hostTargets[host] = append(hostTargets[host], addr.Value)
+				if len(gw.targets) > 0 {
+					hostTargets[host] = append(hostTargets[host], gw.targets...)
 				}
 				match = true
 			}
 		}
 		if !match {
 			log.Debugf("Gateway %s/%s section %q does not match %s %s/%s hostnames %q", namespace, ref.Name, section, c.src.rtKind, meta.Namespace, meta.Name, rtHosts)
+			continue
 		}
+		accepted++
 	}
+	gatewayRouteParents.WithLabelValues(c.src.rtKind, meta.Namespace, meta.Name, "true").Set(float64(accepted))
+	gatewayRouteParents.WithLabelValues(c.src.rtKind, meta.Namespace, meta.Name, "false").Set(float64(parents - accepted))
 	// If a Gateway has multiple matching Listeners for the same host, then we'll
 	// add its IPs to the target list multiple times and should dedupe them.
 	for host, targets := range hostTargets {
@@ -434,8 +483,7 @@ func (c *gatewayRouteResolver) routeIsAllowed(gw *v1beta1.Gateway, lis *v1beta1.
 	}
 
 	// Check the route's kind, if any are specified by the listener.
-	// TODO: Do we need to consider SupportedKinds in the ListenerStatus instead of the Spec?
-	// We only support core kinds and already check the protocol... Does this matter at all?
+	// The kinds supported by the implementation are checked against the ListenerStatus separately.
 	if allow == nil || len(allow.Kinds) == 0 {
 		return true
 	}
@@ -449,6 +497,65 @@ func (c *gatewayRouteResolver) routeIsAllowed(gw *v1beta1.Gateway, lis *v1beta1.
 	return false
 }
 
+// supportsKind returns whether the Gateway supports the Route kind on the Listener. The Listeners whose status does not
+// report any supported kinds support the kinds allowed by their spec.
+func (gw gatewayListeners) supportsKind(section v1beta1.SectionName, kind string) bool {
+	kinds, ok := gw.supportedKinds[section]
+	if !ok {
+		return true
+	}
+	for _, gk := range kinds {
+		if strVal((*string)(gk.Group), gatewayGroup) == gatewayGroup && string(gk.Kind) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// gwAddressTargets returns the targets of the addresses of the Gateway status. The IP addresses and the hostnames are
+// published, the named addresses and the implementation-specific types are not resolvable and left out.
+func gwAddressTargets(gw *v1beta1.Gateway) endpoint.Targets {
+	var targets endpoint.Targets
+	for _, addr := range gw.Status.Addresses {
+		typ := v1beta1.IPAddressType
+		if addr.Type != nil {
+			typ = *addr.Type
+		}
+		switch typ {
+		case v1beta1.IPAddressType:
+			if net.ParseIP(addr.Value) == nil {
+				log.Debugf("Skipping invalid IP address %q of Gateway %s/%s", addr.Value, gw.Namespace, gw.Name)
+				continue
+			}
+		case v1beta1.HostnameAddressType:
+			if addr.Value == "" {
+				continue
+			}
+		case v1beta1.NamedAddressType:
+			// The named addresses are implementation-specific: only the ones naming an IP address or a fully
+			// qualified hostname are resolvable, the others, e.g. the names of address pools, are left out.
+			if !isResolvableName(addr.Value) {
+				log.Debugf("Skipping named address %q of Gateway %s/%s", addr.Value, gw.Namespace, gw.Name)
+				continue
+			}
+		default:
+			log.Debugf("Skipping address %q of type %s of Gateway %s/%s", addr.Value, typ, gw.Namespace, gw.Name)
+			continue
+		}
+		targets = append(targets, addr.Value)
+	}
+	return targets
+}
+
+// isResolvableName tells whether a named address of a Gateway is an IP address or a fully qualified hostname
+func isResolvableName(name string) bool {
+	if net.ParseIP(name) != nil {
+		return true
+	}
+	name = strings.TrimSuffix(name, ".")
+	return strings.Contains(name, ".") && len(validation.IsDNS1123Subdomain(name)) == 0
+}
+
 func gwRouteIsAccepted(conds []metav1.Condition) bool {
 	for _, c := range conds {
 		if v1beta1.RouteConditionType(c.Type) == v1beta1.RouteConditionAccepted {
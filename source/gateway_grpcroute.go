@@ -27,7 +27,7 @@ import (
 
 // NewGatewayGRPCRouteSource creates a new Gateway GRPCRoute source with the given config.
 func NewGatewayGRPCRouteSource(clients ClientGenerator, config *Config) (Source, error) {
-	return newGatewayRouteSource(clients, config, "GRPCRoute", func(factory informers.SharedInformerFactory) gatewayRouteInformer {
+	return newGatewayRouteSource(clients, config, v1alpha2.SchemeGroupVersion.WithKind("GRPCRoute"), func(factory informers.SharedInformerFactory) gatewayRouteInformer {
 		return &gatewayGRPCRouteInformer{factory.Gateway().V1alpha2().GRPCRoutes()}
 	})
 }
@@ -26,7 +26,7 @@ import (
 
 // NewGatewayHTTPRouteSource creates a new Gateway HTTPRoute source with the given config.
 func NewGatewayHTTPRouteSource(clients ClientGenerator, config *Config) (Source, error) {
-	return newGatewayRouteSource(clients, config, "HTTPRoute", func(factory informers.SharedInformerFactory) gatewayRouteInformer {
+	return newGatewayRouteSource(clients, config, v1beta1.SchemeGroupVersion.WithKind("HTTPRoute"), func(factory informers.SharedInformerFactory) gatewayRouteInformer {
 		return &gatewayHTTPRouteInformer{factory.Gateway().V1beta1().HTTPRoutes()}
 	})
 }
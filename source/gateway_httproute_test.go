@@ -20,6 +20,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -1002,6 +1004,170 @@ func TestGatewayHTTPRouteSourceEndpoints(t *testing.T) {
 				newTestEndpoint("foo.example.internal", "A", "1.2.3.4"),
 			},
 		},
+		{
+			title:      "GatewayAddressTypes",
+			config:     Config{},
+			namespaces: namespaces("default"),
+			gateways: []*v1beta1.Gateway{
+				{
+					ObjectMeta: objectMeta("default", "ips"),
+					Spec: v1beta1.GatewaySpec{
+						Listeners: []v1beta1.Listener{{Protocol: v1beta1.HTTPProtocolType}},
+					},
+					Status: v1beta1.GatewayStatus{
+						Addresses: []v1beta1.GatewayAddress{
+							// The IPAddress type is the default one.
+							{Value: "1.2.3.4"},
+							{Type: addressTypePtr(v1beta1.IPAddressType), Value: "2001:db8::1"},
+							{Type: addressTypePtr(v1beta1.IPAddressType), Value: "not-an-ip"},
+							{Type: addressTypePtr(v1beta1.NamedAddressType), Value: "my-address-pool"},
+							{Type: addressTypePtr("example.com/custom"), Value: "custom"},
+						},
+					},
+				},
+				{
+					ObjectMeta: objectMeta("default", "hostname"),
+					Spec: v1beta1.GatewaySpec{
+						Listeners: []v1beta1.Listener{{Protocol: v1beta1.HTTPProtocolType}},
+					},
+					Status: v1beta1.GatewayStatus{
+						Addresses: []v1beta1.GatewayAddress{
+							{Type: addressTypePtr(v1beta1.HostnameAddressType), Value: "lb.example.com"},
+						},
+					},
+				},
+				{
+					ObjectMeta: objectMeta("default", "named"),
+					Spec: v1beta1.GatewaySpec{
+						Listeners: []v1beta1.Listener{{Protocol: v1beta1.HTTPProtocolType}},
+					},
+					Status: v1beta1.GatewayStatus{
+						Addresses: []v1beta1.GatewayAddress{
+							// The named addresses naming an IP address or a hostname are resolvable.
+							{Type: addressTypePtr(v1beta1.NamedAddressType), Value: "10.0.0.1"},
+							{Type: addressTypePtr(v1beta1.NamedAddressType), Value: "pool.lb.example.com"},
+							{Type: addressTypePtr(v1beta1.NamedAddressType), Value: "my-address-pool"},
+						},
+					},
+				},
+			},
+			routes: []*v1beta1.HTTPRoute{
+				{
+					ObjectMeta: objectMeta("default", "ips"),
+					Spec: v1beta1.HTTPRouteSpec{
+						Hostnames: hostnames("ips.example.internal"),
+					},
+					Status: httpRouteStatus(gatewayParentRef("default", "ips")),
+				},
+				{
+					ObjectMeta: objectMeta("default", "hostname"),
+					Spec: v1beta1.HTTPRouteSpec{
+						Hostnames: hostnames("hostname.example.internal"),
+					},
+					Status: httpRouteStatus(gatewayParentRef("default", "hostname")),
+				},
+				{
+					ObjectMeta: objectMeta("default", "named"),
+					Spec: v1beta1.HTTPRouteSpec{
+						Hostnames: hostnames("named.example.internal"),
+					},
+					Status: httpRouteStatus(gatewayParentRef("default", "named")),
+				},
+			},
+			endpoints: []*endpoint.Endpoint{
+				newTestEndpoint("ips.example.internal", "A", "1.2.3.4"),
+				newTestEndpoint("ips.example.internal", "AAAA", "2001:db8::1"),
+				newTestEndpoint("hostname.example.internal", "CNAME", "lb.example.com"),
+				newTestEndpoint("named.example.internal", "A", "10.0.0.1"),
+				newTestEndpoint("named.example.internal", "CNAME", "pool.lb.example.com"),
+			},
+		},
+		{
+			title:      "AllowedRoutesKinds",
+			config:     Config{},
+			namespaces: namespaces("default"),
+			gateways: []*v1beta1.Gateway{{
+				ObjectMeta: objectMeta("default", "test"),
+				Spec: v1beta1.GatewaySpec{
+					Listeners: []v1beta1.Listener{
+						{
+							Name:     "http",
+							Protocol: v1beta1.HTTPProtocolType,
+							Hostname: hostnamePtr("http.example.internal"),
+							AllowedRoutes: &v1beta1.AllowedRoutes{
+								Kinds: []v1beta1.RouteGroupKind{{Kind: "HTTPRoute"}},
+							},
+						},
+						{
+							Name:     "grpc",
+							Protocol: v1beta1.HTTPProtocolType,
+							Hostname: hostnamePtr("grpc.example.internal"),
+							AllowedRoutes: &v1beta1.AllowedRoutes{
+								Kinds: []v1beta1.RouteGroupKind{{Kind: "GRPCRoute"}},
+							},
+						},
+					},
+				},
+				Status: gatewayStatus("1.2.3.4"),
+			}},
+			routes: []*v1beta1.HTTPRoute{{
+				ObjectMeta: objectMeta("default", "test"),
+				Spec: v1beta1.HTTPRouteSpec{
+					Hostnames: hostnames("*.example.internal"),
+				},
+				Status: httpRouteStatus(gatewayParentRef("default", "test")),
+			}},
+			endpoints: []*endpoint.Endpoint{
+				newTestEndpoint("http.example.internal", "A", "1.2.3.4"),
+			},
+		},
+		{
+			title:      "ListenerStatusSupportedKinds",
+			config:     Config{},
+			namespaces: namespaces("default"),
+			gateways: []*v1beta1.Gateway{{
+				ObjectMeta: objectMeta("default", "test"),
+				Spec: v1beta1.GatewaySpec{
+					Listeners: []v1beta1.Listener{
+						{
+							Name:     "foo",
+							Protocol: v1beta1.HTTPProtocolType,
+							Hostname: hostnamePtr("foo.example.internal"),
+						},
+						{
+							Name:     "bar",
+							Protocol: v1beta1.HTTPProtocolType,
+							Hostname: hostnamePtr("bar.example.internal"),
+						},
+						{
+							Name:     "baz",
+							Protocol: v1beta1.HTTPProtocolType,
+							Hostname: hostnamePtr("baz.example.internal"),
+						},
+					},
+				},
+				Status: v1beta1.GatewayStatus{
+					Addresses: gatewayStatus("1.2.3.4").Addresses,
+					Listeners: []v1beta1.ListenerStatus{
+						{Name: "foo", SupportedKinds: []v1beta1.RouteGroupKind{{Kind: "HTTPRoute"}}},
+						{Name: "bar", SupportedKinds: []v1beta1.RouteGroupKind{{Kind: "GRPCRoute"}}},
+						// A Listener without supported kinds in its status supports the kinds of its spec.
+						{Name: "baz"},
+					},
+				},
+			}},
+			routes: []*v1beta1.HTTPRoute{{
+				ObjectMeta: objectMeta("default", "test"),
+				Spec: v1beta1.HTTPRouteSpec{
+					Hostnames: hostnames("*.example.internal"),
+				},
+				Status: httpRouteStatus(gatewayParentRef("default", "test")),
+			}},
+			endpoints: []*endpoint.Endpoint{
+				newTestEndpoint("foo.example.internal", "A", "1.2.3.4"),
+				newTestEndpoint("baz.example.internal", "A", "1.2.3.4"),
+			},
+		},
 		{
 			title:      "MissingNamespace",
 			config:     Config{},
@@ -1070,4 +1236,69 @@ func TestGatewayHTTPRouteSourceEndpoints(t *testing.T) {
 	}
 }
 
+func TestGatewayHTTPRouteParentsMetric(t *testing.T) {
+	ctx := context.Background()
+	gwClient := gatewayfake.NewSimpleClientset()
+	gateways := []*v1beta1.Gateway{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "http"},
+			Spec: v1beta1.GatewaySpec{
+				Listeners: []v1beta1.Listener{{Protocol: v1beta1.HTTPProtocolType}},
+			},
+			Status: gatewayStatus("1.2.3.4"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tcp"},
+			Spec: v1beta1.GatewaySpec{
+				Listeners: []v1beta1.Listener{{Protocol: v1beta1.TCPProtocolType}},
+			},
+			Status: gatewayStatus("2.3.4.5"),
+		},
+	}
+	for _, gw := range gateways {
+		_, err := gwClient.GatewayV1beta1().Gateways(gw.Namespace).Create(ctx, gw, metav1.CreateOptions{})
+		require.NoError(t, err, "failed to create Gateway")
+	}
+	rt := &v1beta1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "parents"},
+		Spec: v1beta1.HTTPRouteSpec{
+			Hostnames: []v1beta1.Hostname{"parents.example.internal"},
+		},
+		// The tcp Gateway has no Listener for the Route, and the missing one does not exist.
+		Status: httpRouteStatus(
+			gatewayParentRef("default", "http"),
+			gatewayParentRef("default", "tcp"),
+			gatewayParentRef("default", "missing"),
+		),
+	}
+	_, err := gwClient.GatewayV1beta1().HTTPRoutes(rt.Namespace).Create(ctx, rt, metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create HTTPRoute")
+	kubeClient := kubefake.NewSimpleClientset()
+	_, err = kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}, metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create Namespace")
+
+	clients := new(MockClientGenerator)
+	clients.On("GatewayClient").Return(gwClient, nil)
+	clients.On("KubeClient").Return(kubeClient, nil)
+
+	src, err := NewGatewayHTTPRouteSource(clients, &Config{})
+	require.NoError(t, err, "failed to create Gateway HTTPRoute Source")
+
+	endpoints, err := src.Endpoints(ctx)
+	require.NoError(t, err, "failed to get Endpoints")
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		newTestEndpoint("parents.example.internal", "A", "1.2.3.4"),
+	})
+	require.Equal(t, 1.0, gaugeVecValue(t, gatewayRouteParents, "HTTPRoute", "default", "parents", "true"))
+	require.Equal(t, 2.0, gaugeVecValue(t, gatewayRouteParents, "HTTPRoute", "default", "parents", "false"))
+}
+
+func gaugeVecValue(t *testing.T, vec *prometheus.GaugeVec, values ...string) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, vec.WithLabelValues(values...).Write(m))
+	return m.GetGauge().GetValue()
+}
+
 func hostnamePtr(val v1beta1.Hostname) *v1beta1.Hostname { return &val }
+
+func addressTypePtr(val v1beta1.AddressType) *v1beta1.AddressType { return &val }
@@ -27,7 +27,7 @@ import (
 
 // NewGatewayUDPRouteSource creates a new Gateway UDPRoute source with the given config.
 func NewGatewayUDPRouteSource(clients ClientGenerator, config *Config) (Source, error) {
-	return newGatewayRouteSource(clients, config, "UDPRoute", func(factory informers.SharedInformerFactory) gatewayRouteInformer {
+	return newGatewayRouteSource(clients, config, v1alpha2.SchemeGroupVersion.WithKind("UDPRoute"), func(factory informers.SharedInformerFactory) gatewayRouteInformer {
 		return &gatewayUDPRouteInformer{factory.Gateway().V1alpha2().UDPRoutes()}
 	})
 }
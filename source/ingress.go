@@ -284,6 +284,7 @@ func (sc *ingressSource) setResourceLabel(ingress *networkv1.Ingress, endpoints
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("ingress/%s/%s", ingress.Namespace, ingress.Name)
 	}
+	setPlanningLabels(ingress, endpoints)
 }
 
 func (sc *ingressSource) setDualstackLabel(ingress *networkv1.Ingress, endpoints []*endpoint.Endpoint) {
@@ -382,3 +383,7 @@ func (sc *ingressSource) AddEventHandler(ctx context.Context, handler func()) {
 	// https://github.com/kubernetes/kubernetes/issues/79610
 	sc.ingressInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
+
+func (sc *ingressSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "ingress", gvk: networkv1.SchemeGroupVersion.WithKind("Ingress"), store: sc.ingressInformer.Informer().GetStore()}}
+}
@@ -195,6 +195,10 @@ func (sc *gatewaySource) AddEventHandler(ctx context.Context, handler func()) {
 	sc.gatewayInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
 
+func (sc *gatewaySource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "gateway", gvk: networkingv1alpha3.SchemeGroupVersion.WithKind("Gateway"), store: sc.gatewayInformer.Informer().GetStore()}}
+}
+
 // filterByAnnotations filters a list of configs by a given annotation selector.
 func (sc *gatewaySource) filterByAnnotations(gateways []networkingv1alpha3.Gateway) ([]networkingv1alpha3.Gateway, error) {
 	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
@@ -230,6 +234,7 @@ func (sc *gatewaySource) setResourceLabel(gateway networkingv1alpha3.Gateway, en
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("gateway/%s/%s", gateway.Namespace, gateway.Name)
 	}
+	setPlanningLabels(&gateway, endpoints)
 }
 
 func (sc *gatewaySource) targetsFromGateway(gateway networkingv1alpha3.Gateway) (targets endpoint.Targets, err error) {
@@ -187,6 +187,10 @@ func (sc *virtualServiceSource) AddEventHandler(ctx context.Context, handler fun
 	sc.virtualserviceInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
 
+func (sc *virtualServiceSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "virtualservice", gvk: networkingv1alpha3.SchemeGroupVersion.WithKind("VirtualService"), store: sc.virtualserviceInformer.Informer().GetStore()}}
+}
+
 func (sc *virtualServiceSource) getGateway(ctx context.Context, gatewayStr string, virtualService *networkingv1alpha3.VirtualService) (*networkingv1alpha3.Gateway, error) {
 	if gatewayStr == "" || gatewayStr == IstioMeshGateway {
 		// This refers to "all sidecars in the mesh"; ignore.
@@ -274,6 +278,7 @@ func (sc *virtualServiceSource) setResourceLabel(virtualservice *networkingv1alp
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("virtualservice/%s/%s", virtualservice.Namespace, virtualservice.Name)
 	}
+	setPlanningLabels(virtualservice, endpoints)
 }
 
 // append a target to the list of targets unless it's already in the list
@@ -193,6 +193,7 @@ func (sc *kongTCPIngressSource) setResourceLabel(tcpIngress *TCPIngress, endpoin
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("tcpingress/%s/%s", tcpIngress.Namespace, tcpIngress.Name)
 	}
+	setPlanningLabels(tcpIngress, endpoints)
 }
 
 func (sc *kongTCPIngressSource) setDualstackLabel(tcpIngress *TCPIngress, endpoints []*endpoint.Endpoint) {
@@ -240,6 +241,10 @@ func (sc *kongTCPIngressSource) AddEventHandler(ctx context.Context, handler fun
 	sc.kongTCPIngressInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
 
+func (sc *kongTCPIngressSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "tcpingress", store: sc.kongTCPIngressInformer.Informer().GetStore()}}
+}
+
 // newUnstructuredConverter returns a new unstructuredConverter initialized
 func newKongUnstructuredConverter() (*unstructuredConverter, error) {
 	uc := &unstructuredConverter{
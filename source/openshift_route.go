@@ -110,6 +110,10 @@ func (ors *ocpRouteSource) AddEventHandler(ctx context.Context, handler func())
 	ors.routeInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
 
+func (ors *ocpRouteSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "route", gvk: routev1.SchemeGroupVersion.WithKind("Route"), store: ors.routeInformer.Informer().GetStore()}}
+}
+
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
 // Retrieves all OpenShift Route resources on all namespaces, unless an explicit namespace
 // is specified in ocpRouteSource.
@@ -228,6 +232,7 @@ func (ors *ocpRouteSource) setResourceLabel(ocpRoute *routev1.Route, endpoints [
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("route/%s/%s", ocpRoute.Namespace, ocpRoute.Name)
 	}
+	setPlanningLabels(ocpRoute, endpoints)
 }
 
 // endpointsFromOcpRoute extracts the endpoints from a OpenShift Route object
@@ -18,11 +18,15 @@ package source
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"text/template"
 
 	"sigs.k8s.io/external-dns/endpoint"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
@@ -31,15 +35,34 @@ import (
 )
 
 type podSource struct {
-	client        kubernetes.Interface
-	namespace     string
-	podInformer   coreinformers.PodInformer
-	nodeInformer  coreinformers.NodeInformer
-	compatibility string
+	client                         kubernetes.Interface
+	namespace                      string
+	annotationFilter               string
+	fqdnTemplate                   *template.Template
+	combineFQDNAnnotation          bool
+	ignoreHostnameAnnotation       bool
+	labelSelector                  labels.Selector
+	publishPodIPs                  bool
+	namespaceSelector              labels.Selector
+	alwaysPublishNotReadyAddresses bool
+	podInformer                    coreinformers.PodInformer
+	nodeInformer                   coreinformers.NodeInformer
+	namespaceInformer              coreinformers.NamespaceInformer
+	compatibility                  string
 }
 
 // NewPodSource creates a new podSource with the given config.
-func NewPodSource(ctx context.Context, kubeClient kubernetes.Interface, namespace string, compatibility string) (Source, error) {
+func NewPodSource(ctx context.Context, kubeClient kubernetes.Interface, namespace, annotationFilter, fqdnTemplate string, combineFqdnAnnotation, ignoreHostnameAnnotation bool, compatibility string, labelSelector labels.Selector, publishPodIPs bool, namespaceLabelFilter string, alwaysPublishNotReadyAddresses bool) (Source, error) {
+	tmpl, err := parseTemplate(fqdnTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceSelector, err := labels.Parse(namespaceLabelFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the pod namespace label filter %q: %w", namespaceLabelFilter, err)
+	}
+
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
 	podInformer := informerFactory.Core().V1().Pods()
 	nodeInformer := informerFactory.Core().V1().Nodes()
@@ -57,6 +80,18 @@ func NewPodSource(ctx context.Context, kubeClient kubernetes.Interface, namespac
 		},
 	)
 
+	// the namespaces are only watched to select the ones whose pods are published with their pod IPs
+	var namespaceInformer coreinformers.NamespaceInformer
+	if publishPodIPs && !namespaceSelector.Empty() {
+		namespaceInformer = informerFactory.Core().V1().Namespaces()
+		namespaceInformer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -65,68 +100,135 @@ func NewPodSource(ctx context.Context, kubeClient kubernetes.Interface, namespac
 	}
 
 	return &podSource{
-		client:        kubeClient,
-		podInformer:   podInformer,
-		nodeInformer:  nodeInformer,
-		namespace:     namespace,
-		compatibility: compatibility,
+		client:                         kubeClient,
+		podInformer:                    podInformer,
+		nodeInformer:                   nodeInformer,
+		namespaceInformer:              namespaceInformer,
+		namespace:                      namespace,
+		annotationFilter:               annotationFilter,
+		fqdnTemplate:                   tmpl,
+		combineFQDNAnnotation:          combineFqdnAnnotation,
+		ignoreHostnameAnnotation:       ignoreHostnameAnnotation,
+		labelSelector:                  labelSelector,
+		publishPodIPs:                  publishPodIPs,
+		namespaceSelector:              namespaceSelector,
+		alwaysPublishNotReadyAddresses: alwaysPublishNotReadyAddresses,
+		compatibility:                  compatibility,
 	}, nil
 }
 
 func (*podSource) AddEventHandler(ctx context.Context, handler func()) {
 }
 
+func (ps *podSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "pod", gvk: corev1.SchemeGroupVersion.WithKind("Pod"), store: ps.podInformer.Informer().GetStore()}}
+}
+
+// podEndpointKey is the key of the endpoints of the pod source, the pods with different set identifiers are kept apart
+type podEndpointKey struct {
+	endpointKey
+	setIdentifier string
+}
+
 func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	pods, err := ps.podInformer.Lister().Pods(ps.namespace).List(labels.Everything())
+	pods, err := ps.podInformer.Lister().Pods(ps.namespace).List(ps.labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err = ps.filterByAnnotations(pods)
 	if err != nil {
 		return nil, err
 	}
+	// the oldest pod of a record sets its properties and planning labels, whatever the order of the lister
+	sort.Slice(pods, func(i, j int) bool {
+		if !pods[i].CreationTimestamp.Equal(&pods[j].CreationTimestamp) {
+			return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+		}
+		return pods[i].Namespace+"/"+pods[i].Name < pods[j].Namespace+"/"+pods[j].Name
+	})
 
-	endpointMap := make(map[endpointKey][]string)
+	endpointMap := make(map[podEndpointKey]*endpoint.Endpoint)
 	for _, pod := range pods {
-		if !pod.Spec.HostNetwork {
-			log.Debugf("skipping pod %s. hostNetwork=false", pod.Name)
+		if !pod.Spec.HostNetwork && !ps.isPublishedWithPodIPs(pod) {
 			continue
 		}
 
+		// the records of the pods with host networking point to their node, the pods of a node which is not found,
+		// e.g. the pods which are not scheduled yet, are skipped
+		var node *corev1.Node
+		if pod.Spec.HostNetwork {
+			n, err := ps.nodeInformer.Lister().Get(pod.Spec.NodeName)
+			if err != nil {
+				log.Errorf("Get node[%s] of pod[%s] error: %v; not adding any endpoints of the pod", pod.Spec.NodeName, pod.GetName(), err)
+				continue
+			}
+			node = n
+		}
+
+		ttl, err := getTTLFromAnnotations(pod.Annotations)
+		if err != nil {
+			log.Warn(err)
+		}
+		providerSpecific, setIdentifier := getProviderSpecificAnnotations(pod.Annotations)
+		targets := getTargetsFromTargetAnnotation(pod.Annotations)
+
+		addToEndpointMap := func(domain string, recordType string, address string) {
+			if len(targets) > 0 {
+				// the targets of the annotation replace the addresses of the pod, they are added once per domain
+				for _, target := range targets {
+					ps.addToEndpointMap(endpointMap, pod, domain, suitableType(target), target, ttl, providerSpecific, setIdentifier)
+				}
+				return
+			}
+			ps.addToEndpointMap(endpointMap, pod, domain, recordType, address, ttl, providerSpecific, setIdentifier)
+		}
+
 		if domainAnnotation, ok := pod.Annotations[internalHostnameAnnotationKey]; ok {
 			domainList := splitHostnameAnnotation(domainAnnotation)
 			for _, domain := range domainList {
-				addToEndpointMap(endpointMap, domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
+				for _, address := range podAddresses(pod) {
+					addToEndpointMap(domain, suitableType(address), address)
+				}
 			}
 		}
 
-		if domainAnnotation, ok := pod.Annotations[hostnameAnnotationKey]; ok {
-			domainList := splitHostnameAnnotation(domainAnnotation)
-			for _, domain := range domainList {
-				node, _ := ps.nodeInformer.Lister().Get(pod.Spec.NodeName)
-				for _, address := range node.Status.Addresses {
-					recordType := suitableType(address.Address)
-					// IPv6 addresses are labeled as NodeInternalIP despite being usable externally as well.
-					if address.Type == corev1.NodeExternalIP || (address.Type == corev1.NodeInternalIP && recordType == endpoint.RecordTypeAAAA) {
-						addToEndpointMap(endpointMap, domain, recordType, address.Address)
-					}
+		hostnames, err := ps.hostnames(pod)
+		if err != nil {
+			return nil, err
+		}
+		for _, domain := range hostnames {
+			if !pod.Spec.HostNetwork {
+				for _, address := range podAddresses(pod) {
+					addToEndpointMap(domain, suitableType(address), address)
+				}
+				continue
+			}
+			for _, address := range node.Status.Addresses {
+				recordType := suitableType(address.Address)
+				// IPv6 addresses are labeled as NodeInternalIP despite being usable externally as well.
+				if address.Type == corev1.NodeExternalIP || (address.Type == corev1.NodeInternalIP && recordType == endpoint.RecordTypeAAAA) {
+					addToEndpointMap(domain, recordType, address.Address)
 				}
 			}
 		}
 
-		if ps.compatibility == "kops-dns-controller" {
+		if ps.compatibility == "kops-dns-controller" && pod.Spec.HostNetwork {
 			if domainAnnotation, ok := pod.Annotations[kopsDNSControllerInternalHostnameAnnotationKey]; ok {
 				domainList := splitHostnameAnnotation(domainAnnotation)
 				for _, domain := range domainList {
-					addToEndpointMap(endpointMap, domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
+					addToEndpointMap(domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
 				}
 			}
 
 			if domainAnnotation, ok := pod.Annotations[kopsDNSControllerHostnameAnnotationKey]; ok {
 				domainList := splitHostnameAnnotation(domainAnnotation)
 				for _, domain := range domainList {
-					node, _ := ps.nodeInformer.Lister().Get(pod.Spec.NodeName)
 					for _, address := range node.Status.Addresses {
 						recordType := suitableType(address.Address)
 						// IPv6 addresses are labeled as NodeInternalIP despite being usable externally as well.
 						if address.Type == corev1.NodeExternalIP || (address.Type == corev1.NodeInternalIP && recordType == endpoint.RecordTypeAAAA) {
-							addToEndpointMap(endpointMap, domain, recordType, address.Address)
+							addToEndpointMap(domain, recordType, address.Address)
 						}
 					}
 				}
@@ -134,19 +236,134 @@ func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 		}
 	}
 	endpoints := []*endpoint.Endpoint{}
-	for key, targets := range endpointMap {
-		endpoints = append(endpoints, endpoint.NewEndpoint(key.dnsName, key.recordType, targets...))
+	for _, ep := range endpointMap {
+		endpoints = append(endpoints, ep)
 	}
 	return endpoints, nil
 }
 
-func addToEndpointMap(endpointMap map[endpointKey][]string, domain string, recordType string, address string) {
-	key := endpointKey{
-		dnsName:    domain,
-		recordType: recordType,
+// isPublishedWithPodIPs tells whether a pod without host networking is published with its pod IPs: the pod IPs must be
+// published, the namespace of the pod selected, and the pod ready, unless the not ready addresses are published too.
+func (ps *podSource) isPublishedWithPodIPs(pod *corev1.Pod) bool {
+	if !ps.publishPodIPs {
+		log.Debugf("skipping pod %s. hostNetwork=false", pod.Name)
+		return false
+	}
+	if ps.namespaceInformer != nil {
+		namespace, err := ps.namespaceInformer.Lister().Get(pod.Namespace)
+		if err != nil || !ps.namespaceSelector.Matches(labels.Set(namespace.Labels)) {
+			log.Debugf("skipping pod %s. namespace %s is not selected", pod.Name, pod.Namespace)
+			return false
+		}
+	}
+	if len(podAddresses(pod)) == 0 {
+		log.Debugf("skipping pod %s. no pod IP", pod.Name)
+		return false
+	}
+	if !ps.alwaysPublishNotReadyAddresses && !isPodReady(pod) {
+		log.Debugf("skipping pod %s. not ready", pod.Name)
+		return false
+	}
+	return true
+}
+
+// hostnames returns the hostnames of the pod from its hostname annotation and the FQDN template
+func (ps *podSource) hostnames(pod *corev1.Pod) ([]string, error) {
+	var hostnames []string
+	if !ps.ignoreHostnameAnnotation {
+		hostnames = getHostnamesFromAnnotations(pod.Annotations)
+	}
+
+	if ps.fqdnTemplate != nil && (len(hostnames) == 0 || ps.combineFQDNAnnotation) {
+		templateHostnames, err := execTemplate(ps.fqdnTemplate, pod)
+		if err != nil {
+			return nil, err
+		}
+		hostnames = append(hostnames, templateHostnames...)
+	}
+	return hostnames, nil
+}
+
+// filterByAnnotations filters a list of pods by a given annotation selector.
+func (ps *podSource) filterByAnnotations(pods []*corev1.Pod) ([]*corev1.Pod, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(ps.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return pods, nil
 	}
+
+	filteredList := []*corev1.Pod{}
+
+	for _, pod := range pods {
+		// include pod if its annotations match the selector
+		if selector.Matches(labels.Set(pod.Annotations)) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList, nil
+}
+
+func (ps *podSource) addToEndpointMap(endpointMap map[podEndpointKey]*endpoint.Endpoint, pod *corev1.Pod, domain string, recordType string, address string, ttl endpoint.TTL, providerSpecific endpoint.ProviderSpecific, setIdentifier string) {
+	key := podEndpointKey{
+		endpointKey: endpointKey{
+			dnsName:    domain,
+			recordType: recordType,
+		},
+		setIdentifier: setIdentifier,
+	}
+	// the first pod of a record sets its TTL, provider specific properties and planning labels
 	if _, ok := endpointMap[key]; !ok {
-		endpointMap[key] = []string{}
+		ep := endpoint.NewEndpointWithTTL(domain, recordType, ttl)
+		if ep == nil {
+			return
+		}
+		ep.ProviderSpecific = providerSpecific
+		ep.SetIdentifier = setIdentifier
+		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("pod/%s/%s", pod.Namespace, pod.Name)
+		setPlanningLabels(pod, []*endpoint.Endpoint{ep})
+		endpointMap[key] = ep
+	}
+	for _, target := range endpointMap[key].Targets {
+		if target == address {
+			return
+		}
+	}
+	endpointMap[key].Targets = append(endpointMap[key].Targets, address)
+}
+
+// podAddresses returns the IPs of the pod, of both IP families for dual-stack pods
+func podAddresses(pod *corev1.Pod) []string {
+	if len(pod.Status.PodIPs) == 0 {
+		if pod.Status.PodIP == "" {
+			return nil
+		}
+		return []string{pod.Status.PodIP}
+	}
+	addresses := make([]string, 0, len(pod.Status.PodIPs))
+	for _, podIP := range pod.Status.PodIPs {
+		addresses = append(addresses, podIP.IP)
+	}
+	return addresses
+}
+
+// isPodReady tells whether the pod is ready to serve, terminating pods are never ready.
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
 	}
-	endpointMap[key] = append(endpointMap[key], address)
+	return false
 }
@@ -19,10 +19,12 @@ package source
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/fake"
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -566,6 +568,77 @@ func TestPodSource(t *testing.T) {
 				},
 			},
 		},
+		{
+			"skip the pods with host networking of a node which is not found",
+			"",
+			"",
+			[]*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"54.10.11.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "internal.a.foo.example.org", Targets: endpoint.Targets{"10.0.1.1"}, RecordType: endpoint.RecordTypeA},
+			},
+			false,
+			[]*corev1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "my-node1",
+					},
+					Status: corev1.NodeStatus{
+						Addresses: []corev1.NodeAddress{
+							{Type: corev1.NodeExternalIP, Address: "54.10.11.1"},
+							{Type: corev1.NodeInternalIP, Address: "10.0.1.1"},
+						},
+					},
+				},
+			},
+			[]*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-pod1",
+						Namespace: "kube-system",
+						Annotations: map[string]string{
+							internalHostnameAnnotationKey: "internal.a.foo.example.org",
+							hostnameAnnotationKey:         "a.foo.example.org",
+						},
+					},
+					Spec: corev1.PodSpec{
+						HostNetwork: true,
+						NodeName:    "my-node1",
+					},
+					Status: corev1.PodStatus{
+						PodIP: "10.0.1.1",
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-pod2",
+						Namespace: "kube-system",
+						Annotations: map[string]string{
+							internalHostnameAnnotationKey: "internal.a.foo.example.org",
+							hostnameAnnotationKey:         "a.foo.example.org",
+						},
+					},
+					Spec: corev1.PodSpec{
+						HostNetwork: true,
+						NodeName:    "my-node2",
+					},
+					Status: corev1.PodStatus{
+						PodIP: "10.0.1.2",
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-pod3",
+						Namespace: "kube-system",
+						Annotations: map[string]string{
+							hostnameAnnotationKey: "a.foo.example.org",
+						},
+					},
+					Spec: corev1.PodSpec{
+						HostNetwork: true,
+					},
+				},
+			},
+		},
 	} {
 		tc := tc
 		t.Run(tc.title, func(t *testing.T) {
@@ -590,7 +663,7 @@ func TestPodSource(t *testing.T) {
 				}
 			}
 
-			client, err := NewPodSource(context.TODO(), kubernetes, tc.targetNamespace, tc.compatibility)
+			client, err := NewPodSource(context.TODO(), kubernetes, tc.targetNamespace, "", "", false, false, tc.compatibility, labels.Everything(), false, "", false)
 			require.NoError(t, err)
 
 			endpoints, err := client.Endpoints(ctx)
@@ -606,3 +679,201 @@ func TestPodSource(t *testing.T) {
 
 	}
 }
+
+// TestPodSourcePodIPs tests that the pods without host networking are published with their pod IPs.
+func TestPodSourcePodIPs(t *testing.T) {
+	t.Parallel()
+
+	ready := []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	newPod := func(namespace, name string, podLabels, annotations map[string]string, conditions []corev1.PodCondition, podIPs ...string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Labels:      podLabels,
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "my-node1",
+			},
+			Status: corev1.PodStatus{
+				Conditions: conditions,
+			},
+		}
+		for _, podIP := range podIPs {
+			pod.Status.PodIPs = append(pod.Status.PodIPs, corev1.PodIP{IP: podIP})
+		}
+		if len(podIPs) > 0 {
+			pod.Status.PodIP = podIPs[0]
+		}
+		return pod
+	}
+	createdAt := func(pod *corev1.Pod, ts string) *corev1.Pod {
+		created, err := time.Parse(time.RFC3339, ts)
+		require.NoError(t, err)
+		pod.CreationTimestamp = metav1.NewTime(created)
+		return pod
+	}
+
+	for _, tc := range []struct {
+		title                          string
+		publishPodIPs                  bool
+		namespaceLabelFilter           string
+		annotationFilter               string
+		labelFilter                    string
+		fqdnTemplate                   string
+		combineFQDNAnnotation          bool
+		alwaysPublishNotReadyAddresses bool
+		pods                           []*corev1.Pod
+		expected                       []*endpoint.Endpoint
+	}{
+		{
+			title: "pods without host networking are not published by default",
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-0", nil, map[string]string{hostnameAnnotationKey: "worker-0.example.org"}, ready, "10.1.0.1"),
+			},
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:         "ready pods are published with their pod IPs of both IP families",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-0", nil, map[string]string{hostnameAnnotationKey: "worker-0.example.org", internalHostnameAnnotationKey: "workers.internal.example.org"}, ready, "10.1.0.1", "2001:db8::1"),
+				newPod("batch", "worker-1", nil, map[string]string{internalHostnameAnnotationKey: "workers.internal.example.org"}, ready, "10.1.0.2"),
+				newPod("batch", "worker-2", nil, map[string]string{hostnameAnnotationKey: "worker-2.example.org"}, nil, "10.1.0.3"),
+				newPod("batch", "worker-3", nil, map[string]string{hostnameAnnotationKey: "worker-3.example.org"}, ready),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "worker-0.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "worker-0.example.org", Targets: endpoint.Targets{"2001:db8::1"}, RecordType: endpoint.RecordTypeAAAA},
+				{DNSName: "workers.internal.example.org", Targets: endpoint.Targets{"10.1.0.1", "10.1.0.2"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "workers.internal.example.org", Targets: endpoint.Targets{"2001:db8::1"}, RecordType: endpoint.RecordTypeAAAA},
+			},
+		},
+		{
+			title:                          "not ready pods are published with always-publish-not-ready-addresses",
+			publishPodIPs:                  true,
+			alwaysPublishNotReadyAddresses: true,
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-2", nil, map[string]string{hostnameAnnotationKey: "worker-2.example.org"}, nil, "10.1.0.3"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "worker-2.example.org", Targets: endpoint.Targets{"10.1.0.3"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:                "only the pods of the selected namespaces are published with their pod IPs",
+			publishPodIPs:        true,
+			namespaceLabelFilter: "dns=pods",
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-0", nil, map[string]string{hostnameAnnotationKey: "worker-0.example.org"}, ready, "10.1.0.1"),
+				newPod("default", "web-0", nil, map[string]string{hostnameAnnotationKey: "web-0.example.org"}, ready, "10.1.0.2"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "worker-0.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:            "FQDN template is applied to the pods filtered by labels and annotations",
+			publishPodIPs:    true,
+			fqdnTemplate:     "{{.Name}}.{{.Namespace}}.example.org",
+			labelFilter:      "app=worker",
+			annotationFilter: "team=batch",
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-0", map[string]string{"app": "worker"}, map[string]string{"team": "batch"}, ready, "10.1.0.1"),
+				newPod("batch", "worker-1", map[string]string{"app": "worker"}, map[string]string{"team": "other"}, ready, "10.1.0.2"),
+				newPod("batch", "web-0", map[string]string{"app": "web"}, map[string]string{"team": "batch"}, ready, "10.1.0.3"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "worker-0.batch.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:                 "FQDN template is combined with the hostname annotation",
+			publishPodIPs:         true,
+			fqdnTemplate:          "{{.Name}}.example.org",
+			combineFQDNAnnotation: true,
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-0", nil, map[string]string{hostnameAnnotationKey: "worker.example.org"}, ready, "10.1.0.1"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "worker.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "worker-0.example.org", Targets: endpoint.Targets{"10.1.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:         "the oldest pod of a record sets its planning labels",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				createdAt(newPod("batch", "worker-1", nil, map[string]string{internalHostnameAnnotationKey: "workers.internal.example.org", adoptAnnotationKey: "true"}, ready, "10.1.0.2"), "2023-02-01T00:00:00Z"),
+				createdAt(newPod("batch", "worker-0", nil, map[string]string{internalHostnameAnnotationKey: "workers.internal.example.org", sharedAnnotationKey: "true"}, ready, "10.1.0.1"), "2023-01-01T00:00:00Z"),
+			},
+			expected: []*endpoint.Endpoint{
+				{
+					DNSName:    "workers.internal.example.org",
+					Targets:    endpoint.Targets{"10.1.0.1", "10.1.0.2"},
+					RecordType: endpoint.RecordTypeA,
+					Labels: endpoint.Labels{
+						endpoint.ResourceLabelKey:                  "pod/batch/worker-0",
+						endpoint.SharedLabelKey:                    "true",
+						endpoint.ResourceCreationTimestampLabelKey: "2023-01-01T00:00:00Z",
+					},
+				},
+			},
+		},
+		{
+			title:         "TTL, target and provider specific annotations are applied",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("batch", "worker-0", nil, map[string]string{
+					hostnameAnnotationKey: "worker-0.example.org",
+					ttlAnnotationKey:      "60",
+					targetAnnotationKey:   "192.0.2.1",
+					CloudflareProxiedKey:  "true",
+				}, ready, "10.1.0.1"),
+			},
+			expected: []*endpoint.Endpoint{
+				{
+					DNSName:          "worker-0.example.org",
+					Targets:          endpoint.Targets{"192.0.2.1"},
+					RecordType:       endpoint.RecordTypeA,
+					RecordTTL:        60,
+					ProviderSpecific: endpoint.ProviderSpecific{{Name: CloudflareProxiedKey, Value: "true"}},
+				},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			kubernetes := fake.NewSimpleClientset()
+			ctx := context.Background()
+
+			_, err := kubernetes.CoreV1().Nodes().Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "my-node1"}}, metav1.CreateOptions{})
+			require.NoError(t, err)
+			for _, namespace := range []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "batch", Labels: map[string]string{"dns": "pods"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			} {
+				_, err = kubernetes.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			for _, pod := range tc.pods {
+				_, err = kubernetes.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			labelSelector, err := labels.Parse(tc.labelFilter)
+			require.NoError(t, err)
+
+			client, err := NewPodSource(context.TODO(), kubernetes, "", tc.annotationFilter, tc.fqdnTemplate, tc.combineFQDNAnnotation, false, "", labelSelector, tc.publishPodIPs, tc.namespaceLabelFilter, tc.alwaysPublishNotReadyAddresses)
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints(ctx)
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// referenceStore holds the objects of a kind part of the resource label set by a source
+type referenceStore struct {
+	// kind is the kind part of the resource label, it is empty when the kinds of the objects are only known from the
+	// objects themselves, e.g. the unstructured ones
+	kind string
+	// gvk is the kind of the objects, it is empty when the objects carry their kind, e.g. the unstructured ones
+	gvk   schema.GroupVersionKind
+	store cache.Store
+}
+
+// referenceStoreSource is implemented by the sources setting the resource label of their endpoints: they return the
+// stores of the objects the endpoints come from, i.e. their informer caches or the objects they listed last.
+type referenceStoreSource interface {
+	referenceStores() []referenceStore
+}
+
+// ResourceReferenceResolver resolves the resource label of the endpoints to a reference to the Kubernetes object
+// the endpoints come from, looking it up in the stores of the sources.
+type ResourceReferenceResolver struct {
+	stores map[string][]referenceStore
+}
+
+// NewResourceReferenceResolver returns a ResourceReferenceResolver looking the objects up in the informer caches
+// of the given sources
+func NewResourceReferenceResolver(sources []Source) *ResourceReferenceResolver {
+	r := &ResourceReferenceResolver{stores: map[string][]referenceStore{}}
+	for _, s := range sources {
+		if rs, ok := s.(referenceStoreSource); ok {
+			for _, store := range rs.referenceStores() {
+				r.stores[store.kind] = append(r.stores[store.kind], store)
+			}
+		}
+	}
+	return r
+}
+
+// ResolveReference returns a reference to the object identified by a resource label of the form kind/namespace/name.
+// The reference carries the UID of the object.
+func (r *ResourceReferenceResolver) ResolveReference(ctx context.Context, resource string) (*corev1.ObjectReference, error) {
+	parts := strings.SplitN(resource, "/", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid resource label %q", resource)
+	}
+	var stores []referenceStore
+	stores = append(stores, r.stores[parts[0]]...)
+	stores = append(stores, r.stores[""]...)
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("unsupported kind %q in resource label %q", parts[0], resource)
+	}
+	for _, s := range stores {
+		ref, err := s.reference(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve resource label %q: %w", resource, err)
+		}
+		if ref != nil {
+			return ref, nil
+		}
+	}
+	return nil, fmt.Errorf("object of resource label %q not found", resource)
+}
+
+// reference returns a reference to the object of the store with the given kind, namespace and name, nil if it is not there
+func (s referenceStore) reference(kind, namespace, name string) (*corev1.ObjectReference, error) {
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	item, exists, err := s.store.GetByKey(key)
+	if err != nil || !exists {
+		return nil, err
+	}
+	obj, err := meta.Accessor(item)
+	if err != nil {
+		return nil, err
+	}
+	gvk := s.gvk
+	if gvk.Empty() {
+		if ro, ok := item.(runtime.Object); ok {
+			gvk = ro.GetObjectKind().GroupVersionKind()
+		}
+	}
+	if s.kind == "" && strings.ToLower(gvk.Kind) != kind {
+		return nil, nil
+	}
+	apiVersion, objKind := gvk.ToAPIVersionAndKind()
+	return &corev1.ObjectReference{
+		Kind:            objKind,
+		APIVersion:      apiVersion,
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		UID:             obj.GetUID(),
+		ResourceVersion: obj.GetResourceVersion(),
+	}, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+type referenceStoreSourceStub struct {
+	Source
+	stores []referenceStore
+}
+
+func (s *referenceStoreSourceStub) referenceStores() []referenceStore {
+	return s.stores
+}
+
+func TestResolveReference(t *testing.T) {
+	ingresses := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, ingresses.Add(&networkv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "my-app", UID: types.UID("6a4a3b5e")},
+	}))
+	mappings := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	mapping := &unstructured.Unstructured{}
+	mapping.SetAPIVersion("serving.knative.dev/v1beta1")
+	mapping.SetKind("DomainMapping")
+	mapping.SetNamespace("default")
+	mapping.SetName("my-app.example.org")
+	mapping.SetUID(types.UID("0c2e87f1"))
+	require.NoError(t, mappings.Add(mapping))
+
+	resolver := NewResourceReferenceResolver([]Source{
+		&referenceStoreSourceStub{stores: []referenceStore{{kind: "ingress", gvk: networkv1.SchemeGroupVersion.WithKind("Ingress"), store: ingresses}}},
+		// the kinds of the unstructured objects are only known from the objects
+		&referenceStoreSourceStub{stores: []referenceStore{{store: mappings}}},
+		// sources without stores are skipped
+		NewEmptySource(),
+	})
+
+	ref, err := resolver.ResolveReference(context.Background(), "ingress/default/my-app")
+	require.NoError(t, err)
+	assert.Equal(t, "Ingress", ref.Kind)
+	assert.Equal(t, "networking.k8s.io/v1", ref.APIVersion)
+	assert.Equal(t, "default", ref.Namespace)
+	assert.Equal(t, "my-app", ref.Name)
+	assert.Equal(t, types.UID("6a4a3b5e"), ref.UID)
+
+	ref, err = resolver.ResolveReference(context.Background(), "domainmapping/default/my-app.example.org")
+	require.NoError(t, err)
+	assert.Equal(t, "DomainMapping", ref.Kind)
+	assert.Equal(t, "serving.knative.dev/v1beta1", ref.APIVersion)
+	assert.Equal(t, types.UID("0c2e87f1"), ref.UID)
+
+	for _, resource := range []string{
+		"ingress/default/other-app",
+		"ingress/default/my-app.example.org",
+		"ingress/my-app",
+		"unknown/default/my-app",
+	} {
+		_, err := resolver.ResolveReference(context.Background(), resource)
+		assert.Error(t, err, resource)
+	}
+}
@@ -26,10 +26,12 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -38,6 +40,8 @@ import (
 
 const (
 	defaultTargetsCapacity = 10
+	// serviceNameIndex is the name of the index of the EndpointSlices by the namespace and name of their service
+	serviceNameIndex = "serviceName"
 )
 
 // serviceSource is an implementation of Source for Kubernetes service objects.
@@ -59,8 +63,9 @@ type serviceSource struct {
 	publishHostIP                  bool
 	alwaysPublishNotReadyAddresses bool
 	resolveLoadBalancerHostname    bool
+	srvRecordsManaged              bool
 	serviceInformer                coreinformers.ServiceInformer
-	endpointsInformer              coreinformers.EndpointsInformer
+	endpointSlicesInformer         discoveryinformers.EndpointSliceInformer
 	podInformer                    coreinformers.PodInformer
 	nodeInformer                   coreinformers.NodeInformer
 	serviceTypeFilter              map[string]struct{}
@@ -68,7 +73,7 @@ type serviceSource struct {
 }
 
 // NewServiceSource creates a new serviceSource with the given config.
-func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, compatibility string, publishInternal bool, publishHostIP bool, alwaysPublishNotReadyAddresses bool, serviceTypeFilter []string, ignoreHostnameAnnotation bool, labelSelector labels.Selector, resolveLoadBalancerHostname bool) (Source, error) {
+func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, compatibility string, publishInternal bool, publishHostIP bool, alwaysPublishNotReadyAddresses bool, serviceTypeFilter []string, ignoreHostnameAnnotation bool, labelSelector labels.Selector, resolveLoadBalancerHostname bool, managedRecordTypes []string) (Source, error) {
 	tmpl, err := parseTemplate(fqdnTemplate)
 	if err != nil {
 		return nil, err
@@ -78,7 +83,7 @@ func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, name
 	// Set resync period to 0, to prevent processing when nothing has changed
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
 	serviceInformer := informerFactory.Core().V1().Services()
-	endpointsInformer := informerFactory.Core().V1().Endpoints()
+	endpointSlicesInformer := informerFactory.Discovery().V1().EndpointSlices()
 	podInformer := informerFactory.Core().V1().Pods()
 	nodeInformer := informerFactory.Core().V1().Nodes()
 
@@ -89,12 +94,16 @@ func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, name
 			},
 		},
 	)
-	endpointsInformer.Informer().AddEventHandler(
+	endpointSlicesInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 			},
 		},
 	)
+	// A service may have several EndpointSlices, which are looked up by the name of the service.
+	if err := endpointSlicesInformer.Informer().AddIndexers(cache.Indexers{serviceNameIndex: endpointSliceServiceName}); err != nil {
+		return nil, err
+	}
 	podInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
@@ -121,6 +130,12 @@ func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, name
 	for _, serviceType := range serviceTypeFilter {
 		serviceTypes[serviceType] = struct{}{}
 	}
+	srvRecordsManaged := false
+	for _, recordType := range managedRecordTypes {
+		if recordType == endpoint.RecordTypeSRV {
+			srvRecordsManaged = true
+		}
+	}
 
 	return &serviceSource{
 		client:                         kubeClient,
@@ -134,12 +149,13 @@ func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, name
 		publishHostIP:                  publishHostIP,
 		alwaysPublishNotReadyAddresses: alwaysPublishNotReadyAddresses,
 		serviceInformer:                serviceInformer,
-		endpointsInformer:              endpointsInformer,
+		endpointSlicesInformer:         endpointSlicesInformer,
 		podInformer:                    podInformer,
 		nodeInformer:                   nodeInformer,
 		serviceTypeFilter:              serviceTypes,
 		labelSelector:                  labelSelector,
 		resolveLoadBalancerHostname:    resolveLoadBalancerHostname,
+		srvRecordsManaged:              srvRecordsManaged,
 	}, nil
 }
 
@@ -244,7 +260,7 @@ func (sc *serviceSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 	return endpoints, nil
 }
 
-// extractHeadlessEndpoints extracts endpoints from a headless service using the "Endpoints" Kubernetes API resource
+// extractHeadlessEndpoints extracts endpoints from a headless service using its "EndpointSlice" Kubernetes API resources
 func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname string, ttl endpoint.TTL) []*endpoint.Endpoint {
 	var endpoints []*endpoint.Endpoint
 
@@ -257,42 +273,36 @@ func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname stri
 		return nil
 	}
 
-	endpointsObject, err := sc.endpointsInformer.Lister().Endpoints(svc.Namespace).Get(svc.GetName())
+	endpointSlices, err := sc.serviceEndpointSlices(svc)
 	if err != nil {
-		log.Errorf("Get endpoints of service[%s] error:%v", svc.GetName(), err)
-		return endpoints
-	}
-
-	pods, err := sc.podInformer.Lister().Pods(svc.Namespace).List(selector)
-	if err != nil {
-		log.Errorf("List pods of service[%s] error: %v", svc.GetName(), err)
+		log.Errorf("Get endpoint slices of service[%s] error:%v", svc.GetName(), err)
 		return endpoints
 	}
 
 	endpointsType := getEndpointsTypeFromAnnotations(svc.Annotations)
+	publishNotReadyAddresses := svc.Spec.PublishNotReadyAddresses || sc.alwaysPublishNotReadyAddresses
+	publishSRVRecords := sc.publishSRVRecords(svc)
 
 	targetsByHeadlessDomainAndType := make(map[endpointKey]endpoint.Targets)
-	for _, subset := range endpointsObject.Subsets {
-		addresses := subset.Addresses
-		if svc.Spec.PublishNotReadyAddresses || sc.alwaysPublishNotReadyAddresses {
-			addresses = append(addresses, subset.NotReadyAddresses...)
+	for _, endpointSlice := range endpointSlices {
+		// the addresses of both IP families are in different slices, the FQDN ones are not published
+		if endpointSlice.AddressType != discoveryv1.AddressTypeIPv4 && endpointSlice.AddressType != discoveryv1.AddressTypeIPv6 {
+			log.Debugf("Skipping endpoint slice %s of service[%s] because of its address type %s", endpointSlice.Name, svc.GetName(), endpointSlice.AddressType)
+			continue
 		}
 
-		for _, address := range addresses {
-			// find pod for this address
-			if address.TargetRef == nil || address.TargetRef.APIVersion != "" || address.TargetRef.Kind != "Pod" {
-				log.Debugf("Skipping address because its target is not a pod: %v", address)
+		for _, ep := range endpointSlice.Endpoints {
+			if !isPublishedEndpoint(ep.Conditions, publishNotReadyAddresses) {
 				continue
 			}
-			var pod *v1.Pod
-			for _, v := range pods {
-				if v.Name == address.TargetRef.Name {
-					pod = v
-					break
-				}
+			// find pod for this endpoint
+			if ep.TargetRef == nil || ep.TargetRef.APIVersion != "" || ep.TargetRef.Kind != "Pod" {
+				log.Debugf("Skipping endpoint because its target is not a pod: %v", ep.Addresses)
+				continue
 			}
-			if pod == nil {
-				log.Errorf("Pod %s not found for address %v", address.TargetRef.Name, address)
+			pod, err := sc.podInformer.Lister().Pods(svc.Namespace).Get(ep.TargetRef.Name)
+			if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+				log.Errorf("Pod %s not found for endpoint %v", ep.TargetRef.Name, ep.Addresses)
 				continue
 			}
 
@@ -320,8 +330,8 @@ func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname stri
 						targets = endpoint.Targets{pod.Status.HostIP}
 						log.Debugf("Generating matching endpoint %s with HostIP %s", headlessDomain, pod.Status.HostIP)
 					} else {
-						targets = endpoint.Targets{address.IP}
-						log.Debugf("Generating matching endpoint %s with EndpointAddress IP %s", headlessDomain, address.IP)
+						targets = append(targets, ep.Addresses...)
+						log.Debugf("Generating matching endpoint %s with EndpointSlice addresses %v", headlessDomain, ep.Addresses)
 					}
 				}
 				for _, target := range targets {
@@ -332,6 +342,25 @@ func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname stri
 					targetsByHeadlessDomainAndType[key] = append(targetsByHeadlessDomainAndType[key], target)
 				}
 			}
+
+			// like in-cluster DNS, the SRV records of the named ports point at the pods with a hostname
+			if publishSRVRecords && pod.Spec.Hostname != "" {
+				for _, port := range endpointSlice.Ports {
+					if port.Name == nil || *port.Name == "" || port.Port == nil {
+						continue
+					}
+					protocol := v1.ProtocolTCP
+					if port.Protocol != nil {
+						protocol = *port.Protocol
+					}
+					key := endpointKey{
+						dnsName:    srvRecordName(*port.Name, protocol, hostname),
+						recordType: endpoint.RecordTypeSRV,
+					}
+					target := fmt.Sprintf("0 50 %d %s.%s", *port.Port, pod.Spec.Hostname, hostname)
+					targetsByHeadlessDomainAndType[key] = append(targetsByHeadlessDomainAndType[key], target)
+				}
+			}
 		}
 	}
 
@@ -370,6 +399,54 @@ func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname stri
 	return endpoints
 }
 
+// serviceEndpointSlices returns the EndpointSlices of the service, it may have several of them for large numbers of
+// endpoints or several IP families
+func (sc *serviceSource) serviceEndpointSlices(svc *v1.Service) ([]*discoveryv1.EndpointSlice, error) {
+	objs, err := sc.endpointSlicesInformer.Informer().GetIndexer().ByIndex(serviceNameIndex, svc.Namespace+"/"+svc.Name)
+	if err != nil {
+		return nil, err
+	}
+	endpointSlices := make([]*discoveryv1.EndpointSlice, 0, len(objs))
+	for _, obj := range objs {
+		if endpointSlice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+			endpointSlices = append(endpointSlices, endpointSlice)
+		}
+	}
+	return endpointSlices, nil
+}
+
+// endpointSliceServiceName indexes an EndpointSlice by the namespace and name of its service
+func endpointSliceServiceName(obj interface{}) ([]string, error) {
+	endpointSlice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		return nil, nil
+	}
+	serviceName, ok := endpointSlice.Labels[discoveryv1.LabelServiceName]
+	if !ok || serviceName == "" {
+		return nil, nil
+	}
+	return []string{endpointSlice.Namespace + "/" + serviceName}, nil
+}
+
+// isPublishedEndpoint tells whether the addresses of an endpoint of an EndpointSlice are published: the ready endpoints
+// are, and the not ready ones as well when the not ready addresses are published, unless they are terminating. The
+// terminating endpoints are left out even while they are still serving, like they are left out of the Endpoints.
+func isPublishedEndpoint(conditions discoveryv1.EndpointConditions, publishNotReadyAddresses bool) bool {
+	// unknown conditions are interpreted as ready and not terminating
+	if conditions.Ready == nil || *conditions.Ready {
+		return true
+	}
+	return publishNotReadyAddresses && (conditions.Terminating == nil || !*conditions.Terminating)
+}
+
+// isServingEndpoint tells whether an endpoint of an EndpointSlice serves the traffic, even while it is terminating
+func isServingEndpoint(conditions discoveryv1.EndpointConditions) bool {
+	if conditions.Serving != nil {
+		return *conditions.Serving
+	}
+	return conditions.Ready == nil || *conditions.Ready
+}
+
 func (sc *serviceSource) endpointsFromTemplate(svc *v1.Service) ([]*endpoint.Endpoint, error) {
 	hostnames, err := execTemplate(sc.fqdnTemplate, svc)
 	if err != nil {
@@ -456,6 +533,7 @@ func (sc *serviceSource) setResourceLabel(service *v1.Service, endpoints []*endp
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("service/%s/%s", service.Namespace, service.Name)
 	}
+	setPlanningLabels(service, endpoints)
 }
 
 func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, providerSpecific endpoint.ProviderSpecific, setIdentifier string, useClusterIP bool) []*endpoint.Endpoint {
@@ -522,6 +600,10 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 		}
 	}
 
+	if svc.Spec.Type == v1.ServiceTypeLoadBalancer && sc.publishSRVRecords(svc) {
+		endpoints = append(endpoints, extractLoadBalancerSRVEndpoints(svc, hostname, ttl)...)
+	}
+
 	for _, t := range targets {
 		switch suitableType(t) {
 		case endpoint.RecordTypeA:
@@ -612,25 +694,21 @@ func (sc *serviceSource) extractNodePortTargets(svc *v1.Service) (endpoint.Targe
 
 	switch svc.Spec.ExternalTrafficPolicy {
 	case v1.ServiceExternalTrafficPolicyTypeLocal:
-		nodesMap := map[*v1.Node]struct{}{}
-		labelSelector, err := metav1.ParseToLabelSelector(labels.Set(svc.Spec.Selector).AsSelectorPreValidated().String())
-		if err != nil {
-			return nil, err
-		}
-		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-		if err != nil {
-			return nil, err
-		}
-		pods, err := sc.podInformer.Lister().Pods(svc.Namespace).List(selector)
+		// the traffic is only routed to the nodes running a serving endpoint of the service
+		endpointSlices, err := sc.serviceEndpointSlices(svc)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, v := range pods {
-			if v.Status.Phase == v1.PodRunning {
-				node, err := sc.nodeInformer.Lister().Get(v.Spec.NodeName)
+		nodesMap := map[*v1.Node]struct{}{}
+		for _, endpointSlice := range endpointSlices {
+			for _, ep := range endpointSlice.Endpoints {
+				if ep.NodeName == nil || !isServingEndpoint(ep.Conditions) {
+					continue
+				}
+				node, err := sc.nodeInformer.Lister().Get(*ep.NodeName)
 				if err != nil {
-					log.Debugf("Unable to find node where Pod %s is running", v.Spec.Hostname)
+					log.Debugf("Unable to find node %s where endpoint %v is running", *ep.NodeName, ep.Addresses)
 					continue
 				}
 				if _, ok := nodesMap[node]; !ok {
@@ -712,6 +790,49 @@ func (sc *serviceSource) extractNodePortEndpoints(svc *v1.Service, hostname stri
 	return endpoints
 }
 
+// extractLoadBalancerSRVEndpoints returns the SRV records of the named ports of the service, pointing at the given
+// hostname with the port of the service
+func extractLoadBalancerSRVEndpoints(svc *v1.Service, hostname string, ttl endpoint.TTL) []*endpoint.Endpoint {
+	var endpoints []*endpoint.Endpoint
+
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "" {
+			continue
+		}
+		recordName := srvRecordName(port.Name, port.Protocol, hostname)
+		target := fmt.Sprintf("0 50 %d %s", port.Port, hostname)
+
+		if ttl.IsConfigured() {
+			endpoints = append(endpoints, endpoint.NewEndpointWithTTL(recordName, endpoint.RecordTypeSRV, ttl, target))
+		} else {
+			endpoints = append(endpoints, endpoint.NewEndpoint(recordName, endpoint.RecordTypeSRV, target))
+		}
+	}
+
+	return endpoints
+}
+
+// publishSRVRecords tells whether the service asks for the SRV records of its named ports. The records are dropped
+// by the planner unless SRV is one of the managed record types, which is warned about.
+func (sc *serviceSource) publishSRVRecords(svc *v1.Service) bool {
+	if !getSRVRecordsFromAnnotations(svc.Annotations) {
+		return false
+	}
+	if !sc.srvRecordsManaged {
+		log.Warnf("The SRV records of service %s/%s are not published: SRV is not one of the managed record types, see --managed-record-types", svc.Namespace, svc.Name)
+	}
+	return true
+}
+
+// srvRecordName returns the name of the SRV record of a named port following RFC 2782, i.e. _port._proto.hostname
+func srvRecordName(portName string, protocol v1.Protocol, hostname string) string {
+	proto := strings.ToLower(string(protocol))
+	if proto == "" {
+		proto = "tcp"
+	}
+	return fmt.Sprintf("_%s._%s.%s", portName, proto, hostname)
+}
+
 func (sc *serviceSource) AddEventHandler(ctx context.Context, handler func()) {
 	log.Debug("Adding event handler for service")
 
@@ -719,3 +840,7 @@ func (sc *serviceSource) AddEventHandler(ctx context.Context, handler func()) {
 	// https://github.com/kubernetes/kubernetes/issues/79610
 	sc.serviceInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
 }
+
+func (sc *serviceSource) referenceStores() []referenceStore {
+	return []referenceStore{{kind: "service", gvk: v1.SchemeGroupVersion.WithKind("Service"), store: sc.serviceInformer.Informer().GetStore()}}
+}
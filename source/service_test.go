@@ -18,6 +18,7 @@ package source
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"sort"
 	"strings"
@@ -27,6 +28,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/fake"
@@ -79,6 +81,7 @@ func (suite *ServiceSuite) SetupTest() {
 		false,
 		labels.Everything(),
 		false,
+		nil,
 	)
 	suite.NoError(err, "should initialize service source")
 }
@@ -160,6 +163,7 @@ func testServiceSourceNewServiceSource(t *testing.T) {
 				false,
 				labels.Everything(),
 				false,
+				nil,
 			)
 
 			if ti.expectError {
@@ -1108,6 +1112,7 @@ func testServiceSourceEndpoints(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				sourceLabel,
 				tc.resolveLoadBalancerHostname,
+				nil,
 			)
 
 			require.NoError(t, err)
@@ -1298,6 +1303,7 @@ func testMultipleServicesEndpoints(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				labels.Everything(),
 				false,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -1590,6 +1596,7 @@ func TestClusterIpServices(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				labelSelector,
 				false,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -2138,6 +2145,7 @@ func TestServiceSourceNodePortServices(t *testing.T) {
 			}
 
 			// Create  pods
+			var sliceEndpoints []discoveryv1.Endpoint
 			for i, podname := range tc.podNames {
 				pod := &v1.Pod{
 					Spec: v1.PodSpec{
@@ -2158,7 +2166,16 @@ func TestServiceSourceNodePortServices(t *testing.T) {
 
 				_, err := kubernetes.CoreV1().Pods(tc.svcNamespace).Create(context.Background(), pod, metav1.CreateOptions{})
 				require.NoError(t, err)
+
+				ready := tc.phases[i] == v1.PodRunning
+				sliceEndpoints = append(sliceEndpoints, discoveryv1.Endpoint{
+					Addresses:  []string{"10.0.0.1"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					NodeName:   &pod.Spec.NodeName,
+					TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: podname},
+				})
 			}
+			createTestEndpointSlices(t, kubernetes, tc.svcNamespace, tc.svcName, sliceEndpoints)
 
 			// Create a service to test against
 			service := &v1.Service{
@@ -2198,6 +2215,7 @@ func TestServiceSourceNodePortServices(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				labels.Everything(),
 				false,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -2854,7 +2872,7 @@ func TestHeadlessServices(t *testing.T) {
 			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
 			require.NoError(t, err)
 
-			var addresses, notReadyAddresses []v1.EndpointAddress
+			var sliceEndpoints []discoveryv1.Endpoint
 			for i, podname := range tc.podnames {
 				pod := &v1.Pod{
 					Spec: v1.PodSpec{
@@ -2876,35 +2894,18 @@ func TestHeadlessServices(t *testing.T) {
 				_, err = kubernetes.CoreV1().Pods(tc.svcNamespace).Create(context.Background(), pod, metav1.CreateOptions{})
 				require.NoError(t, err)
 
-				address := v1.EndpointAddress{
-					IP: tc.podIPs[i],
+				ready := tc.podsReady[i]
+				sliceEndpoints = append(sliceEndpoints, discoveryv1.Endpoint{
+					Addresses:  []string{tc.podIPs[i]},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
 					TargetRef: &v1.ObjectReference{
 						APIVersion: "",
 						Kind:       "Pod",
 						Name:       podname,
 					},
-				}
-				if tc.podsReady[i] {
-					addresses = append(addresses, address)
-				} else {
-					notReadyAddresses = append(notReadyAddresses, address)
-				}
-			}
-			endpointsObject := &v1.Endpoints{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: tc.svcNamespace,
-					Name:      tc.svcName,
-					Labels:    tc.labels,
-				},
-				Subsets: []v1.EndpointSubset{
-					{
-						Addresses:         addresses,
-						NotReadyAddresses: notReadyAddresses,
-					},
-				},
+				})
 			}
-			_, err = kubernetes.CoreV1().Endpoints(tc.svcNamespace).Create(context.Background(), endpointsObject, metav1.CreateOptions{})
-			require.NoError(t, err)
+			createTestEndpointSlices(t, kubernetes, tc.svcNamespace, tc.svcName, sliceEndpoints)
 			for _, node := range tc.nodes {
 				_, err = kubernetes.CoreV1().Nodes().Create(context.Background(), &node, metav1.CreateOptions{})
 				require.NoError(t, err)
@@ -2926,6 +2927,7 @@ func TestHeadlessServices(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				labels.Everything(),
 				false,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -3321,8 +3323,7 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
 			require.NoError(t, err)
 
-			var addresses []v1.EndpointAddress
-			var notReadyAddresses []v1.EndpointAddress
+			var sliceEndpoints []discoveryv1.Endpoint
 			for i, podname := range tc.podnames {
 				pod := &v1.Pod{
 					Spec: v1.PodSpec{
@@ -3343,31 +3344,14 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 				_, err = kubernetes.CoreV1().Pods(tc.svcNamespace).Create(context.Background(), pod, metav1.CreateOptions{})
 				require.NoError(t, err)
 
-				address := v1.EndpointAddress{
-					IP:        "4.3.2.1",
-					TargetRef: tc.targetRefs[i],
-				}
-				if tc.podsReady[i] {
-					addresses = append(addresses, address)
-				} else {
-					notReadyAddresses = append(notReadyAddresses, address)
-				}
+				ready := tc.podsReady[i]
+				sliceEndpoints = append(sliceEndpoints, discoveryv1.Endpoint{
+					Addresses:  []string{"4.3.2.1"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					TargetRef:  tc.targetRefs[i],
+				})
 			}
-			endpointsObject := &v1.Endpoints{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: tc.svcNamespace,
-					Name:      tc.svcName,
-					Labels:    tc.labels,
-				},
-				Subsets: []v1.EndpointSubset{
-					{
-						Addresses:         addresses,
-						NotReadyAddresses: notReadyAddresses,
-					},
-				},
-			}
-			_, err = kubernetes.CoreV1().Endpoints(tc.svcNamespace).Create(context.Background(), endpointsObject, metav1.CreateOptions{})
-			require.NoError(t, err)
+			createTestEndpointSlices(t, kubernetes, tc.svcNamespace, tc.svcName, sliceEndpoints)
 
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
@@ -3385,6 +3369,7 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				labels.Everything(),
 				false,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -3401,6 +3386,309 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 	}
 }
 
+// createTestEndpointSlices creates the EndpointSlices of a service, with an EndpointSlice per IP family of the endpoints
+func createTestEndpointSlices(t *testing.T, client *fake.Clientset, namespace, serviceName string, endpoints []discoveryv1.Endpoint) {
+	slices := map[discoveryv1.AddressType]*discoveryv1.EndpointSlice{}
+	for _, ep := range endpoints {
+		addressType := discoveryv1.AddressTypeIPv4
+		if suitableType(ep.Addresses[0]) == endpoint.RecordTypeAAAA {
+			addressType = discoveryv1.AddressTypeIPv6
+		}
+		if _, ok := slices[addressType]; !ok {
+			slices[addressType] = &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      serviceName + "-" + strings.ToLower(string(addressType)),
+					Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+				},
+				AddressType: addressType,
+			}
+		}
+		slices[addressType].Endpoints = append(slices[addressType].Endpoints, ep)
+	}
+	for _, slice := range slices {
+		_, err := client.DiscoveryV1().EndpointSlices(namespace).Create(context.Background(), slice, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+}
+
+// TestHeadlessServicesEndpointSlices tests that the endpoints of all the EndpointSlices of a headless service are published
+// according to their conditions.
+func TestHeadlessServicesEndpointSlices(t *testing.T) {
+	t.Parallel()
+
+	ready, notReady := true, false
+	newSlice := func(name, serviceName string, addressType discoveryv1.AddressType, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "testing",
+				Name:      name,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+			},
+			AddressType: addressType,
+			Endpoints:   endpoints,
+		}
+	}
+	newEndpoint := func(podName string, conditions discoveryv1.EndpointConditions, addresses ...string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  addresses,
+			Conditions: conditions,
+			TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: podName},
+		}
+	}
+
+	for _, tc := range []struct {
+		title                    string
+		publishNotReadyAddresses bool
+		expected                 []*endpoint.Endpoint
+	}{
+		{
+			title: "ready endpoints of every slice and IP family are published",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo-0.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+				{DNSName: "foo-0.service.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+				{DNSName: "foo-1.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.2"}},
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1", "1.1.1.2"}},
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+		},
+		{
+			title:                    "not ready endpoints are published unless they are terminating",
+			publishNotReadyAddresses: true,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo-0.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+				{DNSName: "foo-0.service.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+				{DNSName: "foo-1.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.2"}},
+				{DNSName: "foo-2.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.3"}},
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1", "1.1.1.2", "1.1.1.3"}},
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			kubernetes := fake.NewSimpleClientset()
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: map[string]string{hostnameAnnotationKey: "service.example.org"},
+				},
+				Spec: v1.ServiceSpec{
+					Type:                     v1.ServiceTypeClusterIP,
+					ClusterIP:                v1.ClusterIPNone,
+					Selector:                 map[string]string{"component": "foo"},
+					PublishNotReadyAddresses: tc.publishNotReadyAddresses,
+				},
+			}
+			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
+			require.NoError(t, err)
+			for _, name := range []string{"foo-0", "foo-1", "foo-2", "foo-3", "bar-0"} {
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "testing",
+						Name:      name,
+						Labels:    map[string]string{"component": strings.Split(name, "-")[0]},
+					},
+					Spec: v1.PodSpec{Hostname: name},
+				}
+				_, err = kubernetes.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			terminating := discoveryv1.EndpointConditions{Ready: &notReady, Serving: &ready, Terminating: &ready}
+			for _, slice := range []*discoveryv1.EndpointSlice{
+				// the endpoints of a service are split in several slices
+				newSlice("foo-a", "foo", discoveryv1.AddressTypeIPv4, newEndpoint("foo-0", discoveryv1.EndpointConditions{Ready: &ready}, "1.1.1.1")),
+				newSlice("foo-b", "foo", discoveryv1.AddressTypeIPv4,
+					newEndpoint("foo-1", discoveryv1.EndpointConditions{}, "1.1.1.2"),
+					newEndpoint("foo-2", discoveryv1.EndpointConditions{Ready: &notReady}, "1.1.1.3"),
+					newEndpoint("foo-3", terminating, "1.1.1.4"),
+				),
+				newSlice("foo-c", "foo", discoveryv1.AddressTypeIPv6, newEndpoint("foo-0", discoveryv1.EndpointConditions{Ready: &ready}, "2001:db8::1")),
+				newSlice("foo-d", "foo", discoveryv1.AddressTypeFQDN, newEndpoint("foo-1", discoveryv1.EndpointConditions{Ready: &ready}, "foo-1.example.org")),
+				// the slices of other services are left out
+				newSlice("bar-a", "bar", discoveryv1.AddressTypeIPv4, newEndpoint("bar-0", discoveryv1.EndpointConditions{Ready: &ready}, "1.1.1.5")),
+			} {
+				_, err = kubernetes.DiscoveryV1().EndpointSlices(slice.Namespace).Create(context.Background(), slice, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			client, err := NewServiceSource(
+				context.TODO(),
+				kubernetes,
+				"",
+				"",
+				"",
+				false,
+				"",
+				true,
+				false,
+				false,
+				[]string{},
+				false,
+				labels.Everything(),
+				false,
+				nil,
+			)
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints(context.Background())
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestServiceSourceSRVRecords(t *testing.T) {
+	t.Parallel()
+
+	ready := true
+	grpc, sip, unnamed := "grpc", "sip", ""
+	tcp, udp := v1.ProtocolTCP, v1.ProtocolUDP
+	grpcPort, sipPort, metricsPort := int32(8080), int32(5060), int32(9090)
+
+	for _, tc := range []struct {
+		title       string
+		svcType     v1.ServiceType
+		clusterIP   string
+		annotations map[string]string
+		expected    []*endpoint.Endpoint
+	}{
+		{
+			title:       "headless service publishes the SRV records of its named ports pointing at the pods with a hostname",
+			svcType:     v1.ServiceTypeClusterIP,
+			clusterIP:   v1.ClusterIPNone,
+			annotations: map[string]string{hostnameAnnotationKey: "service.example.org", srvRecordsAnnotationKey: "true"},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1", "1.1.1.2", "1.1.1.3"}},
+				{DNSName: "foo-0.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+				{DNSName: "foo-1.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.2"}},
+				{DNSName: "_grpc._tcp.service.example.org", RecordType: endpoint.RecordTypeSRV, Targets: endpoint.Targets{"0 50 8080 foo-0.service.example.org", "0 50 8080 foo-1.service.example.org"}},
+				{DNSName: "_sip._udp.service.example.org", RecordType: endpoint.RecordTypeSRV, Targets: endpoint.Targets{"0 50 5060 foo-0.service.example.org", "0 50 5060 foo-1.service.example.org"}},
+			},
+		},
+		{
+			title:       "headless service without annotation does not publish SRV records",
+			svcType:     v1.ServiceTypeClusterIP,
+			clusterIP:   v1.ClusterIPNone,
+			annotations: map[string]string{hostnameAnnotationKey: "service.example.org"},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1", "1.1.1.2", "1.1.1.3"}},
+				{DNSName: "foo-0.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+				{DNSName: "foo-1.service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.2"}},
+			},
+		},
+		{
+			title:       "LoadBalancer service publishes the SRV records of its named ports pointing at its hostname",
+			svcType:     v1.ServiceTypeLoadBalancer,
+			annotations: map[string]string{hostnameAnnotationKey: "service.example.org", srvRecordsAnnotationKey: "true", ttlAnnotationKey: "60"},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, RecordTTL: 60, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "_grpc._tcp.service.example.org", RecordType: endpoint.RecordTypeSRV, RecordTTL: 60, Targets: endpoint.Targets{"0 50 443 service.example.org"}},
+				{DNSName: "_sip._udp.service.example.org", RecordType: endpoint.RecordTypeSRV, RecordTTL: 60, Targets: endpoint.Targets{"0 50 5060 service.example.org"}},
+			},
+		},
+		{
+			title:       "LoadBalancer service without annotation does not publish SRV records",
+			svcType:     v1.ServiceTypeLoadBalancer,
+			annotations: map[string]string{hostnameAnnotationKey: "service.example.org"},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			kubernetes := fake.NewSimpleClientset()
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "testing",
+					Name:        "foo",
+					Annotations: tc.annotations,
+				},
+				Spec: v1.ServiceSpec{
+					Type:      tc.svcType,
+					ClusterIP: tc.clusterIP,
+					Selector:  map[string]string{"component": "foo"},
+					Ports: []v1.ServicePort{
+						{Name: "grpc", Protocol: v1.ProtocolTCP, Port: 443},
+						{Name: "sip", Protocol: v1.ProtocolUDP, Port: 5060},
+						{Port: 9090},
+					},
+				},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}},
+				},
+			}
+			_, err := kubernetes.CoreV1().Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
+			require.NoError(t, err)
+
+			var sliceEndpoints []discoveryv1.Endpoint
+			for i, hostname := range []string{"foo-0", "foo-1", ""} {
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "testing",
+						Name:      fmt.Sprintf("foo-%d", i),
+						Labels:    map[string]string{"component": "foo"},
+					},
+					Spec: v1.PodSpec{Hostname: hostname},
+				}
+				_, err = kubernetes.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+				require.NoError(t, err)
+				sliceEndpoints = append(sliceEndpoints, discoveryv1.Endpoint{
+					Addresses:  []string{fmt.Sprintf("1.1.1.%d", i+1)},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: pod.Name},
+				})
+			}
+			slice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "testing",
+					Name:      "foo-a",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: "foo"},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   sliceEndpoints,
+				// the slices hold the target ports of the service
+				Ports: []discoveryv1.EndpointPort{
+					{Name: &grpc, Protocol: &tcp, Port: &grpcPort},
+					{Name: &sip, Protocol: &udp, Port: &sipPort},
+					{Name: &unnamed, Protocol: &tcp, Port: &metricsPort},
+				},
+			}
+			_, err = kubernetes.DiscoveryV1().EndpointSlices(slice.Namespace).Create(context.Background(), slice, metav1.CreateOptions{})
+			require.NoError(t, err)
+
+			client, err := NewServiceSource(
+				context.TODO(),
+				kubernetes,
+				"",
+				"",
+				"",
+				false,
+				"",
+				false,
+				false,
+				false,
+				[]string{},
+				false,
+				labels.Everything(),
+				false,
+				nil,
+			)
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints(context.Background())
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
 // TestExternalServices tests that external services generate the correct endpoints.
 func TestExternalServices(t *testing.T) {
 	t.Parallel()
@@ -3517,6 +3805,7 @@ func TestExternalServices(t *testing.T) {
 				tc.ignoreHostnameAnnotation,
 				labels.Everything(),
 				false,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -3572,6 +3861,7 @@ func BenchmarkServiceEndpoints(b *testing.B) {
 		false,
 		labels.Everything(),
 		false,
+		nil,
 	)
 	require.NoError(b, err)
 
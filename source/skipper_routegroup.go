@@ -34,6 +34,10 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -55,6 +59,9 @@ type routeGroupSource struct {
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
+	gvk                      schema.GroupVersionKind
+	// listed holds the metadata of the route groups listed last, to resolve the references to them
+	listed cache.Store
 }
 
 // for testing
@@ -222,6 +229,8 @@ func NewRouteGroupSource(timeout time.Duration, token, tokenPath, apiServerURL,
 		fqdnTemplate:             tmpl,
 		combineFQDNAnnotation:    combineFqdnAnnotation,
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		gvk:                      schema.FromAPIVersionAndKind(routegroupVersion, "RouteGroup"),
+		listed:                   cache.NewStore(cache.MetaNamespaceKeyFunc),
 	}
 	if namespace != "" {
 		sc.apiEndpoint = apiServer + fmt.Sprintf(routeGroupNamespacedResource, routegroupVersion, namespace)
@@ -247,6 +256,20 @@ func (sc *routeGroupSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint
 	if err != nil {
 		return nil, err
 	}
+	if sc.listed != nil {
+		listed := make([]interface{}, 0, len(rgList.Items))
+		for _, rg := range rgList.Items {
+			listed = append(listed, &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+				Namespace:       rg.Metadata.Namespace,
+				Name:            rg.Metadata.Name,
+				UID:             rg.Metadata.UID,
+				ResourceVersion: rg.Metadata.ResourceVersion,
+			}})
+		}
+		if err := sc.listed.Replace(listed, ""); err != nil {
+			return nil, err
+		}
+	}
 
 	endpoints := []*endpoint.Endpoint{}
 	for _, rg := range rgList.Items {
@@ -291,6 +314,13 @@ func (sc *routeGroupSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint
 	return endpoints, nil
 }
 
+func (sc *routeGroupSource) referenceStores() []referenceStore {
+	if sc.listed == nil {
+		return nil
+	}
+	return []referenceStore{{kind: "routegroup", gvk: sc.gvk, store: sc.listed}}
+}
+
 func (sc *routeGroupSource) endpointsFromTemplate(rg *routeGroup) ([]*endpoint.Endpoint, error) {
 	// Process the whole template string
 	var buf bytes.Buffer
@@ -435,9 +465,11 @@ type routeGroup struct {
 }
 
 type itemMetadata struct {
-	Namespace   string            `json:"namespace"`
-	Name        string            `json:"name"`
-	Annotations map[string]string `json:"annotations"`
+	Namespace       string            `json:"namespace"`
+	Name            string            `json:"name"`
+	UID             types.UID         `json:"uid"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Annotations     map[string]string `json:"annotations"`
 }
 
 type routeGroupSpec struct {
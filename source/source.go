@@ -19,6 +19,7 @@ package source
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -29,6 +30,7 @@ import (
 	"time"
 	"unicode"
 
+	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -59,6 +61,16 @@ const (
 	controllerAnnotationValue = "dns-controller"
 	// The annotation used for defining the desired hostname
 	internalHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/internal-hostname"
+	// The annotation used for ranking resources claiming the same record with the priority conflict resolver
+	priorityAnnotationKey = "external-dns.alpha.kubernetes.io/priority"
+	// The annotation used for taking over the records of the resource which are owned by another instance
+	adoptAnnotationKey = "external-dns.alpha.kubernetes.io/adopt"
+	// The annotation used for sharing the records of the resource with the other instances contributing targets to them
+	sharedAnnotationKey = "external-dns.alpha.kubernetes.io/shared"
+	// The annotation used for keeping the records of the resource, and their ownership, once the resource is deleted
+	retainOnDeleteAnnotationKey = "external-dns.alpha.kubernetes.io/retain-on-delete"
+	// The annotation used for publishing the SRV records of the named ports of headless and LoadBalancer services
+	srvRecordsAnnotationKey = "external-dns.alpha.kubernetes.io/srv-records"
 )
 
 const (
@@ -66,6 +78,9 @@ const (
 	EndpointsTypeHostIP         = "HostIP"
 )
 
+// ErrCacheNotSynced is returned when the informer caches of a source could not be synced with the API server
+var ErrCacheNotSynced = errors.New("failed to sync informer caches")
+
 // Provider-specific annotations
 const (
 	// The annotation used for determining if traffic will go through Cloudflare
@@ -185,6 +200,51 @@ func getAliasFromAnnotations(annotations map[string]string) bool {
 	return exists && aliasAnnotation == "true"
 }
 
+func getSRVRecordsFromAnnotations(annotations map[string]string) bool {
+	srvRecordsAnnotation, exists := annotations[srvRecordsAnnotationKey]
+	return exists && srvRecordsAnnotation == "true"
+}
+
+// setPlanningLabels attaches the resource metadata used by the planner to resolve
+// conflicts between resources, to adopt records, to share them and to retain them to the endpoints generated from obj.
+func setPlanningLabels(obj metav1.Object, endpoints []*endpoint.Endpoint) {
+	var creationTimestamp string
+	if ts := obj.GetCreationTimestamp(); !ts.IsZero() {
+		creationTimestamp = ts.UTC().Format(time.RFC3339)
+	}
+
+	var priority string
+	if value, ok := obj.GetAnnotations()[priorityAnnotationKey]; ok {
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			log.Warnf("Ignoring invalid %s annotation %q on %s/%s: %v", priorityAnnotationKey, value, obj.GetNamespace(), obj.GetName(), err)
+		} else {
+			priority = value
+		}
+	}
+
+	adopt := obj.GetAnnotations()[adoptAnnotationKey] == "true"
+	shared := obj.GetAnnotations()[sharedAnnotationKey] == "true"
+	retain := obj.GetAnnotations()[retainOnDeleteAnnotationKey] == "true"
+
+	for _, ep := range endpoints {
+		if adopt {
+			ep.Labels[endpoint.AdoptLabelKey] = "true"
+		}
+		if shared {
+			ep.Labels[endpoint.SharedLabelKey] = "true"
+		}
+		if retain {
+			ep.Labels[endpoint.RetainLabelKey] = "true"
+		}
+		if creationTimestamp != "" {
+			ep.Labels[endpoint.ResourceCreationTimestampLabelKey] = creationTimestamp
+		}
+		if priority != "" {
+			ep.Labels[endpoint.PriorityLabelKey] = priority
+		}
+	}
+}
+
 func getProviderSpecificAnnotations(annotations map[string]string) (endpoint.ProviderSpecific, string) {
 	providerSpecificAnnotations := endpoint.ProviderSpecific{}
 
@@ -353,9 +413,9 @@ func waitForCacheSync(ctx context.Context, factory informerFactory) error {
 		if !done {
 			select {
 			case <-ctx.Done():
-				return fmt.Errorf("failed to sync %v: %v", typ, ctx.Err())
+				return fmt.Errorf("%w: %v: %v", ErrCacheNotSynced, typ, ctx.Err())
 			default:
-				return fmt.Errorf("failed to sync %v", typ)
+				return fmt.Errorf("%w: %v", ErrCacheNotSynced, typ)
 			}
 		}
 	}
@@ -373,9 +433,9 @@ func waitForDynamicCacheSync(ctx context.Context, factory dynamicInformerFactory
 		if !done {
 			select {
 			case <-ctx.Done():
-				return fmt.Errorf("failed to sync %v: %v", typ, ctx.Err())
+				return fmt.Errorf("%w: %v: %v", ErrCacheNotSynced, typ, ctx.Err())
 			default:
-				return fmt.Errorf("failed to sync %v", typ)
+				return fmt.Errorf("%w: %v", ErrCacheNotSynced, typ)
 			}
 		}
 	}
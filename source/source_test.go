@@ -19,8 +19,10 @@ package source
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -106,3 +108,76 @@ func TestSuitableType(t *testing.T) {
 		}
 	}
 }
+
+func TestSetPlanningLabels(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	for _, tc := range []struct {
+		title    string
+		meta     metav1.ObjectMeta
+		expected endpoint.Labels
+	}{
+		{
+			title:    "no metadata",
+			meta:     metav1.ObjectMeta{Name: "foo"},
+			expected: endpoint.Labels{},
+		},
+		{
+			title: "creation timestamp and priority",
+			meta: metav1.ObjectMeta{
+				Name:              "foo",
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{priorityAnnotationKey: "10"},
+			},
+			expected: endpoint.Labels{
+				endpoint.ResourceCreationTimestampLabelKey: "2023-01-02T02:04:05Z",
+				endpoint.PriorityLabelKey:                  "10",
+			},
+		},
+		{
+			title: "invalid priority",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{priorityAnnotationKey: "high"},
+			},
+			expected: endpoint.Labels{},
+		},
+		{
+			title: "adopt",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{adoptAnnotationKey: "true"},
+			},
+			expected: endpoint.Labels{endpoint.AdoptLabelKey: "true"},
+		},
+		{
+			title: "adopt disabled",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{adoptAnnotationKey: "false"},
+			},
+			expected: endpoint.Labels{},
+		},
+		{
+			title: "shared",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{sharedAnnotationKey: "true"},
+			},
+			expected: endpoint.Labels{endpoint.SharedLabelKey: "true"},
+		},
+		{
+			title: "retain on delete",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{retainOnDeleteAnnotationKey: "true"},
+			},
+			expected: endpoint.Labels{endpoint.RetainLabelKey: "true"},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ep := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+			setPlanningLabels(&tc.meta, []*endpoint.Endpoint{ep})
+			assert.Equal(t, tc.expected, ep.Labels)
+		})
+	}
+}
@@ -58,6 +58,10 @@ type Config struct {
 	PublishInternal                bool
 	PublishHostIP                  bool
 	AlwaysPublishNotReadyAddresses bool
+	PublishPodIPs                  bool
+	PodNamespaceLabelFilter        string
+	PodFQDNTemplate                string
+	UnstructuredResources          []string
 	ConnectorServer                string
 	CRDSourceAPIVersion            string
 	CRDSourceKind                  string
@@ -75,6 +79,7 @@ type Config struct {
 	OCPRouterName                  string
 	UpdateEvents                   bool
 	ResolveLoadBalancerHostname    bool
+	ManagedRecordTypes             []string
 }
 
 // ClientGenerator provides clients
@@ -217,7 +222,7 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 		if err != nil {
 			return nil, err
 		}
-		return NewServiceSource(ctx, client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.AlwaysPublishNotReadyAddresses, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter, cfg.ResolveLoadBalancerHostname)
+		return NewServiceSource(ctx, client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.AlwaysPublishNotReadyAddresses, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter, cfg.ResolveLoadBalancerHostname, cfg.ManagedRecordTypes)
 	case "ingress":
 		client, err := p.KubeClient()
 		if err != nil {
@@ -229,7 +234,10 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 		if err != nil {
 			return nil, err
 		}
-		return NewPodSource(ctx, client, cfg.Namespace, cfg.Compatibility)
+		// the pods are named with a template of their own: the global one and the options going with it are meant for
+		// the other sources, and would change the records of the pods published so far
+		podTemplate := cfg.PodFQDNTemplate != ""
+		return NewPodSource(ctx, client, cfg.Namespace, cfg.AnnotationFilter, cfg.PodFQDNTemplate, podTemplate && cfg.CombineFQDNAndAnnotation, podTemplate && cfg.IgnoreHostnameAnnotation, cfg.Compatibility, cfg.LabelFilter, cfg.PublishPodIPs, cfg.PodNamespaceLabelFilter, cfg.AlwaysPublishNotReadyAddresses)
 	case "gateway-httproute":
 		return NewGatewayHTTPRouteSource(p, cfg)
 	case "gateway-grpcroute":
@@ -333,6 +341,12 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 			return nil, err
 		}
 		return NewKongTCPIngressSource(ctx, dynamicClient, kubernetesClient, cfg.Namespace, cfg.AnnotationFilter)
+	case "unstructured":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewUnstructuredSource(ctx, dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.LabelFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.UnstructuredResources)
 	case "f5-virtualserver":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
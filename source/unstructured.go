@@ -0,0 +1,321 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// unstructuredSource is an implementation of Source for the resources of any kind, read with the dynamic client.
+// The hostnames and the targets are extracted from the resources with the JSONPath expressions of their kind, along
+// with the usual annotations.
+type unstructuredSource struct {
+	namespace                string
+	annotationFilter         labels.Selector
+	labelSelector            labels.Selector
+	fqdnTemplate             *template.Template
+	combineFQDNAnnotation    bool
+	ignoreHostnameAnnotation bool
+	unstructuredConverter    *UnstructuredConverter
+	informers                []resourceInformer
+}
+
+type resourceInformer struct {
+	unstructuredResource
+	informer informers.GenericInformer
+}
+
+// unstructuredResource is a kind of resources read by the unstructured source, along with the JSONPath expressions
+// extracting their hostnames and targets.
+type unstructuredResource struct {
+	gvr              schema.GroupVersionResource
+	hostnameJSONPath *jsonpath.JSONPath
+	targetJSONPath   *jsonpath.JSONPath
+}
+
+// NewUnstructuredSource creates a new unstructuredSource with the given config. The resources are given as
+// resource.version.group=hostnameJSONPath,targetJSONPath, e.g.
+// ingressroutes.v1alpha1.traefik.containo.us={.spec.host},{.status.loadBalancer.ingress[*].ip}, the group being empty
+// for the core group and the expressions being optional.
+func NewUnstructuredSource(
+	ctx context.Context,
+	dynamicKubeClient dynamic.Interface,
+	namespace string,
+	annotationFilter string,
+	labelSelector labels.Selector,
+	fqdnTemplate string,
+	combineFqdnAnnotation bool,
+	ignoreHostnameAnnotation bool,
+	resources []string,
+) (Source, error) {
+	if len(resources) == 0 {
+		return nil, errors.New("at least one resource is required by the unstructured source")
+	}
+
+	tmpl, err := parseTemplate(fqdnTemplate)
+	if err != nil {
+		return nil, err
+	}
+	annotationSelector, err := getLabelSelector(annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+	uc, err := NewUnstructuredConverter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Use shared informers to listen for add/update/delete of the resources in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+	resourceInformers := make([]resourceInformer, 0, len(resources))
+	for _, resource := range resources {
+		ur, err := parseUnstructuredResource(resource)
+		if err != nil {
+			return nil, err
+		}
+		informer := informerFactory.ForResource(ur.gvr)
+
+		// Add default resource event handlers to properly initialize informer.
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+		resourceInformers = append(resourceInformers, resourceInformer{unstructuredResource: ur, informer: informer})
+	}
+
+	informerFactory.Start(ctx.Done())
+
+	// wait for the local cache to be populated.
+	if err := waitForDynamicCacheSync(context.Background(), informerFactory); err != nil {
+		return nil, err
+	}
+
+	return &unstructuredSource{
+		namespace:                namespace,
+		annotationFilter:         annotationSelector,
+		labelSelector:            labelSelector,
+		fqdnTemplate:             tmpl,
+		combineFQDNAnnotation:    combineFqdnAnnotation,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		unstructuredConverter:    uc,
+		informers:                resourceInformers,
+	}, nil
+}
+
+// referenceStores returns the informer caches of the resources, their kinds are only known from the objects
+func (us *unstructuredSource) referenceStores() []referenceStore {
+	stores := make([]referenceStore, 0, len(us.informers))
+	for _, ri := range us.informers {
+		stores = append(stores, referenceStore{store: ri.informer.Informer().GetStore()})
+	}
+	return stores
+}
+
+// Endpoints returns endpoint objects for each host-target combination that should be processed.
+// Retrieves all the resources of the configured kinds in the source's namespace(s).
+func (us *unstructuredSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	var endpoints []*endpoint.Endpoint
+
+	for _, ri := range us.informers {
+		objs, err := ri.informer.Lister().ByNamespace(us.namespace).List(us.labelSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, errors.New("could not convert")
+			}
+			fullname := fmt.Sprintf("%s %s/%s", ri.gvr.Resource, u.GetNamespace(), u.GetName())
+
+			annotations := u.GetAnnotations()
+			if !us.annotationFilter.Matches(labels.Set(annotations)) {
+				continue
+			}
+
+			// Check controller annotation to see if we are responsible.
+			controller, ok := annotations[controllerAnnotationKey]
+			if ok && controller != controllerAnnotationValue {
+				log.Debugf("Skipping %s because controller value does not match, found: %s, required: %s",
+					fullname, controller, controllerAnnotationValue)
+				continue
+			}
+
+			hostnames, err := us.hostnames(ri.unstructuredResource, u)
+			if err != nil {
+				return nil, err
+			}
+			targets := getTargetsFromTargetAnnotation(annotations)
+			if len(targets) == 0 {
+				targets, err = executeJSONPath(ri.targetJSONPath, u)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get the targets of %s", fullname)
+				}
+			}
+			if len(hostnames) == 0 || len(targets) == 0 {
+				log.Debugf("No endpoints could be generated from %s", fullname)
+				continue
+			}
+
+			ttl, err := getTTLFromAnnotations(annotations)
+			if err != nil {
+				log.Warn(err)
+			}
+			providerSpecific, setIdentifier := getProviderSpecificAnnotations(annotations)
+
+			var objEndpoints []*endpoint.Endpoint
+			for _, hostname := range hostnames {
+				objEndpoints = append(objEndpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+			}
+			resource := fmt.Sprintf("%s/%s/%s", strings.ToLower(u.GetKind()), u.GetNamespace(), u.GetName())
+			for _, ep := range objEndpoints {
+				ep.Labels[endpoint.ResourceLabelKey] = resource
+			}
+			setPlanningLabels(u, objEndpoints)
+
+			log.Debugf("Endpoints generated from %s: %v", fullname, objEndpoints)
+			endpoints = append(endpoints, objEndpoints...)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// hostnames returns the hostnames of the resource from the JSONPath expression of its kind, the hostname annotation and
+// the FQDN template.
+func (us *unstructuredSource) hostnames(ur unstructuredResource, u *unstructured.Unstructured) ([]string, error) {
+	hostnames, err := executeJSONPath(ur.hostnameJSONPath, u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the hostnames of %s/%s", u.GetNamespace(), u.GetName())
+	}
+	if !us.ignoreHostnameAnnotation {
+		hostnames = append(hostnames, getHostnamesFromAnnotations(u.GetAnnotations())...)
+	}
+
+	if us.fqdnTemplate != nil && (len(hostnames) == 0 || us.combineFQDNAnnotation) {
+		obj, err := us.unstructuredConverter.typedObject(u)
+		if err != nil {
+			return nil, err
+		}
+		templateHostnames, err := execTemplate(us.fqdnTemplate, obj)
+		if err != nil {
+			return nil, err
+		}
+		hostnames = append(hostnames, templateHostnames...)
+	}
+	return hostnames, nil
+}
+
+func (us *unstructuredSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for unstructured resources")
+
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	for _, ri := range us.informers {
+		ri.informer.Informer().AddEventHandler(eventHandlerFunc(handler))
+	}
+}
+
+// parseUnstructuredResource parses a resource given as resource.version.group=hostnameJSONPath,targetJSONPath. The
+// expressions are separated by the first comma outside of their braces and brackets, they can hold commas themselves.
+func parseUnstructuredResource(resource string) (unstructuredResource, error) {
+	arg, expressions, _ := strings.Cut(resource, "=")
+	gvr, _ := schema.ParseResourceArg(arg)
+	if gvr == nil {
+		return unstructuredResource{}, fmt.Errorf("invalid resource %q of the unstructured source, expected resource.version.group", arg)
+	}
+	hostnameExpression, targetExpression := splitJSONPaths(expressions)
+	hostnamePath, err := parseJSONPath("hostname", hostnameExpression)
+	if err != nil {
+		return unstructuredResource{}, fmt.Errorf("invalid resource %q of the unstructured source: %w", resource, err)
+	}
+	targetPath, err := parseJSONPath("target", targetExpression)
+	if err != nil {
+		return unstructuredResource{}, fmt.Errorf("invalid resource %q of the unstructured source: %w", resource, err)
+	}
+	return unstructuredResource{gvr: *gvr, hostnameJSONPath: hostnamePath, targetJSONPath: targetPath}, nil
+}
+
+// splitJSONPaths splits the hostname and target JSONPath expressions at the first comma outside of braces and brackets.
+func splitJSONPaths(expressions string) (string, string) {
+	depth := 0
+	for i, c := range expressions {
+		switch c {
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				return expressions[:i], expressions[i+1:]
+			}
+		}
+	}
+	return expressions, ""
+}
+
+// parseJSONPath parses a JSONPath expression such as {.spec.host}, the braces may be left out. The missing keys are
+// allowed so that the resources lacking the fields, e.g. before their status is set, are skipped.
+func parseJSONPath(name, expression string) (*jsonpath.JSONPath, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	if !strings.Contains(expression, "{") {
+		expression = fmt.Sprintf("{%s}", expression)
+	}
+	jp := jsonpath.New(name).AllowMissingKeys(true)
+	if err := jp.Parse(expression); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s JSONPath expression %q: %w", name, expression, err)
+	}
+	return jp, nil
+}
+
+// executeJSONPath returns the whitespace separated values found by the JSONPath expression in the resource.
+func executeJSONPath(jp *jsonpath.JSONPath, u *unstructured.Unstructured) ([]string, error) {
+	if jp == nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := jp.Execute(&buf, u.UnstructuredContent()); err != nil {
+		return nil, err
+	}
+	return strings.Fields(buf.String()), nil
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package source
 
 import (
+	"github.com/pkg/errors"
 	projectcontour "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 )
@@ -44,3 +46,23 @@ func NewUnstructuredConverter() (*UnstructuredConverter, error) {
 
 	return uc, nil
 }
+
+// typedObject converts the resource to its type when the converter knows its kind, e.g. for the core types, so that
+// the templates can use its fields like with the dedicated sources. The resources of the other kinds are left as is.
+func (uc *UnstructuredConverter) typedObject(u *unstructured.Unstructured) (kubeObject, error) {
+	typed, err := uc.scheme.New(u.GroupVersionKind())
+	if runtime.IsNotRegisteredError(err) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := typed.(kubeObject)
+	if !ok {
+		return u, nil
+	}
+	if err := uc.scheme.Convert(u, obj, nil); err != nil {
+		return nil, errors.Wrapf(err, "failed to convert %s %s/%s", u.GetKind(), u.GetNamespace(), u.GetName())
+	}
+	return obj, nil
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var (
+	traefikIngressRouteGVR = schema.GroupVersionResource{Group: "traefik.containo.us", Version: "v1alpha1", Resource: "ingressroutes"}
+	knativeRouteGVR        = schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "routes"}
+	serviceGVR             = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+)
+
+func newUnstructuredObject(gvr schema.GroupVersionResource, kind, namespace, name string, annotations map[string]string, content map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetAPIVersion(gvr.GroupVersion().String())
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetAnnotations(annotations)
+	obj.SetLabels(map[string]string{"app": name})
+	return obj
+}
+
+func TestUnstructuredSource(t *testing.T) {
+	t.Parallel()
+
+	loadBalancerStatus := func(addresses ...string) map[string]interface{} {
+		var ingress []interface{}
+		for _, address := range addresses {
+			ingress = append(ingress, map[string]interface{}{"ip": address})
+		}
+		return map[string]interface{}{"loadBalancer": map[string]interface{}{"ingress": ingress}}
+	}
+	objects := []*unstructured.Unstructured{
+		newUnstructuredObject(traefikIngressRouteGVR, "IngressRoute", "default", "web", nil, map[string]interface{}{
+			"spec":   map[string]interface{}{"host": "web.example.org"},
+			"status": loadBalancerStatus("1.2.3.4", "2001:db8::1"),
+		}),
+		newUnstructuredObject(knativeRouteGVR, "Route", "default", "api", map[string]string{
+			hostnameAnnotationKey: "api.example.org",
+			ttlAnnotationKey:      "60",
+		}, map[string]interface{}{
+			"status": loadBalancerStatus("1.2.3.5"),
+		}),
+		newUnstructuredObject(knativeRouteGVR, "Route", "default", "pinned", map[string]string{
+			hostnameAnnotationKey: "pinned.example.org",
+			targetAnnotationKey:   "lb.example.org",
+		}, map[string]interface{}{
+			"status": loadBalancerStatus("1.2.3.6"),
+		}),
+		newUnstructuredObject(knativeRouteGVR, "Route", "default", "pending", nil, map[string]interface{}{
+			"spec": map[string]interface{}{"host": "pending.example.org"},
+		}),
+		newUnstructuredObject(knativeRouteGVR, "Route", "default", "other", map[string]string{
+			controllerAnnotationKey: "other-controller",
+		}, map[string]interface{}{
+			"spec":   map[string]interface{}{"host": "other.example.org"},
+			"status": loadBalancerStatus("1.2.3.7"),
+		}),
+		newUnstructuredObject(serviceGVR, "Service", "default", "svc", nil, map[string]interface{}{
+			"spec":   map[string]interface{}{"type": "ExternalName", "externalName": "svc.example.org"},
+			"status": loadBalancerStatus("1.2.3.8"),
+		}),
+	}
+
+	for _, tc := range []struct {
+		title            string
+		resources        []string
+		annotationFilter string
+		labelFilter      string
+		fqdnTemplate     string
+		expected         []*endpoint.Endpoint
+		expectError      bool
+	}{
+		{
+			title: "hostnames and targets are extracted with the JSONPath expressions and the annotations",
+			resources: []string{
+				"ingressroutes.v1alpha1.traefik.containo.us=.spec.host,{.status.loadBalancer.ingress[*].ip}",
+				"routes.v1.serving.knative.dev=.spec.host,{.status.loadBalancer.ingress[*].ip}",
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "web.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "ingressroute/default/web"}},
+				{DNSName: "web.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "ingressroute/default/web"}},
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, RecordTTL: 60, Targets: endpoint.Targets{"1.2.3.5"}, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "route/default/api"}},
+				{DNSName: "pinned.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "route/default/pinned"}},
+			},
+		},
+		{
+			title:        "only the given resources are read",
+			resources:    []string{"ingressroutes.v1alpha1.traefik.containo.us=,{.status.loadBalancer.ingress[*].ip}"},
+			fqdnTemplate: "{{.GetName}}.{{.GetNamespace}}.example.org",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "web.default.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "web.default.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+		},
+		{
+			title: "resources are filtered by labels and annotations",
+			resources: []string{
+				"ingressroutes.v1alpha1.traefik.containo.us=,{.status.loadBalancer.ingress[*].ip}",
+				"routes.v1.serving.knative.dev=,{.status.loadBalancer.ingress[*].ip}",
+			},
+			labelFilter:      "app in (api, pinned)",
+			annotationFilter: ttlAnnotationKey,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "api.example.org", RecordType: endpoint.RecordTypeA, RecordTTL: 60, Targets: endpoint.Targets{"1.2.3.5"}},
+			},
+		},
+		{
+			title:       "invalid resource",
+			resources:   []string{"ingressroutes"},
+			expectError: true,
+		},
+		{
+			title: "each resource has its JSONPath expressions",
+			resources: []string{
+				"ingressroutes.v1alpha1.traefik.containo.us=.spec.host,{.status.loadBalancer.ingress[*].ip}",
+				"routes.v1.serving.knative.dev=.spec.host",
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "web.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "web.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+				{DNSName: "pinned.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.org"}},
+			},
+		},
+		{
+			title:        "the FQDN template gets the typed resources of the known kinds",
+			resources:    []string{"services.v1.=,{.status.loadBalancer.ingress[*].ip}"},
+			fqdnTemplate: "{{.Spec.ExternalName}}",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "svc.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.8"}, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "service/default/svc"}},
+			},
+		},
+		{
+			title:       "invalid JSONPath expression",
+			resources:   []string{"routes.v1.serving.knative.dev={.spec.host"},
+			expectError: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			fakeDynamicClient := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				traefikIngressRouteGVR: "IngressRouteList",
+				knativeRouteGVR:        "RouteList",
+				serviceGVR:             "ServiceList",
+			})
+			ctx := context.Background()
+			for _, obj := range objects {
+				gvr := knativeRouteGVR
+				switch obj.GetKind() {
+				case "IngressRoute":
+					gvr = traefikIngressRouteGVR
+				case "Service":
+					gvr = serviceGVR
+				}
+				_, err := fakeDynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Create(ctx, obj.DeepCopy(), metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			labelSelector, err := labels.Parse(tc.labelFilter)
+			require.NoError(t, err)
+
+			src, err := NewUnstructuredSource(ctx, fakeDynamicClient, "", tc.annotationFilter, labelSelector, tc.fqdnTemplate, false, false, tc.resources)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			endpoints, err := src.Endpoints(ctx)
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestParseUnstructuredResource(t *testing.T) {
+	for _, tc := range []struct {
+		resource     string
+		gvr          schema.GroupVersionResource
+		hostnamePath string
+		targetPath   string
+		expectError  bool
+	}{
+		{
+			resource: "routes.v1.serving.knative.dev",
+			gvr:      knativeRouteGVR,
+		},
+		{
+			resource:   "services.v1.=,{.spec.clusterIP}",
+			gvr:        schema.GroupVersionResource{Version: "v1", Resource: "services"},
+			targetPath: "{.spec.clusterIP}",
+		},
+		{
+			resource:     "ingressroutes.v1alpha1.traefik.containo.us={.spec['host','hostname']},{.status.loadBalancer.ingress[*]['ip','hostname']}",
+			gvr:          traefikIngressRouteGVR,
+			hostnamePath: "{.spec['host','hostname']}",
+			targetPath:   "{.status.loadBalancer.ingress[*]['ip','hostname']}",
+		},
+		{
+			resource:    "routes",
+			expectError: true,
+		},
+		{
+			resource:    "routes.v1.serving.knative.dev=,{.status.url",
+			expectError: true,
+		},
+	} {
+		ur, err := parseUnstructuredResource(tc.resource)
+		if tc.expectError {
+			require.Error(t, err, tc.resource)
+			continue
+		}
+		require.NoError(t, err, tc.resource)
+		require.Equal(t, tc.gvr, ur.gvr, tc.resource)
+		require.Equal(t, tc.hostnamePath != "", ur.hostnameJSONPath != nil, tc.resource)
+		require.Equal(t, tc.targetPath != "", ur.targetJSONPath != nil, tc.resource)
+
+		_, expressions, _ := strings.Cut(tc.resource, "=")
+		hostnamePath, targetPath := splitJSONPaths(expressions)
+		require.Equal(t, tc.hostnamePath, hostnamePath, tc.resource)
+		require.Equal(t, tc.targetPath, targetPath, tc.resource)
+	}
+}